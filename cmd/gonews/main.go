@@ -0,0 +1,564 @@
+// Утилита командной строки GoNews для обслуживания БД.
+//
+//	go run ./cmd/gonews migrate-data -from redis -to pg
+//	go run ./cmd/gonews import -db pg -entity posts -file posts.csv -mode upsert
+//	go run ./cmd/gonews backup -db pg -out gonews.zip
+//	go run ./cmd/gonews restore -db mongo -in gonews.zip
+//	go run ./cmd/gonews user add -db pg -name alice -role author -author-id 1
+//	go run ./cmd/gonews apikey create -db pg -name ci -user alice
+package main
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/backup"
+	"GoNews/pkg/importer"
+	"GoNews/pkg/migrate"
+	"GoNews/pkg/storage"
+	"GoNews/pkg/storage/backend"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+)
+
+// Подкоманда утилиты: разбирает свои аргументы и возвращает код завершения.
+type command struct {
+	help string
+	run  func(args []string) int
+}
+
+var commands = map[string]command{
+	"migrate-data": {"copy authors and posts from one database to another", migrateData},
+	"import":       {"load authors or posts from a JSON, NDJSON or CSV file", importData},
+	"backup":       {"write authors and posts to a checksummed archive", backupData},
+	"restore":      {"load an archive created by backup into a database", restoreData},
+	"apikey":       {"create, list or revoke API keys: apikey create|list|revoke", apiKeys},
+	"user":         {"manage API users and roles: user add|list|set-role|delete", users},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	os.Exit(cmd.run(os.Args[2:]))
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gonews <command> [flags]")
+	fmt.Fprintln(os.Stderr, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", name, commands[name].help)
+	}
+}
+
+// Перенос данных между БД.
+func migrateData(args []string) int {
+	fs := flag.NewFlagSet("migrate-data", flag.ExitOnError)
+
+	var from, to, loadbd string
+	var verifyOnly bool
+	var opts migrate.Options
+
+	dbConfig := backend.DefaultConfig()
+
+	fs.StringVar(&from, "from", "", "source database: "+backend.Names)
+	fs.StringVar(&to, "to", "", "target database: "+backend.Names)
+	fs.IntVar(&opts.BatchSize, "batch", migrate.DefaultBatchSize, "records per batch")
+	fs.StringVar(&opts.Checkpoint, "checkpoint", "migrate-data.checkpoint.json", "checkpoint file to resume an interrupted run, empty to disable")
+	fs.BoolVar(&verifyOnly, "verify-only", false, "only compare source and target, do not copy")
+	fs.StringVar(&loadbd, "loadbd", "no", "load source from json files before copying (for -from mem): no/yes")
+	dbConfig.RegisterFlags(fs)
+	fs.Parse(args)
+
+	if from == "" || to == "" {
+		fmt.Fprintln(os.Stderr, "migrate-data: -from and -to are required")
+		fs.Usage()
+		return 2
+	}
+
+	src, err := backend.Open(from, dbConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "source:", err)
+		return 1
+	}
+	defer src.Close()
+
+	dst, err := backend.Open(to, dbConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "target:", err)
+		return 1
+	}
+	defer dst.Close()
+
+	if loadbd == "yes" {
+		if err := importer.InitData(src, importer.Authors, storage.AuthorsDb); err != nil {
+			fmt.Fprintln(os.Stderr, "load source:", err)
+			return 1
+		}
+		if err := importer.InitData(src, importer.Posts, storage.PostsDb); err != nil {
+			fmt.Fprintln(os.Stderr, "load source:", err)
+			return 1
+		}
+	}
+
+	var report migrate.Report
+	if verifyOnly {
+		report, err = migrate.Verify(src, dst)
+	} else {
+		// Ctrl+C останавливает перенос после текущего пакета,
+		// продолжить можно повторным запуском с тем же файлом контрольной точки.
+		ctx, stop := interruptContext()
+		defer stop()
+
+		opts.Source, opts.Target = dbConfig.Identity(from), dbConfig.Identity(to)
+		opts.Progress = func(entity string, done, total int) {
+			fmt.Fprintf(os.Stderr, "%s: %d/%d\n", entity, done, total)
+		}
+		report, err = migrate.Run(ctx, src, dst, opts)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate-data:", err)
+		return 1
+	}
+
+	printJSON(report)
+
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// Загрузка данных из файла.
+func importData(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+
+	var db, filename, format, mode, columns string
+	var opts importer.Options
+
+	dbConfig := backend.DefaultConfig()
+
+	fs.StringVar(&db, "db", backend.MemDB, "database: "+backend.Names)
+	fs.StringVar(&opts.Entity, "entity", "", "what to load: authors or posts")
+	fs.StringVar(&filename, "file", "", "input file, - for stdin")
+	fs.StringVar(&format, "format", "", "json, ndjson or csv (default: by file extension)")
+	fs.StringVar(&mode, "mode", string(importer.ModeFail), "on existing id: upsert, skip or fail")
+	fs.IntVar(&opts.BatchSize, "batch", importer.DefaultBatchSize, "records per batch")
+	fs.StringVar(&columns, "map", "", "csv column mapping: Column=field,Other=field2 (field - to ignore a column)")
+	dbConfig.RegisterFlags(fs)
+	fs.Parse(args)
+
+	if opts.Entity == "" || filename == "" {
+		fmt.Fprintln(os.Stderr, "import: -entity and -file are required")
+		fs.Usage()
+		return 2
+	}
+
+	opts.Format = importer.Format(format)
+	opts.Mode = importer.Mode(mode)
+	var err error
+	if opts.Columns, err = importer.ParseColumns(columns); err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if filename != "-" {
+		if opts.Format == "" {
+			if opts.Format, err = importer.FormatByName(filename); err != nil {
+				fmt.Fprintln(os.Stderr, "import:", err)
+				return 2
+			}
+		}
+		f, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "import:", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	store, err := backend.Open(db, dbConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
+		return 1
+	}
+	defer store.Close()
+
+	opts.Progress = func(st importer.Stats) {
+		fmt.Fprintf(os.Stderr, "%s: read %d, created %d, updated %d, skipped %d, failed %d\n",
+			opts.Entity, st.Read, st.Created, st.Updated, st.Skipped, st.Failed)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	st, err := importer.Import(ctx, store, in, opts)
+
+	printJSON(st)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
+		return 1
+	}
+	if st.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Создание архива.
+func backupData(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+
+	var db, out string
+
+	dbConfig := backend.DefaultConfig()
+
+	fs.StringVar(&db, "db", "", "database: "+backend.Names)
+	fs.StringVar(&out, "out", "", "archive file (default gonews-<db>-<time>.zip)")
+	dbConfig.RegisterFlags(fs)
+	fs.Parse(args)
+
+	if db == "" {
+		fmt.Fprintln(os.Stderr, "backup: -db is required")
+		fs.Usage()
+		return 2
+	}
+
+	if out == "" {
+		out = fmt.Sprintf("gonews-%s-%s.zip", db, time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	store, err := backend.Open(db, dbConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "backup:", err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	m, err := backup.CreateFile(ctx, store, out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "backup:", err)
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr, "backup written to", out)
+	printJSON(m)
+	return 0
+}
+
+// Восстановление из архива.
+func restoreData(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+
+	var db, in, mode string
+	var verifyOnly bool
+	var opts backup.RestoreOptions
+
+	dbConfig := backend.DefaultConfig()
+
+	fs.StringVar(&db, "db", backend.MemDB, "database: "+backend.Names)
+	fs.StringVar(&in, "in", "", "archive file")
+	fs.StringVar(&mode, "mode", string(importer.ModeFail), "on existing id: upsert, skip or fail")
+	fs.IntVar(&opts.BatchSize, "batch", importer.DefaultBatchSize, "records per batch")
+	fs.BoolVar(&verifyOnly, "verify-only", false, "only check the archive, do not restore")
+	dbConfig.RegisterFlags(fs)
+	fs.Parse(args)
+
+	if in == "" {
+		fmt.Fprintln(os.Stderr, "restore: -in is required")
+		fs.Usage()
+		return 2
+	}
+
+	if verifyOnly {
+		m, err := backup.Verify(in)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "restore:", err)
+			return 1
+		}
+		printJSON(m)
+		return 0
+	}
+
+	store, err := backend.Open(db, dbConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "restore:", err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	opts.Mode = importer.Mode(mode)
+	opts.Progress = func(entity string, st importer.Stats) {
+		fmt.Fprintf(os.Stderr, "%s: read %d, created %d, updated %d, skipped %d\n",
+			entity, st.Read, st.Created, st.Updated, st.Skipped)
+	}
+
+	res, err := backup.Restore(ctx, store, in, opts)
+	printJSON(res)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "restore:", err)
+		return 1
+	}
+	return 0
+}
+
+// Управление ключами API.
+func apiKeys(args []string) int {
+	fs := flag.NewFlagSet("apikey", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gonews apikey create|list|revoke [flags]")
+		fs.PrintDefaults()
+	}
+
+	var db, name, user string
+	var id int64
+
+	dbConfig := backend.DefaultConfig()
+
+	fs.StringVar(&db, "db", backend.MemDB, "database: "+backend.Names)
+	fs.StringVar(&name, "name", "", "key name (create)")
+	fs.StringVar(&user, "user", "", "user the key acts as (create); empty - service key with admin rights")
+	fs.Int64Var(&id, "id", 0, "key id (revoke)")
+	dbConfig.RegisterFlags(fs)
+
+	if len(args) == 0 {
+		fs.Usage()
+		return 2
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+
+	if db == backend.MemDB {
+		fmt.Fprintln(os.Stderr, "apikey: warning: memdb keys exist only in this process; use the server output or another database")
+	}
+
+	store, err := backend.Open(db, dbConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "apikey:", err)
+		return 1
+	}
+	defer store.Close()
+
+	keys, ok := store.(auth.KeyStore)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "apikey: database %q does not store API keys\n", db)
+		return 1
+	}
+
+	switch action {
+	case "create":
+		if name == "" {
+			fmt.Fprintln(os.Stderr, "apikey: -name is required")
+			return 2
+		}
+		var userID int64
+		if user != "" {
+			users, ok := store.(auth.UserStore)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "apikey: database %q does not store users\n", db)
+				return 1
+			}
+			u, err := users.UserByName(user)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "apikey:", err)
+				return 1
+			}
+			userID = u.ID
+		}
+		secret, key, err := auth.NewKey(name, time.Now().UnixNano()/int64(time.Millisecond))
+		if err == nil {
+			key.UserID = userID
+			key.ID, err = keys.AddAPIKey(key)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "apikey:", err)
+			return 1
+		}
+		fmt.Fprintln(os.Stderr, "store this key now, it cannot be shown again")
+		printJSON(map[string]interface{}{"key": secret, "id": key.ID, "name": key.Name, "prefix": key.Prefix, "user_id": key.UserID})
+	case "list":
+		list, err := keys.APIKeys()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "apikey:", err)
+			return 1
+		}
+		printJSON(list)
+	case "revoke":
+		if id <= 0 {
+			fmt.Fprintln(os.Stderr, "apikey: -id is required")
+			return 2
+		}
+		if err := keys.DeleteAPIKey(id); err != nil {
+			fmt.Fprintln(os.Stderr, "apikey:", err)
+			return 1
+		}
+		fmt.Fprintln(os.Stderr, "revoked key", id)
+	default:
+		fmt.Fprintf(os.Stderr, "apikey: unknown action %q\n", action)
+		fs.Usage()
+		return 2
+	}
+	return 0
+}
+
+// Управление пользователями API и их ролями.
+func users(args []string) int {
+	fs := flag.NewFlagSet("user", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gonews user add|list|set-role|delete [flags]")
+		fs.PrintDefaults()
+	}
+
+	var db, name, role string
+	var authorID int64
+
+	dbConfig := backend.DefaultConfig()
+
+	fs.StringVar(&db, "db", backend.MemDB, "database: "+backend.Names)
+	fs.StringVar(&name, "name", "", "user name, equals JWT claim sub (add, set-role, delete)")
+	fs.StringVar(&role, "role", string(auth.RoleAuthor), "role: author, editor or admin (add, set-role)")
+	fs.Int64Var(&authorID, "author-id", 0, "linked author, required for role author (add, set-role)")
+	dbConfig.RegisterFlags(fs)
+
+	if len(args) == 0 {
+		fs.Usage()
+		return 2
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+
+	if db == backend.MemDB {
+		fmt.Fprintln(os.Stderr, "user: warning: memdb users exist only in this process; use another database")
+	}
+
+	store, err := backend.Open(db, dbConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "user:", err)
+		return 1
+	}
+	defer store.Close()
+
+	users, ok := store.(auth.UserStore)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "user: database %q does not store users\n", db)
+		return 1
+	}
+
+	if action != "list" && name == "" {
+		fmt.Fprintln(os.Stderr, "user: -name is required")
+		return 2
+	}
+
+	// Новые значения роли и автора для add и set-role.
+	updated := func(u auth.User) (auth.User, error) {
+		r, err := auth.ParseRole(role)
+		if err != nil {
+			return u, err
+		}
+		u.Role, u.AuthorID = r, authorID
+		if authorID != 0 {
+			if _, err := store.AuthorByID(authorID); err != nil {
+				return u, err
+			}
+		}
+		return u, u.Validate()
+	}
+
+	switch action {
+	case "add":
+		u, err := updated(auth.User{Name: name, CreatedAt: time.Now().UnixNano() / int64(time.Millisecond)})
+		if err == nil {
+			u.ID, err = users.AddUser(u)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "user:", err)
+			return 1
+		}
+		printJSON(u)
+	case "list":
+		list, err := users.Users()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "user:", err)
+			return 1
+		}
+		printJSON(list)
+	case "set-role":
+		u, err := users.UserByName(name)
+		if err == nil {
+			u, err = updated(u)
+		}
+		if err == nil {
+			err = users.UpdateUser(u)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "user:", err)
+			return 1
+		}
+		printJSON(u)
+	case "delete":
+		u, err := users.UserByName(name)
+		if err == nil {
+			err = users.DeleteUser(u.ID)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "user:", err)
+			return 1
+		}
+		fmt.Fprintln(os.Stderr, "deleted user", u.Name, "; their API keys no longer grant write access")
+	default:
+		fmt.Fprintf(os.Stderr, "user: unknown action %q\n", action)
+		fs.Usage()
+		return 2
+	}
+	return 0
+}
+
+func printJSON(v interface{}) {
+	out, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(out))
+}
+
+// Контекст, отменяемый по Ctrl+C.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			stop()
+		case <-ctx.Done():
+		}
+		signal.Stop(sig)
+	}()
+	return ctx, stop
+}
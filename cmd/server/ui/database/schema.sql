@@ -2,7 +2,7 @@
 --1) create tables
 --++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 
-DROP TABLE IF EXISTS posts, authors;
+DROP TABLE IF EXISTS posts, authors, events, webhooks, webhook_deliveries, api_keys, users;
 
 CREATE TABLE authors (
     id BIGSERIAL PRIMARY KEY,
@@ -18,6 +18,74 @@ CREATE TABLE posts (
     published_at BIGINT NOT NULL
 );
 
+-- поток изменений authors и posts (заполняется триггерами, см. events_func_capture);
+-- txid - транзакция, записавшая событие: подписчики читают события в порядке
+-- (txid, id) только завершённых транзакций, поэтому транзакция, получившая
+-- меньший id, но завершившаяся позже, не пропускается;
+-- старые события удаляются вручную, например:
+-- DELETE FROM events WHERE created_at < now() - interval '7 days';
+CREATE TABLE events (
+    id BIGSERIAL PRIMARY KEY,
+    txid BIGINT NOT NULL DEFAULT txid_current(),
+    entity TEXT NOT NULL,
+    op TEXT NOT NULL,
+    entity_id BIGINT NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX events_commit_order ON events (txid, id);
+
+-- подписки webhooks; время - в миллисекундах Unix
+CREATE TABLE webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    secret TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at BIGINT NOT NULL
+);
+
+-- очередь и журнал доставок webhooks (без внешнего ключа: журнал
+-- сохраняется после удаления подписки)
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL,
+    event TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at BIGINT NOT NULL,
+    response_code INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_subscription ON webhook_deliveries (subscription_id, id);
+
+-- ключи API: хранится только SHA-256 ключа
+CREATE TABLE api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hash TEXT NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL DEFAULT 0, -- 0 - служебный ключ с правами администратора
+    created_at BIGINT NOT NULL
+);
+
+-- пользователи API и их роли; author_id = 0 - не связан с автором
+CREATE TABLE users (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    role TEXT NOT NULL CHECK (role IN ('author', 'editor', 'admin')),
+    author_id BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL
+);
+
 
 
 
@@ -27,6 +95,7 @@ CREATE TABLE posts (
 
 DROP FUNCTION IF EXISTS authors_func_delete, authors_func_update, authors_func_insert, authors_func_view;
 DROP FUNCTION IF EXISTS posts_func_delete, posts_func_update, posts_func_insert, posts_func_view;
+DROP FUNCTION IF EXISTS events_func_capture;
 
 --=======================
 --table: authors
@@ -43,12 +112,20 @@ DECLARE
 	json_result jsonb;
 BEGIN
 
-	INSERT INTO authors (name) VALUES ((json_data ->> 'name')::TEXT) RETURNING id INTO new_id;
+	--id > 0 сохраняется (перенос данных между БД), иначе назначается последовательностью
+	INSERT INTO authors (id, name) VALUES (
+		COALESCE(NULLIF((json_data ->> 'id')::BIGINT, 0), nextval('authors_id_seq')),
+		(json_data ->> 'name')::TEXT
+		) RETURNING id INTO new_id;
 	
 	IF new_id IS NULL THEN
 		RAISE EXCEPTION 'Parameter value cannot be null';
 	END IF;
 
+	IF COALESCE((json_data ->> 'id')::BIGINT, 0) > 0 THEN
+		PERFORM setval('authors_id_seq', GREATEST(new_id, (SELECT last_value FROM authors_id_seq)));
+	END IF;
+
 	SELECT json_build_object('id',new_id,'err','') INTO json_result;
   	RETURN json_result;
 
@@ -171,7 +248,9 @@ DECLARE
 	json_result jsonb;
 BEGIN
 
+	--id > 0 сохраняется (перенос данных между БД), иначе назначается последовательностью
 	INSERT INTO posts (
+		id,
 		author_id, 
 		title, 
 		content,
@@ -179,6 +258,7 @@ BEGIN
 		published_at
 		) 
 	VALUES (
+		COALESCE(NULLIF((json_data ->> 'id')::BIGINT, 0), nextval('posts_id_seq')),
 		(json_data ->> 'author_id')::BIGINT, 
 		(json_data ->> 'title')::TEXT, 
 		(json_data ->> 'content')::TEXT, 
@@ -191,6 +271,10 @@ BEGIN
 		RAISE EXCEPTION 'Parameter value cannot be null. ';
 	END IF;
 
+	IF COALESCE((json_data ->> 'id')::BIGINT, 0) > 0 THEN
+		PERFORM setval('posts_id_seq', GREATEST(new_id, (SELECT last_value FROM posts_id_seq)));
+	END IF;
+
 	SELECT json_build_object('id',new_id,'err','') INTO json_result;
   	RETURN json_result;
 
@@ -350,6 +434,41 @@ END;
 $$ LANGUAGE plpgsql;
 
 
+--=======================
+--table: events
+--=======================
+--capture: запись изменения строки в events и уведомление подписчиков
+--(канал gonews_events, содержимое уведомления - id события)
+CREATE FUNCTION events_func_capture() RETURNS TRIGGER AS $$
+DECLARE
+	new_id BIGINT;
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		INSERT INTO events (entity, op, entity_id, after)
+		VALUES (TG_TABLE_NAME, 'create', NEW.id, to_jsonb(NEW))
+		RETURNING id INTO new_id;
+	ELSIF TG_OP = 'UPDATE' THEN
+		INSERT INTO events (entity, op, entity_id, before, after)
+		VALUES (TG_TABLE_NAME, 'update', NEW.id, to_jsonb(OLD), to_jsonb(NEW))
+		RETURNING id INTO new_id;
+	ELSE
+		INSERT INTO events (entity, op, entity_id, before)
+		VALUES (TG_TABLE_NAME, 'delete', OLD.id, to_jsonb(OLD))
+		RETURNING id INTO new_id;
+	END IF;
+
+	PERFORM pg_notify('gonews_events', new_id::TEXT);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER authors_trigger_events AFTER INSERT OR UPDATE OR DELETE ON authors
+	FOR EACH ROW EXECUTE FUNCTION events_func_capture();
+
+CREATE TRIGGER posts_trigger_events AFTER INSERT OR UPDATE OR DELETE ON posts
+	FOR EACH ROW EXECUTE FUNCTION events_func_capture();
+
+
 --++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 --3) create test data
 --++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -4,14 +4,16 @@ go 1.15
 
 require (
 	github.com/aws/aws-sdk-go v1.34.28 // indirect
-	github.com/go-redis/redis/v8 v8.11.5 // indirect
+	github.com/evanphx/json-patch v5.9.11+incompatible
+	github.com/go-redis/redis/v8 v8.11.5
 	github.com/gobuffalo/genny v0.1.1 // indirect
 	github.com/gobuffalo/gogen v0.1.1 // indirect
 	github.com/gorilla/mux v1.8.0
+	github.com/jackc/pgconn v1.8.1
 	github.com/jackc/pgx/v4 v4.11.0
 	github.com/karrick/godirwalk v1.10.3 // indirect
 	github.com/pelletier/go-toml v1.7.0 // indirect
-	github.com/rs/zerolog v1.33.0 // indirect
+	github.com/rs/zerolog v1.33.0
 	github.com/stretchr/testify v1.6.1 // indirect
 	github.com/tidwall/pretty v1.0.0 // indirect
 	go.mongodb.org/mongo-driver v1.16.0
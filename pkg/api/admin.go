@@ -0,0 +1,60 @@
+package api
+
+import (
+	"GoNews/pkg/logger"
+	"GoNews/pkg/validation"
+	"expvar"
+	"fmt"
+	"net/http"
+)
+
+// Переменные expvar, не выводимые в /debug/vars: командная строка
+// содержит пароли БД и секреты из флагов.
+var hiddenVars = map[string]bool{"cmdline": true}
+
+// Счётчики expvar в формате expvar.Handler, без hiddenVars.
+//
+//	GET /debug/vars
+func (api *API) varsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if hiddenVars[kv.Key] {
+			return
+		}
+		if !first {
+			fmt.Fprintf(w, ",\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	})
+	fmt.Fprintf(w, "\n}\n")
+}
+
+// Уровень журнала zerolog.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// Текущий уровень журнала.
+func (api *API) logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, logLevel{Level: logger.Level()})
+}
+
+// Изменение уровня журнала без перезапуска сервера.
+//
+//	PUT /admin/log-level {"level": "debug"}
+func (api *API) setLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req logLevel
+	if err := validation.Decode(r.Body, &req, nil); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if err := logger.SetLevel(req.Level); err != nil {
+		writeValidationError(w, validation.Errors{{Field: "level", Message: err.Error()}})
+		return
+	}
+	writeJSON(w, http.StatusOK, logLevel{Level: logger.Level()})
+}
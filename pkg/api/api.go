@@ -1,27 +1,65 @@
 package api
 
 import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/events"
 	"GoNews/pkg/logger"
+	"GoNews/pkg/metrics"
+	"GoNews/pkg/ratelimit"
 	"GoNews/pkg/storage"
+	"GoNews/pkg/tracing"
+	"GoNews/pkg/validation"
+	"GoNews/pkg/webhooks"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
 	"text/template"
-	"time"
 
 	"github.com/gorilla/mux"
 )
 
 // Программный интерфейс сервера GoNews
 type API struct {
-	db     storage.Interface
-	router *mux.Router
+	db       storage.Interface
+	backend  storage.Interface // БД без обёрток (версия, пулы соединений)
+	events   events.Source     // поток изменений для /events, nil - отключён
+	webhooks webhooks.Store    // подписки и доставки /webhooks, nil - отключены
+	auth     *auth.Authenticator
+	limiter  ratelimit.Limiter
+	limits   ratelimit.Config
+	router   *mux.Router
+	stop     chan struct{} // закрывается Shutdown
+	stopOnce sync.Once
+}
+
+// Options - необязательные части API.
+type Options struct {
+	Events   events.Source
+	Webhooks webhooks.Store
+	Auth     *auth.Authenticator // nil - без аутентификации
+	Limiter  ratelimit.Limiter   // nil - без ограничения частоты запросов
+	Limits   ratelimit.Config
+	Backend  storage.Interface // БД без обёрток для /admin/info, nil - db
 }
 
 // Конструктор объекта API
-func New(db storage.Interface) *API {
+func New(db storage.Interface, opts Options) *API {
 	api := API{
-		db: db,
+		db:       db,
+		events:   opts.Events,
+		webhooks: opts.Webhooks,
+		auth:     opts.Auth,
+		limiter:  opts.Limiter,
+		limits:   opts.Limits,
+		backend:  opts.Backend,
+		stop:     make(chan struct{}),
+	}
+	if api.backend == nil {
+		api.backend = db
 	}
 	api.router = mux.NewRouter()
 	api.endpoints()
@@ -31,22 +69,93 @@ func New(db storage.Interface) *API {
 // Регистрация обработчиков API.
 func (api *API) endpoints() {
 
+	// Журнал запросов, ID запроса, метрики и трассировка - первыми, чтобы учитывались и отказы.
+	api.router.Use(logger.Middleware, metrics.Middleware, tracing.Middleware)
+	api.router.NotFoundHandler = logger.Middleware(metrics.Middleware(tracing.Middleware(http.NotFoundHandler())))
+	api.router.MethodNotAllowedHandler = logger.Middleware(metrics.Middleware(tracing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}))))
+
+	// Изменения требуют учётных данных; подписки webhooks и администрирование
+	// закрыты и для чтения. Права по ролям проверяют сами обработчики (authz.go).
+	// Отказы 401 учитываются в корзине IP-адреса клиента до ответа.
+	if api.auth != nil {
+		if api.limiter != nil {
+			api.auth.LimitRejected(ratelimit.Rejected(api.limiter, api.limits))
+		}
+		api.router.Use(api.auth.Middleware("/webhooks", "/admin", "/debug"))
+	}
+	// Частота запросов ограничивается по клиенту, поэтому после аутентификации.
+	if api.limiter != nil {
+		api.router.Use(ratelimit.Middleware(api.limiter, api.limits))
+	}
+
 	api.router.HandleFunc("/", api.templateHandler).Methods(http.MethodGet, http.MethodOptions)
 
 	api.router.HandleFunc("/posts", api.postsHandler).Methods(http.MethodGet, http.MethodOptions)
 	api.router.HandleFunc("/posts", api.addPostHandler).Methods(http.MethodPost, http.MethodOptions)
 	api.router.HandleFunc("/posts", api.updatePostHandler).Methods(http.MethodPut, http.MethodOptions)
 	api.router.HandleFunc("/posts", api.deletePostHandler).Methods(http.MethodDelete, http.MethodOptions)
+	api.router.HandleFunc("/posts:batch", api.postsBatchHandler).Methods(http.MethodPost, http.MethodOptions)
+	api.router.HandleFunc("/posts/{id:[0-9]+}", api.postHandler).Methods(http.MethodGet, http.MethodOptions)
+	api.router.HandleFunc("/posts/{id:[0-9]+}", api.patchPostHandler).Methods(http.MethodPatch, http.MethodOptions)
 
 	api.router.HandleFunc("/authors", api.authorsHandler).Methods(http.MethodGet, http.MethodOptions)
 	api.router.HandleFunc("/authors", api.addAuthorHandler).Methods(http.MethodPost, http.MethodOptions)
 	api.router.HandleFunc("/authors", api.updateAuthorHandler).Methods(http.MethodPut, http.MethodOptions)
 	api.router.HandleFunc("/authors", api.deleteAuthorHandler).Methods(http.MethodDelete, http.MethodOptions)
+	api.router.HandleFunc("/authors:withPosts", api.addAuthorWithPostsHandler).Methods(http.MethodPost, http.MethodOptions)
+	api.router.HandleFunc("/authors:batch", api.authorsBatchHandler).Methods(http.MethodPost, http.MethodOptions)
+	api.router.HandleFunc("/authors/{id:[0-9]+}", api.authorHandler).Methods(http.MethodGet, http.MethodOptions)
+	api.router.HandleFunc("/authors/{id:[0-9]+}", api.patchAuthorHandler).Methods(http.MethodPatch, http.MethodOptions)
+
+	api.router.HandleFunc("/import", api.importHandler).Methods(http.MethodPost, http.MethodOptions)
+
+	api.router.HandleFunc("/events", api.eventsHandler).Methods(http.MethodGet, http.MethodOptions)
+
+	// Подписки webhooks и администрирование доступны только администраторам.
+	admin := func(h http.HandlerFunc) http.HandlerFunc { return api.requireRole(auth.RoleAdmin, "admin", h) }
+	api.router.HandleFunc("/webhooks", admin(api.webhooksHandler)).Methods(http.MethodGet, http.MethodOptions)
+	api.router.HandleFunc("/webhooks", admin(api.addWebhookHandler)).Methods(http.MethodPost, http.MethodOptions)
+	api.router.HandleFunc("/webhooks/deliveries", admin(api.deliveriesHandler)).Methods(http.MethodGet, http.MethodOptions)
+	api.router.HandleFunc("/webhooks/deliveries/{id:[0-9]+}", admin(api.deliveryHandler)).Methods(http.MethodGet, http.MethodOptions)
+	api.router.HandleFunc("/webhooks/deliveries/{id:[0-9]+}/retry", admin(api.retryDeliveryHandler)).Methods(http.MethodPost, http.MethodOptions)
+	api.router.HandleFunc("/webhooks/{id:[0-9]+}", admin(api.webhookHandler)).Methods(http.MethodGet, http.MethodOptions)
+	api.router.HandleFunc("/webhooks/{id:[0-9]+}", admin(api.updateWebhookHandler)).Methods(http.MethodPut, http.MethodOptions)
+	api.router.HandleFunc("/webhooks/{id:[0-9]+}", admin(api.deleteWebhookHandler)).Methods(http.MethodDelete, http.MethodOptions)
+	api.router.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", admin(api.deliveriesHandler)).Methods(http.MethodGet, http.MethodOptions)
+
+	api.router.HandleFunc("/admin/info", admin(api.infoHandler)).Methods(http.MethodGet, http.MethodOptions)
+	api.router.HandleFunc("/admin/logs", admin(api.logsHandler)).Methods(http.MethodGet, http.MethodOptions)
+	api.router.HandleFunc("/admin/log-level", admin(api.logLevelHandler)).Methods(http.MethodGet, http.MethodOptions)
+	api.router.HandleFunc("/admin/log-level", admin(api.setLogLevelHandler)).Methods(http.MethodPut, http.MethodOptions)
+
+	// Проверки состояния для оркестратора (Kubernetes и т.п.).
+	api.router.HandleFunc("/healthz", api.healthzHandler).Methods(http.MethodGet, http.MethodHead)
+	api.router.HandleFunc("/readyz", api.readyzHandler).Methods(http.MethodGet, http.MethodHead)
+
+	// Счётчики (в т.ч. попадания и промахи кэша), только администраторам.
+	api.router.HandleFunc("/debug/vars", admin(api.varsHandler)).Methods(http.MethodGet)
+	// Метрики в формате Prometheus.
+	api.router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 
 	// Регистрация обработчика для статических файлов (шаблонов)
 	api.router.Handle("/ui/", http.StripPrefix("/ui/", http.FileServer(http.Dir("ui"))))
 }
 
+// Shutdown завершает потоковые ответы (/events, /admin/logs?follow=1),
+// которые иначе не дали бы серверу дождаться окончания запросов.
+func (api *API) Shutdown() {
+	api.stopOnce.Do(func() { close(api.stop) })
+}
+
+// Хранилище, выполняющее операции в контексте запроса r
+// (отмена при отключении клиента, спаны трассировки, чтение своих изменений).
+func (api *API) store(r *http.Request) storage.Interface {
+	ctx := storage.WithClient(r.Context(), ratelimit.ClientKey(r, api.limits.TrustProxy))
+	return storage.Bind(api.db, ctx)
+}
+
 // Получение маршрутизатора запросов.
 // Требуется для передачи маршрутизатора веб-серверу.
 func (api *API) Router() *mux.Router {
@@ -66,18 +175,101 @@ func (api *API) templateHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// Формат времени для ответа: часовой пояс из параметра ?tz=,
+// язык отображения из заголовка Accept-Language.
+func timeFormat(r *http.Request) (storage.TimeFormat, error) {
+	tf, err := storage.NewTimeFormat(r.URL.Query().Get("tz"), r.Header.Get("Accept-Language"))
+	if err != nil {
+		return tf, fmt.Errorf("invalid tz: %v", err)
+	}
+	return tf, nil
+}
+
+// Декодирование и проверка публикации из тела запроса.
+// При ошибке ответ клиенту уже отправлен.
+func decodePost(w http.ResponseWriter, r *http.Request, op validation.Op) (storage.Post, bool) {
+	var p storage.Post
+	err := validation.Decode(r.Body, &p, validation.PostReadOnly)
+	if err == nil {
+		err = validation.Post(p, op)
+	}
+	if err != nil {
+		writeValidationError(w, err)
+		return p, false
+	}
+	return p, true
+}
+
+// Декодирование и проверка автора из тела запроса.
+// При ошибке ответ клиенту уже отправлен.
+func decodeAuthor(w http.ResponseWriter, r *http.Request, op validation.Op) (storage.Author, bool) {
+	var a storage.Author
+	err := validation.Decode(r.Body, &a, validation.AuthorReadOnly)
+	if err == nil {
+		err = validation.Author(a, op)
+	}
+	if err != nil {
+		writeValidationError(w, err)
+		return a, false
+	}
+	return a, true
+}
+
+// Ответ на ошибку входных данных: 422 с перечнем ошибок по полям,
+// 413 для слишком большого тела, 400 для некорректного JSON.
+func writeValidationError(w http.ResponseWriter, err error) {
+	var fieldErrs validation.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"errors": fieldErrs})
+	case errors.Is(err, validation.ErrTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// Отправка значения в формате JSON с указанным кодом ответа.
+// Кодирование списка name в JSON со спаном трассировки: для больших
+// списков кодирование - заметная часть времени ответа.
+func encodeJSON(r *http.Request, name string, v interface{}) ([]byte, error) {
+	_, span := tracing.Start(r.Context(), "json.Marshal "+name, tracing.KindInternal)
+	bytes, err := json.Marshal(v)
+	span.SetAttr("json.bytes", len(bytes))
+	span.SetError(err)
+	span.Finish()
+	return bytes, err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bytes)
+}
+
 // 1) Post
 // Получение всех публикаций.
 func (api *API) postsHandler(w http.ResponseWriter, r *http.Request) {
 
-	posts, err := api.db.Posts()
+	tf, err := timeFormat(r)
 	if err != nil {
-		logger.SetLog(time.Now(), api.db.GetInform(), fmt.Sprintf("%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	bytes, err := json.Marshal(posts)
+	posts, err := api.store(r).Posts()
+	if err != nil {
+		api.dbError(w, r, err)
+		return
+	}
+	tf.ApplyPosts(posts)
+
+	bytes, err := encodeJSON(r, "posts", posts)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -86,19 +278,41 @@ func (api *API) postsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(bytes)
 }
 
+// Получение публикации по ID.
+func (api *API) postHandler(w http.ResponseWriter, r *http.Request) {
+
+	tf, err := timeFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	post, err := api.store(r).PostByID(id)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	tf.ApplyPost(&post)
+
+	writeJSON(w, http.StatusOK, post)
+}
+
 // Добавление публикации.
 func (api *API) addPostHandler(w http.ResponseWriter, r *http.Request) {
 
-	var p storage.Post
-	err := json.NewDecoder(r.Body).Decode(&p)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	p, ok := decodePost(w, r, validation.OpCreate)
+	if !ok || !api.allowAuthor(w, r, "posts.create", p.AuthorID) {
 		return
 	}
-	_, err = api.db.AddPost(p)
+	_, err := api.store(r).AddPost(p)
 	if err != nil {
-		logger.SetLog(time.Now(), api.db.GetInform(), fmt.Sprintf("%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		api.dbError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -107,16 +321,13 @@ func (api *API) addPostHandler(w http.ResponseWriter, r *http.Request) {
 // Обновление публикации.
 func (api *API) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 
-	var p storage.Post
-	err := json.NewDecoder(r.Body).Decode(&p)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	p, ok := decodePost(w, r, validation.OpUpdate)
+	if !ok || !api.allowPost(w, r, "posts.update", p.ID) || !api.allowAuthor(w, r, "posts.update", p.AuthorID) {
 		return
 	}
-	_, err = api.db.UpdatePost(p)
+	_, err := api.store(r).UpdatePost(p)
 	if err != nil {
-		logger.SetLog(time.Now(), api.db.GetInform(), fmt.Sprintf("%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		api.dbError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -125,16 +336,13 @@ func (api *API) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 // Удаление публикации.
 func (api *API) deletePostHandler(w http.ResponseWriter, r *http.Request) {
 
-	var p storage.Post
-	err := json.NewDecoder(r.Body).Decode(&p)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	p, ok := decodePost(w, r, validation.OpDelete)
+	if !ok || !api.allowPost(w, r, "posts.delete", p.ID) {
 		return
 	}
-	_, err = api.db.DeletePost(p)
+	_, err := api.store(r).DeletePost(p)
 	if err != nil {
-		logger.SetLog(time.Now(), api.db.GetInform(), fmt.Sprintf("%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		api.dbError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -144,14 +352,13 @@ func (api *API) deletePostHandler(w http.ResponseWriter, r *http.Request) {
 // Получение всех авторов.
 func (api *API) authorsHandler(w http.ResponseWriter, r *http.Request) {
 
-	authors, err := api.db.Authors()
+	authors, err := api.store(r).Authors()
 	if err != nil {
-		logger.SetLog(time.Now(), api.db.GetInform(), fmt.Sprintf("%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		api.dbError(w, r, err)
 		return
 	}
 
-	bytes, err := json.Marshal(authors)
+	bytes, err := encodeJSON(r, "authors", authors)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -160,19 +367,37 @@ func (api *API) authorsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(bytes)
 }
 
+// Получение автора по ID.
+func (api *API) authorHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	author, err := api.store(r).AuthorByID(id)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, author)
+}
+
 // Добавление автора.
 func (api *API) addAuthorHandler(w http.ResponseWriter, r *http.Request) {
 
-	var p storage.Author
-	err := json.NewDecoder(r.Body).Decode(&p)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !api.allowRole(w, r, "authors.create", auth.RoleEditor) {
 		return
 	}
-	_, err = api.db.AddAuthor(p)
+	p, ok := decodeAuthor(w, r, validation.OpCreate)
+	if !ok {
+		return
+	}
+	_, err := api.store(r).AddAuthor(p)
 	if err != nil {
-		logger.SetLog(time.Now(), api.db.GetInform(), fmt.Sprintf("%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		api.dbError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -181,16 +406,13 @@ func (api *API) addAuthorHandler(w http.ResponseWriter, r *http.Request) {
 // Обновление автора.
 func (api *API) updateAuthorHandler(w http.ResponseWriter, r *http.Request) {
 
-	var p storage.Author
-	err := json.NewDecoder(r.Body).Decode(&p)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	p, ok := decodeAuthor(w, r, validation.OpUpdate)
+	if !ok || !api.allowAuthor(w, r, "authors.update", p.ID) {
 		return
 	}
-	_, err = api.db.UpdateAuthor(p)
+	_, err := api.store(r).UpdateAuthor(p)
 	if err != nil {
-		logger.SetLog(time.Now(), api.db.GetInform(), fmt.Sprintf("%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		api.dbError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -199,17 +421,96 @@ func (api *API) updateAuthorHandler(w http.ResponseWriter, r *http.Request) {
 // Удаление автора.
 func (api *API) deleteAuthorHandler(w http.ResponseWriter, r *http.Request) {
 
-	var p storage.Author
-	err := json.NewDecoder(r.Body).Decode(&p)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !api.allowRole(w, r, "authors.delete", auth.RoleEditor) {
+		return
+	}
+	p, ok := decodeAuthor(w, r, validation.OpDelete)
+	if !ok {
 		return
 	}
-	_, err = api.db.DeleteAuthor(p)
+	_, err := api.store(r).DeleteAuthor(p)
 	if err != nil {
-		logger.SetLog(time.Now(), api.db.GetInform(), fmt.Sprintf("%v", err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		api.dbError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// Тело запроса на создание автора вместе с публикациями.
+type authorWithPosts struct {
+	Author storage.Author    `json:"author"`
+	Posts  []json.RawMessage `json:"posts"`
+}
+
+// Создание автора вместе с его публикациями в одной транзакции:
+// при ошибке любой операции не создаётся ничего.
+// ID автора и публикаций можно не указывать - их назначает БД (см. checkID
+// в пакете validation); author_id публикаций заполняется ID нового автора.
+func (api *API) addAuthorWithPostsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !api.allowRole(w, r, "authors.create", auth.RoleEditor) {
+		return
+	}
+	var req authorWithPosts
+	err := validation.DecodeLimit(r.Body, validation.MaxBatchBodySize, &req, nil)
+	if err == nil {
+		err = validation.Author(req.Author, validation.OpCreate)
+	}
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if len(req.Posts) > validation.MaxBatchItems {
+		writeValidationError(w, validation.Errors{{Field: "posts", Message: fmt.Sprintf("must contain at most %d items", validation.MaxBatchItems)}})
+		return
+	}
+
+	posts := make([]storage.Post, len(req.Posts))
+	seen := make(map[int64]int, len(req.Posts)) // индекс публикации по заданному ID
+	for i, raw := range req.Posts {
+		err := validation.Decode(bytes.NewReader(raw), &posts[i], validation.PostReadOnly)
+		if err == nil && posts[i].AuthorID != 0 && posts[i].AuthorID != req.Author.ID {
+			err = validation.Errors{{Field: "author_id", Message: "must be omitted or equal to author.id"}}
+		}
+		if j, ok := seen[posts[i].ID]; err == nil && ok {
+			err = validation.Errors{{Field: "id", Message: fmt.Sprintf("duplicates posts[%d].id", j)}}
+		}
+		if posts[i].ID != 0 {
+			seen[posts[i].ID] = i
+		}
+		if err == nil {
+			// author_id назначается в транзакции, проверяются остальные поля.
+			p := posts[i]
+			p.AuthorID = 1
+			err = validation.Post(p, validation.OpCreate)
+		}
+		if err != nil {
+			writeValidationError(w, validation.Prefix(err, fmt.Sprintf("posts[%d].", i)))
+			return
+		}
+	}
+
+	var authorID int64
+	postIDs := make([]int64, len(posts))
+	err = api.store(r).WithTx(r.Context(), func(tx storage.Tx) error {
+		id, err := tx.AddAuthor(req.Author)
+		if err != nil {
+			return err
+		}
+		authorID = id
+
+		for i, p := range posts {
+			p.AuthorID = id
+			if postIDs[i], err = tx.AddPost(p); err != nil {
+				return fmt.Errorf("posts[%d]: %w", i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		api.dbError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"author_id": authorID, "post_ids": postIDs})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"GoNews/pkg/auth"
+	"net/http"
+)
+
+// Проверка прав клиента запроса на действие action.
+// Без аутентификации (api.auth == nil) разрешено всё. При отказе
+// записывается событие аудита и клиенту уже отправлен ответ 403.
+func (api *API) allow(w http.ResponseWriter, r *http.Request, action string, ok func(auth.Principal) bool, reason string) bool {
+	if api.auth == nil {
+		return true
+	}
+	p, _ := auth.FromContext(r.Context())
+	if ok(p) {
+		return true
+	}
+	auth.Forbidden(w, r, action, reason)
+	return false
+}
+
+// Проверка роли клиента не ниже role.
+func (api *API) allowRole(w http.ResponseWriter, r *http.Request, action string, role auth.Role) bool {
+	return api.allow(w, r, action, func(p auth.Principal) bool { return p.Has(role) }, "role "+string(role)+" required")
+}
+
+// Проверка права изменять автора authorID и его публикации.
+func (api *API) allowAuthor(w http.ResponseWriter, r *http.Request, action string, authorID int64) bool {
+	return api.allow(w, r, action, func(p auth.Principal) bool { return p.CanEditAuthor(authorID) }, "not the owner")
+}
+
+// Обработчик h, доступный только роли role.
+func (api *API) requireRole(role auth.Role, action string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api.allowRole(w, r, action, role) {
+			h(w, r)
+		}
+	}
+}
+
+// Проверка права изменять существующую публикацию id: владельцем
+// считается её автор. Ошибка чтения публикации отправляется клиенту.
+func (api *API) allowPost(w http.ResponseWriter, r *http.Request, action string, id int64) bool {
+	if api.auth == nil {
+		return true
+	}
+	current, err := api.store(r).PostByID(id)
+	if err != nil {
+		api.storageError(w, r, err)
+		return false
+	}
+	return api.allowAuthor(w, r, action, current.AuthorID)
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/logger"
+	"GoNews/pkg/storage"
+	"GoNews/pkg/validation"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Тело пакетного запроса.
+// Режим по умолчанию - storage.BatchAtomic.
+type batchRequest struct {
+	Op    storage.BatchOp   `json:"op"`
+	Mode  storage.BatchMode `json:"mode"`
+	Items []json.RawMessage `json:"items"`
+}
+
+// Ответ на пакетный запрос: результат по каждому элементу.
+type batchResponse struct {
+	Applied bool                  `json:"applied"`
+	Results []storage.BatchResult `json:"results"`
+}
+
+// Пакетная обработка публикаций.
+func (api *API) postsBatchHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !api.allowRole(w, r, "posts.batch", auth.RoleEditor) {
+		return
+	}
+	req, ok := decodeBatch(w, r)
+	if !ok {
+		return
+	}
+
+	posts := make([]storage.Post, len(req.Items))
+	results := storage.NewBatchResults(len(req.Items))
+	for i, raw := range req.Items {
+		err := validation.Decode(bytes.NewReader(raw), &posts[i], validation.PostReadOnly)
+		if err == nil {
+			err = validation.Post(posts[i], batchValidationOp(req.Op))
+		}
+		if err != nil {
+			results[i].Err = err.Error()
+		}
+	}
+
+	api.runBatch(w, r, req, results, "posts", func(valid []int) ([]storage.BatchResult, error) {
+		items := make([]storage.Post, len(valid))
+		for i, idx := range valid {
+			items[i] = posts[idx]
+		}
+		return api.store(r).PostsBatch(req.Op, req.Mode, items)
+	})
+}
+
+// Пакетная обработка авторов.
+func (api *API) authorsBatchHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !api.allowRole(w, r, "authors.batch", auth.RoleEditor) {
+		return
+	}
+	req, ok := decodeBatch(w, r)
+	if !ok {
+		return
+	}
+
+	authors := make([]storage.Author, len(req.Items))
+	results := storage.NewBatchResults(len(req.Items))
+	for i, raw := range req.Items {
+		err := validation.Decode(bytes.NewReader(raw), &authors[i], validation.AuthorReadOnly)
+		if err == nil {
+			err = validation.Author(authors[i], batchValidationOp(req.Op))
+		}
+		if err != nil {
+			results[i].Err = err.Error()
+		}
+	}
+
+	api.runBatch(w, r, req, results, "authors", func(valid []int) ([]storage.BatchResult, error) {
+		items := make([]storage.Author, len(valid))
+		for i, idx := range valid {
+			items[i] = authors[idx]
+		}
+		return api.store(r).AuthorsBatch(req.Op, req.Mode, items)
+	})
+}
+
+// Декодирование и проверка заголовка пакетного запроса.
+// При ошибке ответ клиенту уже отправлен.
+func decodeBatch(w http.ResponseWriter, r *http.Request) (batchRequest, bool) {
+	var req batchRequest
+	if err := validation.DecodeLimit(r.Body, validation.MaxBatchBodySize, &req, nil); err != nil {
+		writeValidationError(w, err)
+		return req, false
+	}
+	if req.Mode == "" {
+		req.Mode = storage.BatchAtomic
+	}
+
+	var errs validation.Errors
+	if !req.Op.Valid() {
+		errs = append(errs, validation.FieldError{Field: "op", Message: "must be one of create, update, delete"})
+	}
+	if !req.Mode.Valid() {
+		errs = append(errs, validation.FieldError{Field: "mode", Message: "must be one of atomic, best_effort"})
+	}
+	if len(req.Items) == 0 || len(req.Items) > validation.MaxBatchItems {
+		errs = append(errs, validation.FieldError{Field: "items", Message: fmt.Sprintf("must contain from 1 to %d items", validation.MaxBatchItems)})
+	}
+	if len(errs) > 0 {
+		writeValidationError(w, errs)
+		return req, false
+	}
+
+	return req, true
+}
+
+func batchValidationOp(op storage.BatchOp) validation.Op {
+	switch op {
+	case storage.BatchUpdate:
+		return validation.OpUpdate
+	case storage.BatchDelete:
+		return validation.OpDelete
+	}
+	return validation.OpCreate
+}
+
+// Выполнение пакета для элементов, прошедших проверку, и отправка ответа.
+// results содержит ошибки проверки; exec получает индексы корректных элементов.
+// Ответ 200 - пакет применён (в режиме best_effort - все корректные элементы),
+// 409 - пакет в режиме atomic отклонён целиком.
+func (api *API) runBatch(w http.ResponseWriter, r *http.Request, req batchRequest, results []storage.BatchResult, entity string, exec func(valid []int) ([]storage.BatchResult, error)) {
+
+	if req.Mode == storage.BatchAtomic && storage.BatchFailed(results) {
+		storage.AbortBatch(results)
+		writeJSON(w, http.StatusConflict, batchResponse{Results: results})
+		return
+	}
+
+	var valid []int
+	for i := range results {
+		if results[i].Err == "" {
+			valid = append(valid, i)
+		}
+	}
+
+	if len(valid) > 0 {
+		dbResults, err := exec(valid)
+		if err != nil && !errors.Is(err, storage.ErrBatchAborted) {
+			api.dbError(w, r, fmt.Errorf("batch %s %s: %w", req.Op, entity, err))
+			return
+		}
+		for i, idx := range valid {
+			results[idx].ID = dbResults[i].ID
+			results[idx].Err = dbResults[i].Err
+		}
+	}
+
+	failed := 0
+	for _, res := range results {
+		if res.Err != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		// Одна запись в журнал на пакет, а не на каждый элемент.
+		logger.SetLogCtx(r.Context(), time.Now(), api.db.GetInform(), fmt.Sprintf("batch %s %s (%s): %d of %d items failed", req.Op, entity, req.Mode, failed, len(results)))
+	}
+
+	if req.Mode == storage.BatchAtomic && failed > 0 {
+		writeJSON(w, http.StatusConflict, batchResponse{Results: results})
+		return
+	}
+	writeJSON(w, http.StatusOK, batchResponse{Applied: true, Results: results})
+}
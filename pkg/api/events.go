@@ -0,0 +1,102 @@
+package api
+
+import (
+	"GoNews/pkg/events"
+	"GoNews/pkg/logger"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Интервал комментария-пинга, чтобы прокси не закрывали простаивающее соединение.
+const sseHeartbeat = 15 * time.Second
+
+// Через сколько миллисекунд браузер переподключается после разрыва.
+const sseRetry = 3000
+
+// Поток изменений авторов и публикаций (Server-Sent Events).
+//
+//	GET /events?entity=posts,authors
+//
+// Каждое событие передаётся как "id: <ID>", "event: posts|authors",
+// "data: <events.Event в JSON>". Продолжение после разрыва - с события из
+// заголовка Last-Event-ID (его отправляет EventSource) или параметра last_event_id.
+func (api *API) eventsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if api.events == nil {
+		http.Error(w, "change events are disabled", http.StatusServiceUnavailable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	entities := map[string]bool{}
+	if s := r.URL.Query().Get("entity"); s != "" {
+		for _, e := range strings.Split(s, ",") {
+			if e != events.Authors && e != events.Posts {
+				http.Error(w, "entity must be a comma-separated list of authors, posts", http.StatusBadRequest)
+				return
+			}
+			entities[e] = true
+		}
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+
+	ch, err := api.events.Subscribe(r.Context(), lastID)
+	if err != nil {
+		logger.SetLogCtx(r.Context(), time.Now(), api.db.GetInform(), fmt.Sprintf("subscribe to events: %v", err))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // без буферизации в nginx
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				// Источник закрыл подписку: клиент переподключится с Last-Event-ID.
+				return
+			}
+			if len(entities) > 0 && !entities[e.Entity] {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Entity, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-api.stop:
+			// Сервер завершает работу: клиент переподключится к другому экземпляру.
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"GoNews/pkg/metrics"
+	"GoNews/pkg/storage"
+	"context"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// Время ожидания ответа БД при проверке готовности и сборе сведений.
+const pingTimeout = 2 * time.Second
+
+// Время запуска сервера (для /admin/info).
+var started = time.Now()
+
+// Состояние сервера для /healthz и /readyz.
+type healthResponse struct {
+	Status  string `json:"status"`
+	Backend string `json:"backend,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Процесс жив и обрабатывает запросы (liveness). БД не проверяется,
+// чтобы её недоступность не приводила к перезапуску сервера.
+//
+//	GET /healthz
+func (api *API) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// Сервер готов принимать запросы (readiness): основная БД отвечает.
+//
+//	GET /readyz - 200 или 503 с описанием ошибки
+func (api *API) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), pingTimeout)
+	defer cancel()
+
+	resp := healthResponse{Status: "ready", Backend: api.db.GetInform()}
+	if err := api.store(r).Ping(ctx); err != nil {
+		resp.Status, resp.Error = "unavailable", err.Error()
+		writeJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Сведения о сервере и БД.
+type infoResponse struct {
+	Backend      string              `json:"backend"`
+	Version      string              `json:"version,omitempty"` // версия сервера БД
+	Ready        bool                `json:"ready"`
+	Error        string              `json:"error,omitempty"`
+	Pools        []metrics.PoolStats `json:"pools,omitempty"`
+	Authors      int                 `json:"authors"`
+	Posts        int                 `json:"posts"`
+	StartedAt    time.Time           `json:"started_at"`
+	Uptime       string              `json:"uptime"`
+	GoVersion    string              `json:"go_version"`
+	NumGoroutine int                 `json:"goroutines"`
+}
+
+// Диагностика БД: тип и версия сервера, доступность, пулы соединений,
+// число авторов и публикаций.
+//
+//	GET /admin/info
+func (api *API) infoHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), pingTimeout)
+	defer cancel()
+
+	resp := infoResponse{
+		Backend:      api.db.GetInform(),
+		StartedAt:    started,
+		Uptime:       time.Since(started).Round(time.Second).String(),
+		GoVersion:    runtime.Version(),
+		NumGoroutine: runtime.NumGoroutine(),
+	}
+	errs := func(err error) {
+		if err != nil && resp.Error == "" {
+			resp.Error = err.Error()
+		}
+	}
+
+	db := api.store(r)
+	err := db.Ping(ctx)
+	resp.Ready = err == nil
+	errs(err)
+
+	// Версия и пулы - у БД без обёрток.
+	if v, ok := api.backend.(storage.Versioner); ok && resp.Ready {
+		resp.Version, err = v.Version(ctx)
+		errs(err)
+	}
+	if p, ok := api.backend.(metrics.PoolReporter); ok {
+		resp.Pools = p.PoolStats()
+	}
+
+	if resp.Ready {
+		authors, err := db.Authors()
+		resp.Authors = len(authors)
+		errs(err)
+		posts, err := db.Posts()
+		resp.Posts = len(posts)
+		errs(err)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/importer"
+	"GoNews/pkg/logger"
+	"GoNews/pkg/storage"
+	"GoNews/pkg/validation"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Тип ответа с ходом загрузки: по строке JSON на каждый записанный пакет.
+const ndjsonType = "application/x-ndjson"
+
+// Ответ на запрос загрузки.
+type importResponse struct {
+	Progress *importer.Stats `json:"progress,omitempty"`
+	Result   *importer.Stats `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Загрузка авторов или публикаций из JSON, NDJSON или CSV.
+//
+//	POST /import?entity=posts&mode=upsert&batch=500&map=Заголовок=title,Автор=author_id
+//
+// Формат задаётся параметром format или заголовком Content-Type.
+// Если клиент принимает application/x-ndjson, ход загрузки передаётся
+// строками {"progress": ...} по мере записи пакетов, последняя строка - {"result": ...}.
+func (api *API) importHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !api.allowRole(w, r, "import", auth.RoleEditor) {
+		return
+	}
+	q := r.URL.Query()
+	opts := importer.Options{
+		Entity: q.Get("entity"),
+		Format: importer.Format(q.Get("format")),
+		Mode:   importer.Mode(q.Get("mode")),
+	}
+
+	var errs validation.Errors
+	if opts.Entity != importer.Authors && opts.Entity != importer.Posts {
+		errs = append(errs, validation.FieldError{Field: "entity", Message: "must be one of authors, posts"})
+	}
+	if opts.Mode == "" {
+		opts.Mode = importer.ModeFail
+	}
+	if !opts.Mode.Valid() {
+		errs = append(errs, validation.FieldError{Field: "mode", Message: "must be one of upsert, skip, fail"})
+	}
+	if opts.Format == "" {
+		format, err := importer.FormatByContentType(r.Header.Get("Content-Type"))
+		if err != nil {
+			errs = append(errs, validation.FieldError{Field: "format", Message: err.Error()})
+		}
+		opts.Format = format
+	} else if !opts.Format.Valid() {
+		errs = append(errs, validation.FieldError{Field: "format", Message: "must be one of json, ndjson, csv"})
+	}
+	if s := q.Get("batch"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			errs = append(errs, validation.FieldError{Field: "batch", Message: "must be a positive integer"})
+		}
+		opts.BatchSize = n
+	}
+	columns, err := importer.ParseColumns(q.Get("map"))
+	if err != nil {
+		errs = append(errs, validation.FieldError{Field: "map", Message: err.Error()})
+	}
+	opts.Columns = columns
+	if len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	stream := strings.Contains(r.Header.Get("Accept"), ndjsonType)
+	var enc *json.Encoder
+	if stream {
+		w.Header().Set("Content-Type", ndjsonType)
+		w.WriteHeader(http.StatusOK)
+		enc = json.NewEncoder(w)
+		opts.Progress = func(st importer.Stats) {
+			enc.Encode(importResponse{Progress: &st})
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+	}
+
+	body := &limitedReader{r: r.Body, n: validation.MaxImportBodySize}
+	st, err := importer.Import(r.Context(), api.store(r), body, opts)
+
+	status := http.StatusOK
+	resp := importResponse{Result: &st}
+	if err != nil {
+		resp.Error = err.Error()
+		switch {
+		case body.exceeded:
+			status = http.StatusRequestEntityTooLarge
+			resp.Error = fmt.Sprintf("%v: limit is %d bytes", validation.ErrTooLarge, validation.MaxImportBodySize)
+		case errors.Is(err, importer.ErrConflict):
+			status = http.StatusConflict
+		case errors.Is(err, importer.ErrInvalidData):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, storage.ErrUnavailable):
+			status = http.StatusServiceUnavailable
+		case errors.Is(err, storage.ErrTxUnsupported):
+			status = http.StatusNotImplemented
+		default:
+			status = http.StatusInternalServerError
+		}
+	}
+	if err != nil || st.Failed > 0 {
+		logger.SetLogCtx(r.Context(), time.Now(), api.db.GetInform(), fmt.Sprintf("import %s (%s): read %d, failed %d: %s", opts.Entity, opts.Mode, st.Read, st.Failed, resp.Error))
+	}
+
+	if stream {
+		// Код ответа уже отправлен, ошибка передаётся в последней строке.
+		enc.Encode(resp)
+		return
+	}
+	writeJSON(w, status, resp)
+}
+
+// Ограничение размера тела запроса при потоковом чтении.
+// В отличие от io.LimitReader, превышение лимита - ошибка, а не конец данных.
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	exceeded bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Лимит исчерпан: ошибка, только если данные ещё есть.
+		var b [1]byte
+		if n, err := l.r.Read(b[:]); n == 0 {
+			return 0, err
+		}
+		l.exceeded = true
+		return 0, validation.ErrTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
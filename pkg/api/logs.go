@@ -0,0 +1,196 @@
+package api
+
+import (
+	"GoNews/pkg/logger"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Ограничения числа записей журнала в ответе.
+const (
+	defaultLogsLimit = 100
+	maxLogsLimit     = 1000
+)
+
+// Страница записей журнала.
+type logsResponse struct {
+	Entries []logger.MessStructLog `json:"entries"`
+	Next    string                 `json:"next,omitempty"` // cursor для следующей страницы
+}
+
+// Записи журнала ошибок БД и аудита, от новых к старым.
+//
+//	GET /admin/logs?since=&until=&database=&level=&q=&request_id=&limit=
+//	GET /admin/logs?follow=1 - последние limit записей и новые записи (Server-Sent Events)
+//
+// since и until - время в RFC 3339; level - минимальный уровень (debug, info,
+// warn, error); q - подстрока сообщения. Следующая страница - с cursor из next
+// (время последней записи и число полученных записей с этим временем: записи
+// одной миллисекунды не теряются на границе страниц).
+func (api *API) logsHandler(w http.ResponseWriter, r *http.Request) {
+
+	q, err := logsQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+
+	// Подписка до чтения файла, чтобы не потерять записи между ними.
+	var ch <-chan logger.MessStructLog
+	if follow {
+		var cancel func()
+		ch, cancel = logger.Follow()
+		defer cancel()
+	}
+
+	entries, err := logger.Search(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !follow {
+		resp := logsResponse{Entries: entries}
+		if resp.Entries == nil {
+			resp.Entries = []logger.MessStructLog{}
+		}
+		if len(entries) == q.Limit {
+			resp.Next = nextLogsCursor(q, entries)
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+
+	// Сначала последние записи от старых к новым, затем новые.
+	var last time.Time
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !writeLogEvent(w, entries[i]) {
+			return
+		}
+		last = entries[i].Datetime
+	}
+	flusher.Flush()
+
+	// Новые записи отбираются без ограничения по времени до.
+	q.Until = time.Time{}
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				// Клиент не успевал читать: он переподключится.
+				return
+			}
+			if !q.Match(e) || !last.IsZero() && !e.Datetime.After(last) {
+				continue
+			}
+			if !writeLogEvent(w, e) {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-api.stop:
+			return
+		}
+	}
+}
+
+func writeLogEvent(w http.ResponseWriter, e logger.MessStructLog) bool {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return true
+	}
+	_, err = fmt.Fprintf(w, "event: log\ndata: %s\n\n", data)
+	return err == nil
+}
+
+// Разбор параметров отбора записей журнала.
+func logsQuery(r *http.Request) (logger.Query, error) {
+	v := r.URL.Query()
+	q := logger.Query{
+		Database:  v.Get("database"),
+		Text:      v.Get("q"),
+		RequestID: v.Get("request_id"),
+		Level:     zerolog.NoLevel,
+		Limit:     defaultLogsLimit,
+	}
+
+	var err error
+	if s := v.Get("since"); s != "" {
+		if q.Since, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return q, fmt.Errorf("since must be RFC 3339 time: %v", err)
+		}
+	}
+	if s := v.Get("until"); s != "" {
+		if q.Until, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return q, fmt.Errorf("until must be RFC 3339 time: %v", err)
+		}
+	}
+	if s := v.Get("cursor"); s != "" {
+		i := strings.LastIndex(s, ",")
+		if i < 0 {
+			return q, fmt.Errorf("cursor must be the next field of the previous page")
+		}
+		if q.Until, err = time.Parse(time.RFC3339Nano, s[:i]); err != nil {
+			return q, fmt.Errorf("cursor must be the next field of the previous page: %v", err)
+		}
+		if q.Skip, err = strconv.Atoi(s[i+1:]); err != nil || q.Skip < 0 {
+			return q, fmt.Errorf("cursor must be the next field of the previous page")
+		}
+	}
+	if s := v.Get("level"); s != "" {
+		if q.Level, err = zerolog.ParseLevel(s); err != nil || q.Level == zerolog.NoLevel {
+			return q, fmt.Errorf("level must be one of trace, debug, info, warn, error")
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxLogsLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxLogsLimit)
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
+
+// Курсор страницы после entries: время последней записи и число записей
+// с этим временем, полученных на этой и предыдущих страницах. Время в UTC:
+// "+" смещения в неэкранированном параметре запроса стал бы пробелом.
+func nextLogsCursor(q logger.Query, entries []logger.MessStructLog) string {
+	last := entries[len(entries)-1].Datetime
+	n := 0
+	for i := len(entries) - 1; i >= 0 && entries[i].Datetime.Equal(last); i-- {
+		n++
+	}
+	if n == len(entries) && q.Skip > 0 && q.Until.Equal(last) {
+		n += q.Skip
+	}
+	return fmt.Sprintf("%s,%d", last.UTC().Format(time.RFC3339Nano), n)
+}
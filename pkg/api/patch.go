@@ -0,0 +1,223 @@
+package api
+
+import (
+	"GoNews/pkg/logger"
+	"GoNews/pkg/storage"
+	"GoNews/pkg/validation"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/gorilla/mux"
+)
+
+// Типы тела PATCH-запроса.
+const (
+	mergePatchType = "application/merge-patch+json" // RFC 7386
+	jsonPatchType  = "application/json-patch+json"  // RFC 6902
+)
+
+// Частичное обновление публикации.
+func (api *API) patchPostHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	current, err := api.store(r).PostByID(id)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	if !api.allowAuthor(w, r, "posts.update", current.AuthorID) {
+		return
+	}
+
+	before, err := validation.Document(current, validation.PostReadOnly)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	after, ok := applyPatch(w, r, before)
+	if !ok {
+		return
+	}
+
+	var p storage.Post
+	fields, err := validation.Patched(before, after, storage.PostPatchable, &p)
+	if err == nil {
+		err = validation.Post(p, validation.OpUpdate)
+	}
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if p.AuthorID != current.AuthorID && !api.allowAuthor(w, r, "posts.update", p.AuthorID) {
+		return
+	}
+
+	if len(fields) > 0 {
+		if _, err = api.store(r).PatchPost(id, fields); err != nil {
+			api.storageError(w, r, err)
+			return
+		}
+	}
+
+	updated, err := api.store(r).PostByID(id)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	tf, err := timeFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tf.ApplyPost(&updated)
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// Частичное обновление автора.
+func (api *API) patchAuthorHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !api.allowAuthor(w, r, "authors.update", id) {
+		return
+	}
+
+	current, err := api.store(r).AuthorByID(id)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+
+	before, err := validation.Document(current, validation.AuthorReadOnly)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	after, ok := applyPatch(w, r, before)
+	if !ok {
+		return
+	}
+
+	var a storage.Author
+	fields, err := validation.Patched(before, after, storage.AuthorPatchable, &a)
+	if err == nil {
+		err = validation.Author(a, validation.OpUpdate)
+	}
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if len(fields) > 0 {
+		if _, err = api.store(r).PatchAuthor(id, fields); err != nil {
+			api.storageError(w, r, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, a)
+}
+
+// Применение тела PATCH-запроса к документу doc.
+// Тип патча определяется заголовком Content-Type, по умолчанию - JSON Merge Patch.
+// При ошибке ответ клиенту уже отправлен.
+func applyPatch(w http.ResponseWriter, r *http.Request, doc []byte) ([]byte, bool) {
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, validation.MaxBodySize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if int64(len(body)) > validation.MaxBodySize {
+		writeValidationError(w, validation.ErrTooLarge)
+		return nil, false
+	}
+
+	mediaType := mergePatchType
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err = mime.ParseMediaType(ct)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return nil, false
+		}
+	}
+
+	var result []byte
+	switch mediaType {
+	case jsonPatchType:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		result, err = patch.Apply(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return nil, false
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return nil, false
+		}
+
+	case mergePatchType, "application/json":
+		result, err = jsonpatch.MergePatch(doc, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+
+	default:
+		http.Error(w, fmt.Sprintf("unsupported Content-Type %q, use %s or %s", mediaType, mergePatchType, jsonPatchType), http.StatusUnsupportedMediaType)
+		return nil, false
+	}
+
+	return result, true
+}
+
+// Ответ на ошибку БД: 404 для отсутствующей записи, иначе как dbError.
+func (api *API) storageError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	api.dbError(w, r, err)
+}
+
+// Ответ на ошибку БД: 503 с Retry-After, если БД недоступна и запрос в неё
+// не отправлялся (storage.ErrUnavailable), 501, если БД не поддерживает
+// транзакции (storage.ErrTxUnsupported), иначе 500 с записью в журнал.
+func (api *API) dbError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrTxUnsupported) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if errors.Is(err, storage.ErrUnavailable) {
+		var retry interface{ RetryAfter() time.Duration }
+		if errors.As(err, &retry) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.RetryAfter().Seconds()))))
+		}
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	logger.SetLogCtx(r.Context(), time.Now(), api.db.GetInform(), fmt.Sprintf("%v", err))
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"GoNews/pkg/validation"
+	"GoNews/pkg/webhooks"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Наибольшее число доставок в одном ответе журнала.
+const maxDeliveriesPage = 500
+
+// Подписка в запросе POST и PUT /webhooks. Пустой secret при создании -
+// сгенерировать, при изменении - оставить прежний; active по умолчанию true.
+type webhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+// Подписка в ответе без секрета.
+func hideSecret(sub webhooks.Subscription) webhooks.Subscription {
+	sub.Secret = ""
+	return sub
+}
+
+// Проверка, что хранилище подписок доступно.
+func (api *API) webhooksEnabled(w http.ResponseWriter) bool {
+	if api.webhooks == nil {
+		http.Error(w, "webhooks are disabled", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// Список подписок (без секретов).
+func (api *API) webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.webhooksEnabled(w) {
+		return
+	}
+
+	subs, err := api.webhooks.Webhooks()
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	data := make([]webhooks.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		data = append(data, hideSecret(sub))
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// Подписка по ID (без секрета).
+func (api *API) webhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.webhooksEnabled(w) {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := api.webhooks.WebhookByID(id)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hideSecret(sub))
+}
+
+// Создание подписки. Секрет возвращается только в этом ответе.
+func (api *API) addWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.webhooksEnabled(w) {
+		return
+	}
+
+	var req webhookRequest
+	if err := validation.Decode(r.Body, &req, nil); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	sub := webhooks.Subscription{
+		URL:       req.URL,
+		Events:    req.Events,
+		Secret:    req.Secret,
+		Active:    req.Active == nil || *req.Active,
+		CreatedAt: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	if err := sub.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if sub.Secret == "" {
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sub.Secret = secret
+	}
+
+	id, err := api.webhooks.AddWebhook(sub)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	sub.ID = id
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+// Изменение подписки.
+func (api *API) updateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.webhooksEnabled(w) {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req webhookRequest
+	if err := validation.Decode(r.Body, &req, nil); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	sub, err := api.webhooks.WebhookByID(id)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	sub.URL = req.URL
+	sub.Events = req.Events
+	if req.Secret != "" {
+		sub.Secret = req.Secret
+	}
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+	if err := sub.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := api.webhooks.UpdateWebhook(sub); err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hideSecret(sub))
+}
+
+// Удаление подписки. Журнал её доставок сохраняется,
+// ожидающие доставки завершаются с ошибкой.
+func (api *API) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.webhooksEnabled(w) {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := api.webhooks.DeleteWebhook(id); err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Журнал доставок, новые первыми.
+//
+//	GET /webhooks/deliveries?status=failed&limit=100&before=<id>
+//	GET /webhooks/{id}/deliveries
+//
+// Следующая страница - с параметром before, равным наименьшему ID в ответе.
+func (api *API) deliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.webhooksEnabled(w) {
+		return
+	}
+
+	q := r.URL.Query()
+	f := webhooks.DeliveryFilter{Status: q.Get("status"), Limit: 100}
+	var errs validation.Errors
+	if s, ok := mux.Vars(r)["id"]; ok {
+		f.SubscriptionID, _ = strconv.ParseInt(s, 10, 64)
+	}
+	switch f.Status {
+	case "", webhooks.StatusPending, webhooks.StatusDelivered, webhooks.StatusFailed:
+	default:
+		errs = append(errs, validation.FieldError{Field: "status", Message: "must be one of pending, delivered, failed"})
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxDeliveriesPage {
+			errs = append(errs, validation.FieldError{Field: "limit", Message: "must be an integer from 1 to " + strconv.Itoa(maxDeliveriesPage)})
+		}
+		f.Limit = n
+	}
+	if s := q.Get("before"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			errs = append(errs, validation.FieldError{Field: "before", Message: "must be a positive integer"})
+		}
+		f.BeforeID = n
+	}
+	if len(errs) > 0 {
+		writeValidationError(w, errs)
+		return
+	}
+
+	data, err := api.webhooks.Deliveries(f)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	if data == nil {
+		data = []webhooks.Delivery{}
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// Доставка по ID.
+func (api *API) deliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.webhooksEnabled(w) {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err := api.webhooks.DeliveryByID(id)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Повторная отправка доставки (например, после исправления получателя).
+func (api *API) retryDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.webhooksEnabled(w) {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err := webhooks.Retry(api.webhooks, id)
+	if err != nil {
+		api.storageError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, d)
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"GoNews/pkg/logger"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// AuditSource - источник записей аудита в журнале (logger.SetLog).
+const AuditSource = "audit"
+
+// Число отказов в доступе по коду ответа ("401", "403") - /debug/vars.
+var auditDenied = expvar.NewMap("auth_denied")
+
+// Записей аудита об отказах 401 с одного адреса за окно; остальные
+// только считаются и попадают в сводную запись следующего окна.
+const (
+	unauthWindow = time.Minute
+	unauthBurst  = 10
+)
+
+// Отказы 401 по адресам клиентов в текущем окне.
+var unauth = struct {
+	mu    sync.Mutex
+	start time.Time
+	count map[string]int
+}{count: make(map[string]int)}
+
+// AuditEvent - запись аудита об отказе в доступе.
+type AuditEvent struct {
+	Time       time.Time `json:"time"`
+	Status     int       `json:"status"` // 401 или 403
+	Action     string    `json:"action"` // например, "posts.update"
+	Reason     string    `json:"reason"`
+	Subject    string    `json:"subject,omitempty"`
+	UserID     int64     `json:"user_id,omitempty"`
+	Role       Role      `json:"role,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote_addr"`
+}
+
+// Audit записывает отказ в доступе к запросу r в журнал.
+// Клиент берётся из контекста запроса, если он аутентифицирован.
+func Audit(r *http.Request, status int, action, reason string) {
+	e := AuditEvent{
+		Time:       time.Now(),
+		Status:     status,
+		Action:     action,
+		Reason:     reason,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+	}
+	if p, ok := FromContext(r.Context()); ok {
+		e.Subject, e.UserID, e.Role = p.Subject, p.UserID, p.Role
+	}
+	auditDenied.Add(fmt.Sprint(status), 1)
+	if status == http.StatusUnauthorized && !allowUnauthorized(r, e.Time) {
+		return
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		logger.SetLogCtx(r.Context(), e.Time, AuditSource, fmt.Sprintf("%v", err))
+		return
+	}
+	logger.SetLogLevel(r.Context(), zerolog.WarnLevel, e.Time, AuditSource, string(data))
+}
+
+// Forbidden записывает отказ в аудит и отвечает 403.
+func Forbidden(w http.ResponseWriter, r *http.Request, action, reason string) {
+	Audit(r, http.StatusForbidden, action, reason)
+	http.Error(w, "forbidden: "+reason, http.StatusForbidden)
+}
+
+// Разрешение записи отказа 401 для адреса клиента r. При смене окна
+// пропущенные записи прошлого окна сводятся в одну запись на адрес.
+func allowUnauthorized(r *http.Request, now time.Time) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	unauth.mu.Lock()
+	var suppressed map[string]int
+	if now.Sub(unauth.start) >= unauthWindow {
+		for h, n := range unauth.count {
+			if n > unauthBurst {
+				if suppressed == nil {
+					suppressed = make(map[string]int)
+				}
+				suppressed[h] = n - unauthBurst
+			}
+		}
+		unauth.start, unauth.count = now, make(map[string]int)
+	}
+	unauth.count[host]++
+	ok := unauth.count[host] <= unauthBurst
+	unauth.mu.Unlock()
+
+	hosts := make([]string, 0, len(suppressed))
+	for h := range suppressed {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	for _, h := range hosts {
+		logger.SetLogLevel(r.Context(), zerolog.WarnLevel, now, AuditSource,
+			fmt.Sprintf("%d more 401 denials from %s not logged individually (limit %d per %s)", suppressed[h], h, unauthBurst, unauthWindow))
+	}
+	return ok
+}
@@ -0,0 +1,118 @@
+// Пакет auth - аутентификация запросов к API.
+//
+// Поддерживаются ключи API (хранятся в основной БД в виде SHA-256, сам ключ
+// показывается один раз при создании) и JWT с подписью HS256 или RS256.
+// Учётные данные передаются заголовком "Authorization: Bearer <ключ или JWT>"
+// или "X-API-Key: <ключ>". Чтение публично, изменения требуют учётных данных.
+//
+// Права определяются ролью пользователя (User): ключ API привязан
+// к пользователю, JWT сопоставляется с ним по claim sub. Отказы в доступе
+// записываются в журнал аудита (Audit).
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// KeyPrefix - начало ключей API; по нему ключ отличается от JWT.
+const KeyPrefix = "gnk_"
+
+// ErrUnauthorized - учётные данные неверны, устарели или отозваны.
+var ErrUnauthorized = errors.New("invalid credentials")
+
+// Key - ключ API. Сам ключ не хранится, только его хэш.
+type Key struct {
+	ID        int64  `json:"id"          bson:"_id"`
+	Name      string `json:"name"        bson:"name"`
+	Prefix    string `json:"prefix"      bson:"prefix"` // начало ключа для опознания в списке
+	Hash      string `json:"-"           bson:"hash"`
+	UserID    int64  `json:"user_id"     bson:"user_id"`    // 0 - служебный ключ с правами администратора
+	CreatedAt int64  `json:"created_at"  bson:"created_at"` // миллисекунды Unix
+}
+
+// KeyStore - хранение ключей API.
+// Отсутствующий ключ - ошибка, оборачивающая storage.ErrNotFound.
+type KeyStore interface {
+	APIKeys() ([]Key, error)
+	APIKeyByHash(hash string) (Key, error)
+	AddAPIKey(Key) (int64, error)
+	DeleteAPIKey(id int64) error
+}
+
+// Способы аутентификации.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal - аутентифицированный клиент.
+type Principal struct {
+	Subject string // "apikey:<ID>" или claim sub токена
+	Method  string
+	KeyID   int64  // для ключа API
+	Claims  Claims // для JWT
+
+	UserID   int64 // 0 - пользователь не найден или служебный ключ
+	Role     Role  // пустая - прав на изменения нет
+	AuthorID int64 // автор, от имени которого действует клиент
+}
+
+// Has сообщает, есть ли у клиента права роли min.
+func (p Principal) Has(min Role) bool {
+	return p.Role.Includes(min)
+}
+
+// CanEditAuthor сообщает, может ли клиент изменять автора authorID
+// и его публикации: свои - автор, любые - редактор и администратор.
+func (p Principal) CanEditAuthor(authorID int64) bool {
+	if p.Has(RoleEditor) {
+		return true
+	}
+	return p.Has(RoleAuthor) && p.AuthorID != 0 && p.AuthorID == authorID
+}
+
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с клиентом p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает клиента запроса; ok == false для анонимного запроса.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// NewKey создаёт ключ API с именем name. Возвращает ключ для клиента
+// и запись для хранения.
+func NewKey(name string, createdAt int64) (string, Key, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", Key{}, err
+	}
+	secret := KeyPrefix + hex.EncodeToString(b)
+	return secret, Key{
+		Name:      name,
+		Prefix:    secret[:len(KeyPrefix)+8],
+		Hash:      HashKey(secret),
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// HashKey - хэш ключа API для хранения и поиска.
+// Ключ случаен и длинен, поэтому соль и медленный хэш не нужны.
+func HashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsKey сообщает, похожа ли строка на ключ API (а не на JWT).
+func IsKey(s string) bool {
+	return strings.HasPrefix(s, KeyPrefix)
+}
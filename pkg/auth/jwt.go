@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// JWTConfig - параметры проверки JWT. Принимаются только алгоритмы,
+// для которых задан ключ; "none" не принимается никогда.
+type JWTConfig struct {
+	HS256Secret []byte         // общий секрет HS256
+	RS256Key    *rsa.PublicKey // открытый ключ RS256
+	Issuer      string         // ожидаемый iss, "" - не проверяется
+	Audience    string         // ожидаемый aud, "" - не проверяется
+	Leeway      time.Duration  // допуск расхождения часов для exp и nbf
+}
+
+// Enabled сообщает, задан ли хотя бы один ключ.
+func (c JWTConfig) Enabled() bool {
+	return len(c.HS256Secret) > 0 || c.RS256Key != nil
+}
+
+// Claims - проверенные поля токена. Все поля - в Raw.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt int64
+	Raw       map[string]interface{}
+}
+
+// ParseJWT проверяет подпись и сроки токена и возвращает его поля.
+func (c JWTConfig) ParseJWT(token string) (Claims, error) {
+	var claims Claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, fmt.Errorf("%w: malformed token", ErrUnauthorized)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return claims, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, fmt.Errorf("%w: malformed signature", ErrUnauthorized)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch {
+	case header.Alg == "HS256" && len(c.HS256Secret) > 0:
+		mac := hmac.New(sha256.New, c.HS256Secret)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return claims, fmt.Errorf("%w: bad signature", ErrUnauthorized)
+		}
+	case header.Alg == "RS256" && c.RS256Key != nil:
+		sum := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(c.RS256Key, crypto.SHA256, sum[:], sig); err != nil {
+			return claims, fmt.Errorf("%w: bad signature", ErrUnauthorized)
+		}
+	default:
+		return claims, fmt.Errorf("%w: unsupported algorithm %q", ErrUnauthorized, header.Alg)
+	}
+
+	if err := decodeSegment(parts[1], &claims.Raw); err != nil {
+		return claims, err
+	}
+	claims.Subject, _ = claims.Raw["sub"].(string)
+	claims.Issuer, _ = claims.Raw["iss"].(string)
+	switch aud := claims.Raw["aud"].(type) {
+	case string:
+		claims.Audience = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				claims.Audience = append(claims.Audience, s)
+			}
+		}
+	}
+
+	now := time.Now()
+	exp, hasExp := numericClaim(claims.Raw, "exp")
+	if !hasExp {
+		return claims, fmt.Errorf("%w: token has no exp", ErrUnauthorized)
+	}
+	claims.ExpiresAt = exp
+	if now.Add(-c.Leeway).After(time.Unix(exp, 0)) {
+		return claims, fmt.Errorf("%w: token expired", ErrUnauthorized)
+	}
+	if nbf, ok := numericClaim(claims.Raw, "nbf"); ok && now.Add(c.Leeway).Before(time.Unix(nbf, 0)) {
+		return claims, fmt.Errorf("%w: token not valid yet", ErrUnauthorized)
+	}
+	if c.Issuer != "" && claims.Issuer != c.Issuer {
+		return claims, fmt.Errorf("%w: unexpected issuer", ErrUnauthorized)
+	}
+	if c.Audience != "" && !contains(claims.Audience, c.Audience) {
+		return claims, fmt.Errorf("%w: unexpected audience", ErrUnauthorized)
+	}
+	if claims.Subject == "" {
+		return claims, fmt.Errorf("%w: token has no sub", ErrUnauthorized)
+	}
+	return claims, nil
+}
+
+// LoadRSAPublicKey читает открытый ключ RS256 из PEM-файла
+// (PUBLIC KEY, RSA PUBLIC KEY или CERTIFICATE).
+func LoadRSAPublicKey(filename string) (*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data in " + filename)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key: " + filename)
+	}
+	return rsaKey, nil
+}
+
+func decodeSegment(seg string, dst interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("%w: malformed token", ErrUnauthorized)
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("%w: malformed token", ErrUnauthorized)
+	}
+	return nil
+}
+
+// Role возвращает роль из claims "role" и "author_id" для пользователей,
+// которых нет в БД. Неизвестная роль - пустая.
+func (c Claims) Role() (Role, int64) {
+	s, _ := c.Raw["role"].(string)
+	role, err := ParseRole(s)
+	if err != nil {
+		return "", 0
+	}
+	authorID, _ := numericClaim(c.Raw, "author_id")
+	return role, authorID
+}
+
+func numericClaim(raw map[string]interface{}, name string) (int64, bool) {
+	v, ok := raw[name].(float64)
+	return int64(v), ok
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-hs256-secret"
+
+// Ключ RS256 для тестов (создаётся один раз).
+var testRSAKey = func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// Токен с заголовком alg и полями claims. Подпись: HMAC ключом key
+// (string), RSA ключом key (*rsa.PrivateKey) или пустая (nil).
+func makeJWT(t *testing.T, alg string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	enc := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := enc(map[string]string{"alg": alg, "typ": "JWT"}) + "." + enc(claims)
+
+	var sig []byte
+	switch k := key.(type) {
+	case string:
+		mac := hmac.New(sha256.New, []byte(k))
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// Поля действующего токена с изменениями changes (nil - удалить поле).
+func claimsWith(changes map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub": "alice",
+		"iss": "https://idp.example",
+		"aud": "gonews",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range changes {
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+func TestParseJWT(t *testing.T) {
+	now := time.Now()
+	hs := JWTConfig{HS256Secret: []byte(testSecret), Leeway: time.Minute}
+	rs := JWTConfig{RS256Key: &testRSAKey.PublicKey, Leeway: time.Minute}
+	strict := JWTConfig{HS256Secret: []byte(testSecret), Issuer: "https://idp.example", Audience: "gonews", Leeway: time.Minute}
+
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		cfg   JWTConfig
+		token string
+		ok    bool
+	}{
+		{"HS256 valid", hs, makeJWT(t, "HS256", testSecret, claimsWith(nil)), true},
+		{"RS256 valid", rs, makeJWT(t, "RS256", testRSAKey, claimsWith(nil)), true},
+		{"issuer and audience valid", strict, makeJWT(t, "HS256", testSecret, claimsWith(nil)), true},
+
+		{"alg none", hs, makeJWT(t, "none", nil, claimsWith(nil)), false},
+		{"alg none without keys", JWTConfig{}, makeJWT(t, "none", nil, claimsWith(nil)), false},
+		{"HS256 with only RS256 key", rs, makeJWT(t, "HS256", testSecret, claimsWith(nil)), false},
+		{"RS256 with only HS256 secret", hs, makeJWT(t, "RS256", testRSAKey, claimsWith(nil)), false},
+		{"unknown alg", hs, makeJWT(t, "HS512", testSecret, claimsWith(nil)), false},
+
+		{"HS256 wrong secret", hs, makeJWT(t, "HS256", "other-secret", claimsWith(nil)), false},
+		{"RS256 wrong key", rs, makeJWT(t, "RS256", otherRSA, claimsWith(nil)), false},
+		{"tampered claims", hs, tamper(makeJWT(t, "HS256", testSecret, claimsWith(nil))), false},
+		{"malformed", hs, "abc.def", false},
+		{"bad signature encoding", hs, makeJWT(t, "HS256", testSecret, claimsWith(nil)) + "!", false},
+
+		{"expired", hs, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()})), false},
+		{"expired within leeway", hs, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()})), true},
+		{"no exp", hs, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"exp": nil})), false},
+		{"nbf in future", hs, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"nbf": now.Add(5 * time.Minute).Unix()})), false},
+		{"nbf within leeway", hs, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"nbf": now.Add(30 * time.Second).Unix()})), true},
+		{"nbf in past", hs, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"nbf": now.Add(-time.Hour).Unix()})), true},
+
+		{"wrong issuer", strict, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"iss": "https://evil.example"})), false},
+		{"no issuer", strict, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"iss": nil})), false},
+		{"wrong audience", strict, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"aud": "other"})), false},
+		{"no audience", strict, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"aud": nil})), false},
+		{"audience array", strict, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"aud": []string{"other", "gonews"}})), true},
+		{"audience array without ours", strict, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"aud": []string{"other", "more"}})), false},
+		{"audience not checked", hs, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"aud": "other"})), true},
+
+		{"no sub", hs, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"sub": nil})), false},
+		{"empty sub", hs, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"sub": ""})), false},
+		{"non-string sub", hs, makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"sub": 42})), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.cfg.ParseJWT(tt.token)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("ParseJWT: %v", err)
+				}
+				if claims.Subject != "alice" {
+					t.Errorf("Subject = %q, want alice", claims.Subject)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("ParseJWT accepted the token")
+			}
+			if !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("ParseJWT error %v does not wrap ErrUnauthorized", err)
+			}
+		})
+	}
+}
+
+func TestParseJWTClaims(t *testing.T) {
+	cfg := JWTConfig{HS256Secret: []byte(testSecret)}
+	exp := time.Now().Add(time.Hour).Unix()
+	token := makeJWT(t, "HS256", testSecret, claimsWith(map[string]interface{}{"aud": []string{"a", "b"}, "exp": exp}))
+	claims, err := cfg.ParseJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Issuer != "https://idp.example" || claims.ExpiresAt != exp ||
+		len(claims.Audience) != 2 || claims.Audience[0] != "a" || claims.Audience[1] != "b" {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+// Токен с изменённым полем sub при прежней подписи.
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	var claims map[string]interface{}
+	data, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	json.Unmarshal(data, &claims)
+	claims["sub"] = "mallory"
+	data, _ = json.Marshal(claims)
+	return parts[0] + "." + base64.RawURLEncoding.EncodeToString(data) + "." + parts[2]
+}
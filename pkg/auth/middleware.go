@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"GoNews/pkg/logger"
+	"GoNews/pkg/storage"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Authenticator проверяет учётные данные запросов.
+type Authenticator struct {
+	keys  KeyStore  // nil - ключи API не принимаются
+	users UserStore // nil - все клиенты действуют как администраторы
+	jwt   JWTConfig
+
+	limit RejectLimit // nil - отказы 401 не ограничиваются
+}
+
+// RejectLimit вызывается перед ответом 401. false - лимит отказов клиента
+// исчерпан и ответ (429) уже отправлен: 401 и запись аудита пропускаются.
+type RejectLimit func(w http.ResponseWriter, r *http.Request) bool
+
+// LimitRejected задаёт ограничение частоты отказов 401 (например, по
+// IP-адресу), чтобы перебор учётных данных не заполнял журнал аудита.
+// Вызывается до Middleware.
+func (a *Authenticator) LimitRejected(limit RejectLimit) {
+	a.limit = limit
+}
+
+// New создаёт проверку ключей из keys и токенов по jwt;
+// роли клиентов берутся из users.
+func New(keys KeyStore, users UserStore, jwt JWTConfig) *Authenticator {
+	return &Authenticator{keys: keys, users: users, jwt: jwt}
+}
+
+// Authenticate проверяет учётные данные запроса.
+// ok == false - учётных данных нет; ошибка ErrUnauthorized - они неверны.
+func (a *Authenticator) Authenticate(r *http.Request) (p Principal, ok bool, err error) {
+	cred := r.Header.Get("X-API-Key")
+	if cred == "" {
+		h := r.Header.Get("Authorization")
+		if h == "" {
+			return p, false, nil
+		}
+		const bearer = "bearer "
+		if len(h) <= len(bearer) || !strings.EqualFold(h[:len(bearer)], bearer) {
+			return p, true, fmt.Errorf("%w: expected Bearer authorization", ErrUnauthorized)
+		}
+		cred = strings.TrimSpace(h[len(bearer):])
+	}
+
+	if IsKey(cred) {
+		if a.keys == nil {
+			return p, true, fmt.Errorf("%w: API keys are not accepted", ErrUnauthorized)
+		}
+		key, err := a.keys.APIKeyByHash(HashKey(cred))
+		if errors.Is(err, storage.ErrNotFound) {
+			return p, true, fmt.Errorf("%w: unknown API key", ErrUnauthorized)
+		}
+		if err != nil {
+			return p, true, err
+		}
+		p = Principal{Subject: "apikey:" + strconv.FormatInt(key.ID, 10), Method: MethodAPIKey, KeyID: key.ID}
+		if key.UserID == 0 {
+			p.Role = RoleAdmin
+			return p, true, nil
+		}
+		return p, true, a.resolve(&p, func() (User, error) { return a.users.UserByID(key.UserID) })
+	}
+
+	if !a.jwt.Enabled() {
+		return p, true, fmt.Errorf("%w: JWT is not accepted", ErrUnauthorized)
+	}
+	claims, err := a.jwt.ParseJWT(cred)
+	if err != nil {
+		return p, true, err
+	}
+	p = Principal{Subject: claims.Subject, Method: MethodJWT, Claims: claims}
+	if err := a.resolve(&p, func() (User, error) { return a.users.UserByName(claims.Subject) }); err != nil {
+		return p, true, err
+	}
+	if p.Role == "" {
+		// Пользователя нет в БД: роль из claims токена, подписанного издателем.
+		p.Role, p.AuthorID = claims.Role()
+	}
+	return p, true, nil
+}
+
+// Заполнение роли клиента по пользователю из lookup. Без хранилища
+// пользователей клиент получает права администратора, неизвестный
+// пользователь - никаких прав на изменения.
+func (a *Authenticator) resolve(p *Principal, lookup func() (User, error)) error {
+	if a.users == nil {
+		p.Role = RoleAdmin
+		return nil
+	}
+	u, err := lookup()
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p.UserID, p.Role, p.AuthorID = u.ID, u.Role, u.AuthorID
+	return nil
+}
+
+// Middleware проверяет учётные данные и передаёт клиента обработчикам через
+// контекст (FromContext). Запросы на изменение и запросы к путям с префиксами
+// private без учётных данных отклоняются с кодом 401; чтение остальных путей
+// доступно анонимно. Неверные учётные данные отклоняются всегда.
+func (a *Authenticator) Middleware(private ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok, err := a.Authenticate(r)
+			switch {
+			case errors.Is(err, ErrUnauthorized):
+				a.reject(w, r, err.Error())
+				return
+			case err != nil:
+				logger.SetLogCtx(r.Context(), time.Now(), "auth", fmt.Sprintf("%v", err))
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			case ok:
+				logger.Annotate(r.Context(), "subject", p.Subject)
+				r = r.WithContext(WithPrincipal(r.Context(), p))
+			case !safeMethod(r.Method) || hasPrefix(r.URL.Path, private):
+				a.reject(w, r, "credentials required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Отказ 401 с записью аудита, если лимит отказов клиента не исчерпан.
+func (a *Authenticator) reject(w http.ResponseWriter, r *http.Request, reason string) {
+	if a.limit != nil && !a.limit(w, r) {
+		return
+	}
+	Audit(r, http.StatusUnauthorized, "authenticate", reason)
+	unauthorized(w, reason)
+}
+
+func unauthorized(w http.ResponseWriter, mess string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="GoNews"`)
+	http.Error(w, mess, http.StatusUnauthorized)
+}
+
+// Методы только для чтения.
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func hasPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"GoNews/pkg/logger"
+	"GoNews/pkg/storage"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Журнал аудита - во временный каталог, а не в logs/ пакета.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "auth-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := logger.OpenFile(filepath.Join(dir, "log.json"), logger.RotateOptions{}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	logger.CloseFile()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// KeyStore в памяти для тестов.
+type memKeys map[string]Key
+
+func (k memKeys) APIKeys() ([]Key, error) {
+	var keys []Key
+	for _, key := range k {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (k memKeys) APIKeyByHash(hash string) (Key, error) {
+	key, ok := k[hash]
+	if !ok {
+		return Key{}, fmt.Errorf("api key: %w", storage.ErrNotFound)
+	}
+	return key, nil
+}
+
+func (k memKeys) AddAPIKey(key Key) (int64, error) {
+	k[key.Hash] = key
+	return key.ID, nil
+}
+
+func (k memKeys) DeleteAPIKey(id int64) error {
+	for hash, key := range k {
+		if key.ID == id {
+			delete(k, hash)
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
+
+func TestMiddleware(t *testing.T) {
+	secret, key, err := NewKey("test", time.Now().UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.ID = 7
+	keys := memKeys{}
+	keys.AddAPIKey(key)
+
+	a := New(keys, nil, JWTConfig{HS256Secret: []byte(testSecret)})
+	validJWT := makeJWT(t, "HS256", testSecret, claimsWith(nil))
+	badJWT := makeJWT(t, "HS256", "other-secret", claimsWith(nil))
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		header  string // заголовок "Имя: значение"
+		status  int
+		subject string // клиент в контексте обработчика
+	}{
+		{"anonymous read", http.MethodGet, "/posts", "", http.StatusOK, ""},
+		{"anonymous HEAD", http.MethodHead, "/posts/1", "", http.StatusOK, ""},
+		{"anonymous write", http.MethodPost, "/posts", "", http.StatusUnauthorized, ""},
+		{"anonymous delete", http.MethodDelete, "/posts/1", "", http.StatusUnauthorized, ""},
+		{"anonymous private read", http.MethodGet, "/admin/info", "", http.StatusUnauthorized, ""},
+
+		{"invalid key on read", http.MethodGet, "/posts", "X-API-Key: " + KeyPrefix + "0000", http.StatusUnauthorized, ""},
+		{"invalid bearer key on read", http.MethodGet, "/posts", "Authorization: Bearer " + KeyPrefix + "0000", http.StatusUnauthorized, ""},
+		{"invalid JWT on read", http.MethodGet, "/posts", "Authorization: Bearer " + badJWT, http.StatusUnauthorized, ""},
+		{"garbage bearer on read", http.MethodGet, "/posts", "Authorization: Bearer abc", http.StatusUnauthorized, ""},
+		{"basic auth on read", http.MethodGet, "/posts", "Authorization: Basic dXNlcjpwYXNz", http.StatusUnauthorized, ""},
+
+		{"key write", http.MethodPost, "/posts", "X-API-Key: " + secret, http.StatusOK, "apikey:7"},
+		{"bearer key private", http.MethodGet, "/admin/info", "Authorization: Bearer " + secret, http.StatusOK, "apikey:7"},
+		{"JWT write", http.MethodPut, "/posts", "Authorization: bearer " + validJWT, http.StatusOK, "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *Principal
+			h := a.Middleware("/admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				p, ok := FromContext(r.Context())
+				if ok {
+					got = &p
+				}
+			}))
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				kv := strings.SplitN(tt.header, ": ", 2)
+				r.Header.Set(kv[0], kv[1])
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusUnauthorized {
+				if w.Header().Get("WWW-Authenticate") == "" {
+					t.Error("401 without WWW-Authenticate")
+				}
+				return
+			}
+			switch {
+			case tt.subject == "" && got != nil:
+				t.Errorf("anonymous request got principal %+v", *got)
+			case tt.subject != "" && got == nil:
+				t.Error("no principal in context")
+			case tt.subject != "" && (got.Subject != tt.subject || got.Role != RoleAdmin):
+				t.Errorf("principal = %+v, want subject %s with role admin", *got, tt.subject)
+			}
+		})
+	}
+}
+
+// Пользователь ключа и JWT определяет роль клиента.
+func TestMiddlewareUserRole(t *testing.T) {
+	secret, key, err := NewKey("test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.ID, key.UserID = 1, 2
+	keys := memKeys{}
+	keys.AddAPIKey(key)
+	users := &memUsers{list: []User{{ID: 2, Name: "alice", Role: RoleAuthor, AuthorID: 5}}}
+
+	a := New(keys, users, JWTConfig{HS256Secret: []byte(testSecret)})
+	for _, header := range []string{
+		"Bearer " + secret,
+		"Bearer " + makeJWT(t, "HS256", testSecret, claimsWith(nil)),
+	} {
+		var got Principal
+		h := a.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, _ = FromContext(r.Context())
+		}))
+		r := httptest.NewRequest(http.MethodPost, "/posts", nil)
+		r.Header.Set("Authorization", header)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d (%s)", w.Code, w.Body.String())
+		}
+		if got.UserID != 2 || got.Role != RoleAuthor || got.AuthorID != 5 {
+			t.Errorf("principal = %+v, want user 2 with role author of author 5", got)
+		}
+	}
+}
+
+// Исчерпанный лимит отказов заменяет ответ 401.
+func TestMiddlewareLimitRejected(t *testing.T) {
+	a := New(memKeys{}, nil, JWTConfig{})
+	a.LimitRejected(func(w http.ResponseWriter, r *http.Request) bool {
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+		return false
+	})
+	called := false
+	h := a.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+
+	r := httptest.NewRequest(http.MethodPost, "/posts", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if called {
+		t.Error("handler called for rejected request")
+	}
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("WWW-Authenticate") != "" {
+		t.Errorf("status = %d, WWW-Authenticate = %q; want 429 without challenge",
+			w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+// UserStore в памяти для тестов.
+type memUsers struct {
+	list []User
+}
+
+func (u *memUsers) Users() ([]User, error) { return u.list, nil }
+
+func (u *memUsers) UserByID(id int64) (User, error) {
+	for _, user := range u.list {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return User{}, storage.ErrNotFound
+}
+
+func (u *memUsers) UserByName(name string) (User, error) {
+	for _, user := range u.list {
+		if user.Name == name {
+			return user, nil
+		}
+	}
+	return User{}, storage.ErrNotFound
+}
+
+func (u *memUsers) AddUser(user User) (int64, error) {
+	u.list = append(u.list, user)
+	return user.ID, nil
+}
+
+func (u *memUsers) UpdateUser(User) error  { return nil }
+func (u *memUsers) DeleteUser(int64) error { return nil }
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUserExists - пользователь с таким именем уже есть.
+var ErrUserExists = errors.New("user already exists")
+
+// Role - роль пользователя.
+type Role string
+
+// Роли по возрастанию прав: автор изменяет только себя и свои публикации,
+// редактор - любых авторов и публикации, администратор - ещё и подписки webhooks.
+const (
+	RoleAuthor Role = "author"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRank = map[Role]int{RoleAuthor: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// ParseRole проверяет название роли.
+func ParseRole(s string) (Role, error) {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := roleRank[r]; !ok {
+		return "", fmt.Errorf("unknown role %q: expected author, editor or admin", s)
+	}
+	return r, nil
+}
+
+// Includes сообщает, даёт ли роль r права роли min.
+func (r Role) Includes(min Role) bool {
+	return roleRank[r] > 0 && roleRank[r] >= roleRank[min]
+}
+
+// User - пользователь API. Пользователь с ролью author связан с автором
+// storage.Author через AuthorID; у редакторов и администраторов связь
+// необязательна.
+type User struct {
+	ID        int64  `json:"id"          bson:"_id"`
+	Name      string `json:"name"        bson:"name"` // уникально; совпадает с claim sub в JWT
+	Role      Role   `json:"role"        bson:"role"`
+	AuthorID  int64  `json:"author_id"   bson:"author_id"`  // 0 - не связан с автором
+	CreatedAt int64  `json:"created_at"  bson:"created_at"` // миллисекунды Unix
+}
+
+// Validate проверяет пользователя перед сохранением.
+func (u User) Validate() error {
+	if strings.TrimSpace(u.Name) == "" {
+		return fmt.Errorf("user name must not be empty")
+	}
+	if _, err := ParseRole(string(u.Role)); err != nil {
+		return err
+	}
+	if u.Role == RoleAuthor && u.AuthorID <= 0 {
+		return fmt.Errorf("user with role author must be linked to an author")
+	}
+	if u.AuthorID < 0 {
+		return fmt.Errorf("author id must not be negative")
+	}
+	return nil
+}
+
+// UserStore - хранение пользователей и их ролей.
+// Отсутствующий пользователь - ошибка, оборачивающая storage.ErrNotFound.
+type UserStore interface {
+	Users() ([]User, error)
+	UserByID(id int64) (User, error)
+	UserByName(name string) (User, error)
+	AddUser(User) (int64, error)
+	UpdateUser(User) error
+	DeleteUser(id int64) error
+}
@@ -0,0 +1,377 @@
+// Пакет backup создаёт и восстанавливает переносимые архивы БД.
+//
+// Архив - zip-файл с записями:
+//
+//	authors.ndjson - авторы, по одному JSON-объекту в строке;
+//	posts.ndjson   - публикации;
+//	manifest.json  - версия формата и схемы, источник, время создания,
+//	                 число записей и SHA-256 каждого файла данных.
+//
+// Архив создаётся из БД, поддерживающей чтение снимка (storage.Snapshotter),
+// и восстанавливается в любую реализацию storage.Interface: данные содержат
+// только хранимые поля, ID сохраняются.
+package backup
+
+import (
+	"GoNews/pkg/importer"
+	"GoNews/pkg/storage"
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// FormatVersion - версия формата архива (состав и назначение файлов).
+	FormatVersion = 1
+	// SchemaVersion - версия схемы записей authors/posts.
+	SchemaVersion = 1
+)
+
+// Имена файлов в архиве.
+const (
+	manifestFile = "manifest.json"
+	authorsFile  = "authors.ndjson"
+	postsFile    = "posts.ndjson"
+)
+
+// ErrCorrupt - архив повреждён или не соответствует манифесту.
+var ErrCorrupt = errors.New("corrupt backup archive")
+
+// Manifest - описание архива.
+type Manifest struct {
+	FormatVersion int        `json:"format_version"`
+	SchemaVersion int        `json:"schema_version"`
+	Source        string     `json:"source"`     // БД, из которой создан архив
+	CreatedAt     string     `json:"created_at"` // RFC 3339, UTC
+	Files         []FileInfo `json:"files"`
+}
+
+// FileInfo - файл данных архива.
+type FileInfo struct {
+	Name    string `json:"name"`
+	Entity  string `json:"entity"` // importer.Authors или importer.Posts
+	Records int    `json:"records"`
+	SHA256  string `json:"sha256"`
+}
+
+// Записи архива: только хранимые поля, вычисляемые (author_name, *_txt) не сохраняются.
+type authorRecord struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type postRecord struct {
+	ID          int64  `json:"id"`
+	AuthorID    int64  `json:"author_id"`
+	Title       string `json:"title"`
+	Content     string `json:"content"`
+	CreatedAt   int64  `json:"created_at"`
+	PublishedAt int64  `json:"published_at"`
+}
+
+// Write записывает архив с данными db в w. Авторы и публикации читаются
+// из одного снимка БД (storage.Snapshotter), поэтому архив соответствует
+// одному моменту времени; db должна быть БД без обёрток.
+func Write(ctx context.Context, db storage.Interface, w io.Writer) (Manifest, error) {
+	now := time.Now()
+	m := Manifest{
+		FormatVersion: FormatVersion,
+		SchemaVersion: SchemaVersion,
+		Source:        db.GetInform(),
+		CreatedAt:     now.UTC().Format(time.RFC3339),
+	}
+
+	snap, ok := db.(storage.Snapshotter)
+	if !ok {
+		return m, fmt.Errorf("%s does not support snapshot reads", db.GetInform())
+	}
+	authors, posts, err := snap.Snapshot(ctx)
+	if err != nil {
+		return m, fmt.Errorf("read snapshot: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return m, err
+	}
+
+	sort.Slice(authors, func(i, j int) bool { return authors[i].ID < authors[j].ID })
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID < posts[j].ID })
+
+	zw := zip.NewWriter(w)
+
+	fi, err := writeRecords(zw, authorsFile, importer.Authors, now, len(authors), func(i int) interface{} {
+		a := authors[i]
+		return authorRecord{ID: a.ID, Name: a.Name}
+	})
+	if err != nil {
+		return m, err
+	}
+	m.Files = append(m.Files, fi)
+
+	fi, err = writeRecords(zw, postsFile, importer.Posts, now, len(posts), func(i int) interface{} {
+		p := posts[i]
+		return postRecord{ID: p.ID, AuthorID: p.AuthorID, Title: p.Title, Content: p.Content, CreatedAt: p.CreatedAt, PublishedAt: p.PublishedAt}
+	})
+	if err != nil {
+		return m, err
+	}
+	m.Files = append(m.Files, fi)
+
+	mw, err := create(zw, manifestFile, now)
+	if err != nil {
+		return m, err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return m, err
+	}
+
+	return m, zw.Close()
+}
+
+// Запись n записей в файл архива name в формате NDJSON с подсчётом SHA-256.
+func writeRecords(zw *zip.Writer, name, entity string, modified time.Time, n int, record func(i int) interface{}) (FileInfo, error) {
+	fi := FileInfo{Name: name, Entity: entity, Records: n}
+
+	fw, err := create(zw, name, modified)
+	if err != nil {
+		return fi, err
+	}
+	h := sha256.New()
+	enc := json.NewEncoder(io.MultiWriter(fw, h))
+	for i := 0; i < n; i++ {
+		if err := enc.Encode(record(i)); err != nil {
+			return fi, err
+		}
+	}
+
+	fi.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return fi, nil
+}
+
+// Создание сжатого файла архива с временем изменения modified.
+func create(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+}
+
+// CreateFile записывает архив в файл filename.
+// Архив пишется во временный файл и переименовывается после успешной записи,
+// поэтому прерванное создание не оставляет неполного архива.
+func CreateFile(ctx context.Context, db storage.Interface, filename string) (Manifest, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	bw := bufio.NewWriter(tmp)
+	m, err := Write(ctx, db, bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return m, err
+	}
+
+	return m, os.Rename(tmp.Name(), filename)
+}
+
+// Verify проверяет архив filename: версии, состав файлов, число записей
+// и контрольные суммы. Возвращает манифест архива.
+func Verify(filename string) (Manifest, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer zr.Close()
+
+	return verify(&zr.Reader)
+}
+
+func verify(zr *zip.Reader) (Manifest, error) {
+	var m Manifest
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	mf, ok := files[manifestFile]
+	if !ok {
+		return m, fmt.Errorf("%w: %s not found", ErrCorrupt, manifestFile)
+	}
+	if err := readJSON(mf, &m); err != nil {
+		return m, fmt.Errorf("%w: %s: %v", ErrCorrupt, manifestFile, err)
+	}
+	if m.FormatVersion < 1 || m.FormatVersion > FormatVersion {
+		return m, fmt.Errorf("unsupported archive format version %d, supported up to %d", m.FormatVersion, FormatVersion)
+	}
+	if m.SchemaVersion < 1 || m.SchemaVersion > SchemaVersion {
+		return m, fmt.Errorf("unsupported schema version %d, supported up to %d", m.SchemaVersion, SchemaVersion)
+	}
+
+	for _, entity := range []string{importer.Authors, importer.Posts} {
+		if _, ok := m.file(entity); !ok {
+			return m, fmt.Errorf("%w: no %s file in manifest", ErrCorrupt, entity)
+		}
+	}
+
+	for _, fi := range m.Files {
+		f, ok := files[fi.Name]
+		if !ok {
+			return m, fmt.Errorf("%w: %s listed in manifest but missing", ErrCorrupt, fi.Name)
+		}
+		sum, lines, err := checksum(f)
+		if err != nil {
+			return m, fmt.Errorf("%w: %s: %v", ErrCorrupt, fi.Name, err)
+		}
+		if sum != fi.SHA256 {
+			return m, fmt.Errorf("%w: %s: checksum mismatch", ErrCorrupt, fi.Name)
+		}
+		if lines != fi.Records {
+			return m, fmt.Errorf("%w: %s: %d records, manifest says %d", ErrCorrupt, fi.Name, lines, fi.Records)
+		}
+	}
+
+	return m, nil
+}
+
+// Файл данных сущности entity.
+func (m Manifest) file(entity string) (FileInfo, bool) {
+	for _, fi := range m.Files {
+		if fi.Entity == entity {
+			return fi, true
+		}
+	}
+	return FileInfo{}, false
+}
+
+// RestoreOptions - параметры восстановления.
+type RestoreOptions struct {
+	Mode      importer.Mode // поведение при существующем ID, по умолчанию importer.ModeFail
+	BatchSize int           // записей в пакете
+	Progress  func(entity string, st importer.Stats)
+}
+
+// RestoreResult - итоги восстановления.
+type RestoreResult struct {
+	Manifest Manifest       `json:"manifest"`
+	Authors  importer.Stats `json:"authors"`
+	Posts    importer.Stats `json:"posts"`
+}
+
+// Restore проверяет архив filename и загружает его данные в db:
+// сначала авторов, затем публикации. При повреждённом архиве БД не изменяется.
+func Restore(ctx context.Context, db storage.Interface, filename string, opts RestoreOptions) (RestoreResult, error) {
+	var res RestoreResult
+
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return res, err
+	}
+	defer zr.Close()
+
+	res.Manifest, err = verify(&zr.Reader)
+	if err != nil {
+		return res, err
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	stats := map[string]*importer.Stats{importer.Authors: &res.Authors, importer.Posts: &res.Posts}
+	for _, entity := range []string{importer.Authors, importer.Posts} {
+		fi, _ := res.Manifest.file(entity)
+
+		rc, err := files[fi.Name].Open()
+		if err != nil {
+			return res, err
+		}
+
+		iopts := importer.Options{
+			Entity:    entity,
+			Format:    importer.FormatNDJSON,
+			Mode:      opts.Mode,
+			BatchSize: opts.BatchSize,
+		}
+		if opts.Progress != nil {
+			entity := entity
+			iopts.Progress = func(st importer.Stats) { opts.Progress(entity, st) }
+		}
+
+		st, err := importer.Import(ctx, db, rc, iopts)
+		rc.Close()
+		*stats[entity] = st
+		if err != nil {
+			return res, fmt.Errorf("restore %s: %w", entity, err)
+		}
+		if st.Failed > 0 {
+			return res, fmt.Errorf("restore %s: %d of %d records rejected", entity, st.Failed, st.Read)
+		}
+	}
+
+	return res, nil
+}
+
+func readJSON(f *zip.File, dst interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(rc).Decode(dst)
+}
+
+// SHA-256 и число строк файла архива.
+func checksum(f *zip.File) (string, int, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	lines, err := countLines(io.TeeReader(rc, h))
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), lines, nil
+}
+
+func countLines(r io.Reader) (int, error) {
+	buf := make([]byte, 32*1024)
+	lines := 0
+	for {
+		n, err := r.Read(buf)
+		for _, b := range buf[:n] {
+			if b == '\n' {
+				lines++
+			}
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"GoNews/pkg/logger"
+	"GoNews/pkg/storage"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Имена архивов по расписанию: gonews-backup-20060102T150405Z.zip.
+// Время в имени в UTC, поэтому порядок имён совпадает с порядком создания.
+const (
+	schedulePrefix = "gonews-backup-"
+	scheduleSuffix = ".zip"
+	scheduleLayout = "20060102T150405Z"
+)
+
+// Schedule - резервное копирование по расписанию.
+type Schedule struct {
+	Dir   string        // каталог архивов
+	Every time.Duration // интервал между архивами
+	Keep  int           // сколько последних архивов хранить, 0 - все
+}
+
+// Run создаёт архив db в каталоге s.Dir каждые s.Every, пока не отменён ctx,
+// и удаляет архивы сверх s.Keep. Ошибки записываются в журнал и не
+// останавливают расписание.
+func (s Schedule) Run(ctx context.Context, db storage.Interface) {
+	ticker := time.NewTicker(s.Every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Once(ctx, db); err != nil {
+				logger.SetLog(time.Now(), db.GetInform(), fmt.Sprintf("scheduled backup: %v", err))
+			}
+		}
+	}
+}
+
+// Once создаёт один архив в каталоге s.Dir, применяет политику хранения
+// и возвращает имя созданного файла.
+func (s Schedule) Once(ctx context.Context, db storage.Interface) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return "", err
+	}
+
+	filename := filepath.Join(s.Dir, schedulePrefix+time.Now().UTC().Format(scheduleLayout)+scheduleSuffix)
+	if _, err := CreateFile(ctx, db, filename); err != nil {
+		return "", err
+	}
+
+	return filename, s.prune()
+}
+
+// Удаление самых старых архивов сверх s.Keep.
+// Учитываются только файлы с именами архивов по расписанию.
+func (s Schedule) prune() error {
+	if s.Keep <= 0 {
+		return nil
+	}
+
+	entries, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, schedulePrefix) && strings.HasSuffix(name, scheduleSuffix) {
+			names = append(names, name)
+		}
+	}
+	if len(names) <= s.Keep {
+		return nil
+	}
+
+	sort.Strings(names)
+	for _, name := range names[:len(names)-s.Keep] {
+		if err := os.Remove(filepath.Join(s.Dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
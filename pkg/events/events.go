@@ -0,0 +1,172 @@
+// Пакет events - поток изменений хранилища (change data capture).
+//
+// Каждое успешное создание, изменение и удаление автора или публикации
+// порождает событие Event с состоянием записи до и после изменения.
+// Источник событий зависит от БД:
+//   - PostgreSQL: триггеры записывают события в таблицу events и
+//     уведомляют подписчиков через NOTIFY (см. schema.sql);
+//   - MongoDB: change streams (нужен набор реплик);
+//   - Redis: поток (stream) gonews:events, события добавляет обёртка Publish;
+//   - memdb: канал в памяти процесса (NewMemory и обёртка Publish).
+//
+// Подписчики получают события через Source.Subscribe.
+package events
+
+import (
+	"GoNews/pkg/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Сущности, изменения которых публикуются.
+const (
+	Authors = "authors"
+	Posts   = "posts"
+)
+
+// Op - вид изменения.
+type Op string
+
+const (
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+	OpDelete Op = "delete"
+)
+
+// Event - изменение одной записи.
+// Before пусто для OpCreate, After пусто для OpDelete.
+// Before и After содержат хранимые поля записи (без author_name
+// и текстовых представлений времени).
+type Event struct {
+	ID       string          `json:"id"` // позиция в потоке, формат зависит от источника
+	Entity   string          `json:"entity"`
+	Op       Op              `json:"op"`
+	EntityID int64           `json:"entity_id"`
+	Time     time.Time       `json:"time"`
+	Before   json.RawMessage `json:"before,omitempty"`
+	After    json.RawMessage `json:"after,omitempty"`
+}
+
+// Source - источник событий.
+type Source interface {
+	// Subscribe возвращает канал событий, следующих за событием afterID;
+	// пустой afterID - только новые события. Канал закрывается при отмене ctx
+	// или ошибке источника; продолжить можно новой подпиской с ID
+	// последнего полученного события.
+	Subscribe(ctx context.Context, afterID string) (<-chan Event, error)
+}
+
+// Bus - источник событий, в который события публикуются из процесса.
+type Bus interface {
+	Source
+	// Publish добавляет событие в поток. ID и Time заполняются шиной.
+	Publish(ctx context.Context, e Event) error
+	Close() error
+}
+
+// AuthorChange возвращает состояния автора до и после изменения (nil, если нет).
+func (e Event) AuthorChange() (before, after *storage.Author, err error) {
+	if e.Entity != Authors {
+		return nil, nil, fmt.Errorf("event entity is %q, not %q", e.Entity, Authors)
+	}
+	if len(e.Before) > 0 {
+		before = new(storage.Author)
+		if err := json.Unmarshal(e.Before, before); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(e.After) > 0 {
+		after = new(storage.Author)
+		if err := json.Unmarshal(e.After, after); err != nil {
+			return nil, nil, err
+		}
+	}
+	return before, after, nil
+}
+
+// PostChange возвращает состояния публикации до и после изменения (nil, если нет).
+func (e Event) PostChange() (before, after *storage.Post, err error) {
+	if e.Entity != Posts {
+		return nil, nil, fmt.Errorf("event entity is %q, not %q", e.Entity, Posts)
+	}
+	if len(e.Before) > 0 {
+		before = new(storage.Post)
+		if err := json.Unmarshal(e.Before, before); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(e.After) > 0 {
+		after = new(storage.Post)
+		if err := json.Unmarshal(e.After, after); err != nil {
+			return nil, nil, err
+		}
+	}
+	return before, after, nil
+}
+
+// Хранимые поля автора.
+type authorRecord struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Хранимые поля публикации.
+type postRecord struct {
+	ID          int64  `json:"id"`
+	AuthorID    int64  `json:"author_id"`
+	Title       string `json:"title"`
+	Content     string `json:"content"`
+	CreatedAt   int64  `json:"created_at"`
+	PublishedAt int64  `json:"published_at"`
+}
+
+// AuthorEvent создаёт событие изменения автора; before или after может быть nil.
+func AuthorEvent(op Op, before, after *storage.Author) (Event, error) {
+	e := Event{Entity: Authors, Op: op}
+	var err error
+	if before != nil {
+		e.EntityID = before.ID
+		if e.Before, err = json.Marshal(authorRecord{ID: before.ID, Name: before.Name}); err != nil {
+			return e, err
+		}
+	}
+	if after != nil {
+		e.EntityID = after.ID
+		if e.After, err = json.Marshal(authorRecord{ID: after.ID, Name: after.Name}); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// PostEvent создаёт событие изменения публикации; before или after может быть nil.
+func PostEvent(op Op, before, after *storage.Post) (Event, error) {
+	e := Event{Entity: Posts, Op: op}
+	var err error
+	if before != nil {
+		e.EntityID = before.ID
+		if e.Before, err = json.Marshal(newPostRecord(before)); err != nil {
+			return e, err
+		}
+	}
+	if after != nil {
+		e.EntityID = after.ID
+		if e.After, err = json.Marshal(newPostRecord(after)); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+func newPostRecord(p *storage.Post) postRecord {
+	return postRecord{
+		ID:          p.ID,
+		AuthorID:    p.AuthorID,
+		Title:       p.Title,
+		Content:     p.Content,
+		CreatedAt:   p.CreatedAt,
+		PublishedAt: p.PublishedAt,
+	}
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultHistory - сколько последних событий хранит Memory для продолжения подписки.
+const DefaultHistory = 1000
+
+// Размер очереди подписчика. Подписчик, не успевающий забирать события,
+// отключается (канал закрывается), чтобы не задерживать запись в БД.
+const subscriberBuffer = 256
+
+// Memory - шина событий в памяти процесса. ID событий - номера по порядку.
+type Memory struct {
+	mu      sync.Mutex
+	seq     int64
+	history []Event // последние события, не больше limit
+	limit   int
+	subs    map[chan Event]struct{}
+	closed  bool
+}
+
+// NewMemory создаёт шину, хранящую history последних событий.
+func NewMemory(history int) *Memory {
+	return &Memory{limit: history, subs: make(map[chan Event]struct{})}
+}
+
+// Publish рассылает событие подписчикам.
+func (m *Memory) Publish(ctx context.Context, e Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return errors.New("event bus closed")
+	}
+
+	m.seq++
+	e.ID = strconv.FormatInt(m.seq, 10)
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if m.limit > 0 {
+		if len(m.history) >= m.limit {
+			m.history = append(m.history[:0], m.history[1:]...)
+		}
+		m.history = append(m.history, e)
+	}
+
+	for ch := range m.subs {
+		select {
+		case ch <- e:
+		default:
+			// Подписчик отстал: отключаем, он может продолжить с последнего ID.
+			delete(m.subs, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Subscribe возвращает канал событий после afterID.
+// Если afterID старше хранимой истории, передаётся вся история.
+func (m *Memory) Subscribe(ctx context.Context, afterID string) (<-chan Event, error) {
+	var after int64
+	if afterID != "" {
+		var err error
+		if after, err = strconv.ParseInt(afterID, 10, 64); err != nil {
+			return nil, errors.New("invalid event id " + strconv.Quote(afterID))
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, errors.New("event bus closed")
+	}
+
+	var replay []Event
+	if afterID != "" {
+		for _, e := range m.history {
+			if seq, _ := strconv.ParseInt(e.ID, 10, 64); seq > after {
+				replay = append(replay, e)
+			}
+		}
+	}
+
+	ch := make(chan Event, len(replay)+subscriberBuffer)
+	for _, e := range replay {
+		ch <- e
+	}
+	m.subs[ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.subs[ch]; ok {
+			delete(m.subs, ch)
+			close(ch)
+		}
+	}()
+
+	return ch, nil
+}
+
+// Close отключает всех подписчиков.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	for ch := range m.subs {
+		delete(m.subs, ch)
+		close(ch)
+	}
+	return nil
+}
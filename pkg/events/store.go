@@ -0,0 +1,174 @@
+package events
+
+import (
+	"GoNews/pkg/logger"
+	"GoNews/pkg/storage"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Store - БД, публикующая события своих изменений в шину.
+// Используется для БД без собственного потока изменений (memdb, Redis).
+// Состояния до и после изменения читаются отдельными запросами, поэтому
+// при одновременных изменениях одной записи могут быть неточными.
+type Store struct {
+	storage.Interface
+	bus Bus
+}
+
+// Publish оборачивает db публикацией событий в bus.
+func Publish(db storage.Interface, bus Bus) *Store {
+	return &Store{Interface: db, bus: bus}
+}
+
+// Bind - копия обёртки над хранилищем, привязанным к ctx.
+func (s *Store) Bind(ctx context.Context) storage.Interface {
+	return &Store{Interface: storage.Bind(s.Interface, ctx), bus: s.bus}
+}
+
+func (s *Store) Close() error {
+	err := s.Interface.Close()
+	if berr := s.bus.Close(); err == nil {
+		err = berr
+	}
+	return err
+}
+
+// Публикация события. Ошибка записывается в журнал и не отменяет изменение.
+func (s *Store) publish(e Event, err error) {
+	if err == nil {
+		err = s.bus.Publish(context.Background(), e)
+	}
+	if err != nil {
+		logger.SetLog(time.Now(), s.GetInform(), fmt.Sprintf("publish %s %s event: %v", e.Entity, e.Op, err))
+	}
+}
+
+// Author - автор.
+func (s *Store) AddAuthor(author storage.Author) (int64, error) {
+	return addAuthor(s.Interface, author, s.publish)
+}
+
+func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
+	return updateAuthor(s.Interface, author, s.publish)
+}
+
+func (s *Store) PatchAuthor(id int64, fields map[string]interface{}) (int64, error) {
+	before, berr := s.Interface.AuthorByID(id)
+	n, err := s.Interface.PatchAuthor(id, fields)
+	if err == nil && berr == nil {
+		after := authorOr(s.Interface, id, before)
+		s.publish(AuthorEvent(OpUpdate, &before, &after))
+	}
+	return n, err
+}
+
+func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
+	return deleteAuthor(s.Interface, author, s.publish)
+}
+
+func (s *Store) AuthorsBatch(op storage.BatchOp, mode storage.BatchMode, authors []storage.Author) ([]storage.BatchResult, error) {
+	befores := make([]*storage.Author, len(authors))
+	if op != storage.BatchCreate {
+		for i, a := range authors {
+			if before, err := s.Interface.AuthorByID(a.ID); err == nil {
+				befores[i] = &before
+			}
+		}
+	}
+
+	results, err := s.Interface.AuthorsBatch(op, mode, authors)
+	for _, r := range results {
+		if r.Err != "" || r.Index < 0 || r.Index >= len(authors) {
+			continue
+		}
+		before := befores[r.Index]
+		switch op {
+		case storage.BatchCreate:
+			after := authorOr(s.Interface, r.ID, withAuthorID(authors[r.Index], r.ID))
+			s.publish(AuthorEvent(OpCreate, nil, &after))
+		case storage.BatchUpdate:
+			after := authorOr(s.Interface, authors[r.Index].ID, authors[r.Index])
+			s.publish(AuthorEvent(OpUpdate, before, &after))
+		case storage.BatchDelete:
+			if before == nil {
+				before = &authors[r.Index]
+			}
+			s.publish(AuthorEvent(OpDelete, before, nil))
+		}
+	}
+	return results, err
+}
+
+// Post - публикация.
+func (s *Store) AddPost(post storage.Post) (int64, error) {
+	return addPost(s.Interface, post, s.publish)
+}
+
+func (s *Store) UpdatePost(post storage.Post) (int64, error) {
+	return updatePost(s.Interface, post, s.publish)
+}
+
+func (s *Store) PatchPost(id int64, fields map[string]interface{}) (int64, error) {
+	before, berr := s.Interface.PostByID(id)
+	n, err := s.Interface.PatchPost(id, fields)
+	if err == nil && berr == nil {
+		after := postOr(s.Interface, id, before)
+		s.publish(PostEvent(OpUpdate, &before, &after))
+	}
+	return n, err
+}
+
+func (s *Store) DeletePost(post storage.Post) (int64, error) {
+	return deletePost(s.Interface, post, s.publish)
+}
+
+func (s *Store) PostsBatch(op storage.BatchOp, mode storage.BatchMode, posts []storage.Post) ([]storage.BatchResult, error) {
+	befores := make([]*storage.Post, len(posts))
+	if op != storage.BatchCreate {
+		for i, p := range posts {
+			if before, err := s.Interface.PostByID(p.ID); err == nil {
+				befores[i] = &before
+			}
+		}
+	}
+
+	results, err := s.Interface.PostsBatch(op, mode, posts)
+	for _, r := range results {
+		if r.Err != "" || r.Index < 0 || r.Index >= len(posts) {
+			continue
+		}
+		before := befores[r.Index]
+		switch op {
+		case storage.BatchCreate:
+			after := postOr(s.Interface, r.ID, withPostID(posts[r.Index], r.ID))
+			s.publish(PostEvent(OpCreate, nil, &after))
+		case storage.BatchUpdate:
+			after := postOr(s.Interface, posts[r.Index].ID, posts[r.Index])
+			s.publish(PostEvent(OpUpdate, before, &after))
+		case storage.BatchDelete:
+			if before == nil {
+				before = &posts[r.Index]
+			}
+			s.publish(PostEvent(OpDelete, before, nil))
+		}
+	}
+	return results, err
+}
+
+// События транзакции публикуются после её фиксации.
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Tx) error) error {
+	var pending []Event
+	err := s.Interface.WithTx(ctx, func(tx storage.Tx) error {
+		// Функция транзакции может выполняться повторно (MongoDB).
+		pending = pending[:0]
+		return fn(&publishingTx{Tx: tx, pending: &pending, store: s})
+	})
+	if err == nil {
+		for _, e := range pending {
+			s.publish(e, nil)
+		}
+	}
+	return err
+}
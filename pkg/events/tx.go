@@ -0,0 +1,143 @@
+package events
+
+import "GoNews/pkg/storage"
+
+// Функция, получающая событие изменения или ошибку его создания.
+type emitter func(e Event, err error)
+
+// Транзакция, откладывающая события до фиксации.
+type publishingTx struct {
+	storage.Tx
+	pending *[]Event
+	store   *Store
+}
+
+func (t *publishingTx) emit(e Event, err error) {
+	if err != nil {
+		t.store.publish(e, err)
+		return
+	}
+	*t.pending = append(*t.pending, e)
+}
+
+func (t *publishingTx) AddAuthor(author storage.Author) (int64, error) {
+	return addAuthor(t.Tx, author, t.emit)
+}
+
+func (t *publishingTx) UpdateAuthor(author storage.Author) (int64, error) {
+	return updateAuthor(t.Tx, author, t.emit)
+}
+
+func (t *publishingTx) DeleteAuthor(author storage.Author) (int64, error) {
+	return deleteAuthor(t.Tx, author, t.emit)
+}
+
+func (t *publishingTx) AddPost(post storage.Post) (int64, error) {
+	return addPost(t.Tx, post, t.emit)
+}
+
+func (t *publishingTx) UpdatePost(post storage.Post) (int64, error) {
+	return updatePost(t.Tx, post, t.emit)
+}
+
+func (t *publishingTx) DeletePost(post storage.Post) (int64, error) {
+	return deletePost(t.Tx, post, t.emit)
+}
+
+// Изменения с событиями, общие для БД и транзакции.
+
+func addAuthor(db storage.Tx, author storage.Author, emit emitter) (int64, error) {
+	id, err := db.AddAuthor(author)
+	if err == nil {
+		after := authorOr(db, id, withAuthorID(author, id))
+		emit(AuthorEvent(OpCreate, nil, &after))
+	}
+	return id, err
+}
+
+func updateAuthor(db storage.Tx, author storage.Author, emit emitter) (int64, error) {
+	before, berr := db.AuthorByID(author.ID)
+	n, err := db.UpdateAuthor(author)
+	if err == nil {
+		after := authorOr(db, author.ID, author)
+		if berr != nil {
+			emit(AuthorEvent(OpUpdate, nil, &after))
+		} else {
+			emit(AuthorEvent(OpUpdate, &before, &after))
+		}
+	}
+	return n, err
+}
+
+func deleteAuthor(db storage.Tx, author storage.Author, emit emitter) (int64, error) {
+	before, berr := db.AuthorByID(author.ID)
+	if berr != nil {
+		before = author
+	}
+	n, err := db.DeleteAuthor(author)
+	if err == nil {
+		emit(AuthorEvent(OpDelete, &before, nil))
+	}
+	return n, err
+}
+
+func addPost(db storage.Tx, post storage.Post, emit emitter) (int64, error) {
+	id, err := db.AddPost(post)
+	if err == nil {
+		after := postOr(db, id, withPostID(post, id))
+		emit(PostEvent(OpCreate, nil, &after))
+	}
+	return id, err
+}
+
+func updatePost(db storage.Tx, post storage.Post, emit emitter) (int64, error) {
+	before, berr := db.PostByID(post.ID)
+	n, err := db.UpdatePost(post)
+	if err == nil {
+		after := postOr(db, post.ID, post)
+		if berr != nil {
+			emit(PostEvent(OpUpdate, nil, &after))
+		} else {
+			emit(PostEvent(OpUpdate, &before, &after))
+		}
+	}
+	return n, err
+}
+
+func deletePost(db storage.Tx, post storage.Post, emit emitter) (int64, error) {
+	before, berr := db.PostByID(post.ID)
+	if berr != nil {
+		before = post
+	}
+	n, err := db.DeletePost(post)
+	if err == nil {
+		emit(PostEvent(OpDelete, &before, nil))
+	}
+	return n, err
+}
+
+// Состояние автора id после изменения; fallback, если прочитать не удалось.
+func authorOr(db storage.Tx, id int64, fallback storage.Author) storage.Author {
+	if a, err := db.AuthorByID(id); err == nil {
+		return a
+	}
+	return fallback
+}
+
+// Состояние публикации id после изменения; fallback, если прочитать не удалось.
+func postOr(db storage.Tx, id int64, fallback storage.Post) storage.Post {
+	if p, err := db.PostByID(id); err == nil {
+		return p
+	}
+	return fallback
+}
+
+func withAuthorID(a storage.Author, id int64) storage.Author {
+	a.ID = id
+	return a
+}
+
+func withPostID(p storage.Post, id int64) storage.Post {
+	p.ID = id
+	return p
+}
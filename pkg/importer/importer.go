@@ -0,0 +1,426 @@
+// Пакет importer загружает авторов и публикации из JSON, NDJSON и CSV.
+//
+// Данные читаются потоком и записываются в БД пакетами через
+// AuthorsBatch/PostsBatch, поэтому размер файла не ограничен памятью.
+// Каждая запись проверяется пакетом validation; вычисляемые поля
+// (author_name, *_txt, *_rfc3339) игнорируются, что позволяет загружать
+// данные, выгруженные через GET /posts.
+package importer
+
+import (
+	"GoNews/pkg/storage"
+	"GoNews/pkg/validation"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Сущности для загрузки.
+const (
+	Authors = "authors"
+	Posts   = "posts"
+)
+
+// Format - формат входных данных.
+type Format string
+
+const (
+	FormatJSON   Format = "json"   // JSON-массив объектов
+	FormatNDJSON Format = "ndjson" // по одному JSON-объекту в строке
+	FormatCSV    Format = "csv"    // CSV с заголовком
+)
+
+// Valid проверяет, что формат известен.
+func (f Format) Valid() bool {
+	return f == FormatJSON || f == FormatNDJSON || f == FormatCSV
+}
+
+// Mode - поведение при загрузке записи с ID, который уже есть в БД.
+type Mode string
+
+const (
+	ModeUpsert Mode = "upsert" // заменить существующую запись
+	ModeSkip   Mode = "skip"   // оставить существующую запись
+	ModeFail   Mode = "fail"   // остановить загрузку, пакет с конфликтом не применяется
+)
+
+// Valid проверяет, что режим известен.
+func (m Mode) Valid() bool {
+	return m == ModeUpsert || m == ModeSkip || m == ModeFail
+}
+
+const (
+	DefaultBatchSize = 500 // размер пакета по умолчанию
+	MaxErrors        = 100 // сколько ошибок по записям сохраняется в Stats
+)
+
+var (
+	// ErrInvalidData - входные данные не разобраны или запись не прошла проверку.
+	ErrInvalidData = errors.New("invalid data")
+	// ErrConflict - запись с таким ID уже есть в БД (режим ModeFail).
+	ErrConflict = errors.New("record already exists")
+)
+
+// Options - параметры загрузки.
+type Options struct {
+	Entity    string            // Authors или Posts
+	Format    Format            // формат входных данных
+	Mode      Mode              // поведение при конфликте ID, по умолчанию ModeFail
+	BatchSize int               // записей в пакете
+	Columns   map[string]string // CSV: колонка -> поле
+	Progress  func(Stats)       // вызывается после каждого пакета
+}
+
+// RowError - ошибка загрузки записи.
+// Row - номер записи во входных данных, начиная с 1 (для CSV без заголовка).
+type RowError struct {
+	Row int    `json:"row"`
+	ID  int64  `json:"id,omitempty"`
+	Err string `json:"err"`
+}
+
+// Stats - итоги загрузки.
+type Stats struct {
+	Read    int        `json:"read"`    // прочитано записей
+	Created int        `json:"created"` // создано
+	Updated int        `json:"updated"` // заменено (ModeUpsert)
+	Skipped int        `json:"skipped"` // пропущено (ModeSkip)
+	Failed  int        `json:"failed"`  // отклонено
+	Errors  []RowError `json:"errors,omitempty"`
+}
+
+func (st *Stats) fail(row int, id int64, err string) {
+	st.Failed++
+	if len(st.Errors) < MaxErrors {
+		st.Errors = append(st.Errors, RowError{Row: row, ID: id, Err: err})
+	}
+}
+
+// FormatByName определяет формат по расширению файла.
+func FormatByName(filename string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("cannot detect format of %q, expected .json, .ndjson, .jsonl or .csv", filename)
+}
+
+// FormatByContentType определяет формат по заголовку Content-Type.
+func FormatByContentType(contentType string) (Format, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+	switch mediaType {
+	case "application/json":
+		return FormatJSON, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON, nil
+	case "text/csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unsupported Content-Type %q, use application/json, application/x-ndjson or text/csv", mediaType)
+}
+
+// File загружает данные из файла filename. Пустой opts.Format определяется по расширению.
+func File(ctx context.Context, db storage.Interface, filename string, opts Options) (Stats, error) {
+	if opts.Format == "" {
+		format, err := FormatByName(filename)
+		if err != nil {
+			return Stats{}, err
+		}
+		opts.Format = format
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer f.Close()
+
+	return Import(ctx, db, f, opts)
+}
+
+// Import загружает данные из r в db.
+// При ошибке возвращаются итоги по уже обработанным записям.
+func Import(ctx context.Context, db storage.Interface, r io.Reader, opts Options) (Stats, error) {
+	var st Stats
+
+	if opts.Mode == "" {
+		opts.Mode = ModeFail
+	}
+	if !opts.Mode.Valid() {
+		return st, fmt.Errorf("unknown mode %q, expected one of upsert, skip, fail", opts.Mode)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	var b batch
+	switch opts.Entity {
+	case Authors:
+		b = &authorBatch{db: db}
+	case Posts:
+		b = &postBatch{db: db}
+	default:
+		return st, fmt.Errorf("unknown entity %q, expected %s or %s", opts.Entity, Authors, Posts)
+	}
+
+	rr, err := newReader(r, opts.Format, opts.Columns)
+	if err != nil {
+		return st, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+
+	var rows []int
+	for {
+		raw, err := rr.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return st, fmt.Errorf("%w: row %d: %v", ErrInvalidData, st.Read+1, err)
+		}
+		st.Read++
+
+		id, err := b.add(raw)
+		if err != nil {
+			st.fail(st.Read, id, err.Error())
+			if opts.Mode == ModeFail {
+				return st, fmt.Errorf("%w: row %d: %v", ErrInvalidData, st.Read, err)
+			}
+			continue
+		}
+		rows = append(rows, st.Read)
+
+		if b.len() >= opts.BatchSize {
+			if err := flush(ctx, b, rows, opts, &st); err != nil {
+				return st, err
+			}
+			rows = rows[:0]
+		}
+	}
+
+	if b.len() > 0 {
+		if err := flush(ctx, b, rows, opts, &st); err != nil {
+			return st, err
+		}
+	}
+
+	return st, nil
+}
+
+// Запись накопленного пакета. rows - номера записей пакета во входных данных.
+//
+// Записи создаются одним пакетом. Для не созданных записей с ID > 0
+// проверяется существование: в режиме ModeSkip они пропускаются,
+// в ModeUpsert заменяются вторым пакетом. В режиме ModeFail пакет
+// выполняется атомарно и первая ошибка останавливает загрузку.
+func flush(ctx context.Context, b batch, rows []int, opts Options, st *Stats) error {
+	defer b.reset()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mode := storage.BatchBestEffort
+	if opts.Mode == ModeFail {
+		mode = storage.BatchAtomic
+	}
+
+	results, err := b.create(mode)
+	if err != nil && !errors.Is(err, storage.ErrBatchAborted) {
+		return err
+	}
+
+	var replace []int
+	for i, res := range results {
+		if res.Err == "" {
+			st.Created++
+			continue
+		}
+		if res.RolledBack() {
+			continue
+		}
+
+		id := b.id(i)
+		exists := false
+		if id > 0 {
+			if exists, err = b.exists(i); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case opts.Mode == ModeFail && exists:
+			st.fail(rows[i], id, ErrConflict.Error())
+			return fmt.Errorf("%w: row %d: id %d", ErrConflict, rows[i], id)
+		case opts.Mode == ModeFail:
+			st.fail(rows[i], id, res.Err)
+			return fmt.Errorf("%w: row %d: %s", ErrInvalidData, rows[i], res.Err)
+		case !exists:
+			st.fail(rows[i], id, res.Err)
+		case opts.Mode == ModeSkip:
+			st.Skipped++
+		default:
+			replace = append(replace, i)
+		}
+	}
+
+	if len(replace) > 0 {
+		results, err := b.update(replace)
+		if err != nil {
+			return err
+		}
+		for j, res := range results {
+			i := replace[j]
+			if res.Err != "" {
+				st.fail(rows[i], b.id(i), res.Err)
+				continue
+			}
+			st.Updated++
+		}
+	}
+
+	if opts.Progress != nil {
+		opts.Progress(*st)
+	}
+	return nil
+}
+
+// Пакет записей одной сущности.
+type batch interface {
+	add(raw json.RawMessage) (int64, error) // разбор и проверка записи, ID для отчёта
+	len() int
+	id(i int) int64
+	create(mode storage.BatchMode) ([]storage.BatchResult, error)
+	exists(i int) (bool, error)
+	update(idx []int) ([]storage.BatchResult, error)
+	reset()
+}
+
+type authorBatch struct {
+	db    storage.Interface
+	items []storage.Author
+}
+
+func (b *authorBatch) add(raw json.RawMessage) (int64, error) {
+	var a storage.Author
+	if err := decode(raw, &a, validation.AuthorReadOnly); err != nil {
+		return 0, err
+	}
+	if err := validation.Author(a, validation.OpCreate); err != nil {
+		return a.ID, err
+	}
+	b.items = append(b.items, a)
+	return a.ID, nil
+}
+
+func (b *authorBatch) len() int       { return len(b.items) }
+func (b *authorBatch) id(i int) int64 { return b.items[i].ID }
+func (b *authorBatch) reset()         { b.items = b.items[:0] }
+
+func (b *authorBatch) create(mode storage.BatchMode) ([]storage.BatchResult, error) {
+	return b.db.AuthorsBatch(storage.BatchCreate, mode, b.items)
+}
+
+func (b *authorBatch) exists(i int) (bool, error) {
+	_, err := b.db.AuthorByID(b.items[i].ID)
+	return found(err)
+}
+
+func (b *authorBatch) update(idx []int) ([]storage.BatchResult, error) {
+	items := make([]storage.Author, len(idx))
+	for j, i := range idx {
+		items[j] = b.items[i]
+	}
+	return b.db.AuthorsBatch(storage.BatchUpdate, storage.BatchBestEffort, items)
+}
+
+type postBatch struct {
+	db    storage.Interface
+	items []storage.Post
+}
+
+func (b *postBatch) add(raw json.RawMessage) (int64, error) {
+	var p storage.Post
+	if err := decode(raw, &p, validation.PostReadOnly); err != nil {
+		return 0, err
+	}
+	if err := validation.Post(p, validation.OpCreate); err != nil {
+		return p.ID, err
+	}
+	b.items = append(b.items, p)
+	return p.ID, nil
+}
+
+func (b *postBatch) len() int       { return len(b.items) }
+func (b *postBatch) id(i int) int64 { return b.items[i].ID }
+func (b *postBatch) reset()         { b.items = b.items[:0] }
+
+func (b *postBatch) create(mode storage.BatchMode) ([]storage.BatchResult, error) {
+	return b.db.PostsBatch(storage.BatchCreate, mode, b.items)
+}
+
+func (b *postBatch) exists(i int) (bool, error) {
+	_, err := b.db.PostByID(b.items[i].ID)
+	return found(err)
+}
+
+func (b *postBatch) update(idx []int) ([]storage.BatchResult, error) {
+	items := make([]storage.Post, len(idx))
+	for j, i := range idx {
+		items[j] = b.items[i]
+	}
+	return b.db.PostsBatch(storage.BatchUpdate, storage.BatchBestEffort, items)
+}
+
+// Результат чтения по ID: true - запись есть, false - storage.ErrNotFound.
+func found(err error) (bool, error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Разбор записи в dst: вычисляемые поля readOnly отбрасываются,
+// неизвестные поля и ошибки типов возвращаются как validation.Errors.
+func decode(raw json.RawMessage, dst interface{}, readOnly []string) error {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("record is not a JSON object: %v", err)
+	}
+	for _, field := range readOnly {
+		delete(doc, field)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return validation.Decode(bytes.NewReader(data), dst, nil)
+}
+
+// InitData загружает начальные данные entity из файла filename в режиме ModeUpsert:
+// записи файла заменяют записи с теми же ID, остальные данные БД сохраняются.
+// Используется флагом -loadbd сервера и командой migrate-data.
+func InitData(db storage.Interface, entity, filename string) error {
+	st, err := File(context.Background(), db, filename, Options{Entity: entity, Mode: ModeUpsert})
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	if st.Failed > 0 {
+		return fmt.Errorf("%s: %d of %d records rejected, first at row %d: %s", filename, st.Failed, st.Read, st.Errors[0].Row, st.Errors[0].Err)
+	}
+	return nil
+}
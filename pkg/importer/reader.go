@@ -0,0 +1,164 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Источник записей: каждая запись возвращается как JSON-объект, в конце - io.EOF.
+type recordReader interface {
+	next() (json.RawMessage, error)
+}
+
+func newReader(r io.Reader, format Format, columns map[string]string) (recordReader, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonArrayReader{dec: json.NewDecoder(bufio.NewReader(r))}, nil
+	case FormatNDJSON:
+		return &ndjsonReader{dec: json.NewDecoder(bufio.NewReader(r))}, nil
+	case FormatCSV:
+		return newCSVReader(r, columns)
+	}
+	return nil, fmt.Errorf("unknown format %q, expected one of json, ndjson, csv", format)
+}
+
+// JSON-массив объектов: [{...}, {...}].
+// Массив читается по одному элементу, целиком в память не загружается.
+type jsonArrayReader struct {
+	dec     *json.Decoder
+	started bool
+}
+
+func (jr *jsonArrayReader) next() (json.RawMessage, error) {
+	if !jr.started {
+		tok, err := jr.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return nil, fmt.Errorf("expected JSON array, got %v", tok)
+		}
+		jr.started = true
+	}
+
+	if !jr.dec.More() {
+		if _, err := jr.dec.Token(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := jr.dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// NDJSON: по одному JSON-объекту в строке.
+type ndjsonReader struct {
+	dec *json.Decoder
+}
+
+func (nr *ndjsonReader) next() (json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := nr.dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// Поля, значения которых в CSV преобразуются в числа.
+var numericFields = map[string]bool{
+	"id":           true,
+	"author_id":    true,
+	"created_at":   true,
+	"published_at": true,
+}
+
+// CSV с заголовком. Имена колонок переводятся в имена полей через columns,
+// колонки без сопоставления используются под своим именем, колонки,
+// сопоставленные с "-", пропускаются.
+type csvReader struct {
+	r      *csv.Reader
+	fields []string
+}
+
+func newCSVReader(r io.Reader, columns map[string]string) (*csvReader, error) {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("csv: missing header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, len(header))
+	for i, col := range header {
+		col = strings.TrimSpace(strings.TrimPrefix(col, "\ufeff"))
+		if field, ok := columns[col]; ok {
+			col = field
+		}
+		if col != "-" {
+			fields[i] = col
+		}
+	}
+
+	return &csvReader{r: cr, fields: fields}, nil
+}
+
+func (cr *csvReader) next() (json.RawMessage, error) {
+	row, err := cr.r.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	record := make(map[string]interface{}, len(cr.fields))
+	for i, value := range row {
+		field := cr.fields[i]
+		if field == "" {
+			continue
+		}
+		if !numericFields[field] {
+			record[field] = value
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		// Нечисловое значение передаётся строкой, проверка сообщит об ошибке в поле.
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			record[field] = n
+		} else {
+			record[field] = value
+		}
+	}
+
+	return json.Marshal(record)
+}
+
+// ParseColumns разбирает сопоставление колонок CSV вида "Колонка=поле,Другая=поле2".
+func ParseColumns(s string) (map[string]string, error) {
+	columns := map[string]string{}
+	if strings.TrimSpace(s) == "" {
+		return columns, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid column mapping %q, expected column=field", pair)
+		}
+		columns[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return columns, nil
+}
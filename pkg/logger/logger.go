@@ -1,35 +1,138 @@
+// Пакет logger - журнал ошибок обращения к БД (файл log.json) и журнал
+// запросов HTTP через zerolog.
 package logger
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
-const fileNameLog string = "ui/database/log.json"
+// DefaultFile - файл журнала по умолчанию. Находится вне каталога ui,
+// который раздаётся как статические файлы.
+const DefaultFile = "logs/log.json"
+
+// Файл журнала SetLog; открывается при первой записи, если не задан OpenFile.
+var (
+	outMu sync.Mutex
+	out   *Writer
+)
+
+// OpenFile направляет журнал SetLog в файл path с ротацией opts.
+// Предыдущий файл закрывается.
+func OpenFile(path string, opts RotateOptions) error {
+	w, err := OpenWriter(path, opts)
+	if err != nil {
+		return err
+	}
+	outMu.Lock()
+	prev := out
+	out = w
+	outMu.Unlock()
+	if prev != nil {
+		return prev.Close()
+	}
+	return nil
+}
+
+// CloseFile закрывает файл журнала (при завершении программы).
+func CloseFile() error {
+	outMu.Lock()
+	defer outMu.Unlock()
+	if out == nil {
+		return nil
+	}
+	err := out.Close()
+	out = nil
+	return err
+}
+
+// Текущий файл журнала.
+func output() (*Writer, error) {
+	outMu.Lock()
+	defer outMu.Unlock()
+	if out == nil {
+		w, err := OpenWriter(DefaultFile, DefaultRotateOptions())
+		if err != nil {
+			return nil, err
+		}
+		out = w
+	}
+	return out, nil
+}
 
 type MessStructLog struct {
-	Datetime time.Time `json:"datetime"`
-	Database string    `json:"database"`
-	Mess     string    `json:"mess"`
+	Datetime  time.Time `json:"datetime"`
+	Database  string    `json:"database"`
+	Mess      string    `json:"mess"`
+	RequestID string    `json:"request_id,omitempty"` // запрос HTTP, при обработке которого возникла ошибка
+	Level     string    `json:"level,omitempty"`      // уровень zerolog; в старых записях нет - error
 }
 
-func SetupLogger() {
-	// Настраиваем zerolog
-	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+// Форматы вывода zerolog.
+const (
+	FormatConsole = "console" // читаемый текст
+	FormatJSON    = "json"    // JSON-строка на запись
+)
+
+// SetupLogger настраивает zerolog: уровень level (trace, debug, info,
+// warn, error) и формат вывода в stderr format (FormatConsole, FormatJSON).
+func SetupLogger(level, format string) error {
+	if err := SetLevel(level); err != nil {
+		return err
+	}
 	zerolog.TimeFieldFormat = "2006-01-02 15:04:05.000"
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, NoColor: false})
+	switch format {
+	case FormatConsole:
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, NoColor: false})
+	case FormatJSON:
+		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+	default:
+		return fmt.Errorf("unknown log format %q, expected %s or %s", format, FormatConsole, FormatJSON)
+	}
+	return nil
+}
+
+// SetLevel меняет уровень журнала zerolog во время работы.
+func SetLevel(level string) error {
+	l, err := zerolog.ParseLevel(strings.ToLower(strings.TrimSpace(level)))
+	if err != nil || level == "" {
+		return fmt.Errorf("unknown log level %q, expected trace, debug, info, warn, error", level)
+	}
+	zerolog.SetGlobalLevel(l)
+	return nil
+}
+
+// Level - текущий уровень журнала zerolog.
+func Level() string {
+	return zerolog.GlobalLevel().String()
 }
 
 func SetLog(datetime time.Time, base string, mess string) {
+	SetLogCtx(context.Background(), datetime, base, mess)
+}
+
+// SetLogCtx - SetLog с ID запроса HTTP из контекста ctx (см. Middleware).
+func SetLogCtx(ctx context.Context, datetime time.Time, base string, mess string) {
+	SetLogLevel(ctx, zerolog.ErrorLevel, datetime, base, mess)
+}
+
+// SetLogLevel - SetLogCtx с уровнем записи level (SetLog пишет ошибки).
+func SetLogLevel(ctx context.Context, level zerolog.Level, datetime time.Time, base string, mess string) {
 	// Создаем новый экземпляр структуры
 	r := MessStructLog{
-		Datetime: datetime,
-		Database: base,
-		Mess:     mess,
+		Datetime:  datetime,
+		Database:  base,
+		Mess:      mess,
+		RequestID: RequestID(ctx),
+		Level:     level.String(),
 	}
 
 	// Записываем данные в JSON-формате
@@ -39,13 +142,12 @@ func SetLog(datetime time.Time, base string, mess string) {
 		return
 	}
 
-	// Открываем файл для записи
-	f, err := os.OpenFile(fileNameLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// Файл журнала открыт постоянно
+	f, err := output()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to open log file")
 		return
 	}
-	defer f.Close()
 
 	// Записываем данные в файл
 	_, err = f.Write(append(logData, '\n'))
@@ -54,42 +156,17 @@ func SetLog(datetime time.Time, base string, mess string) {
 		return
 	}
 
+	// Передаём запись подписчикам (GET /admin/logs?follow=1)
+	publish(r)
+
 	// Записываем данные в консольный лог
-	log.Info().
+	event := log.WithLevel(level).
 		Time("datetime", r.Datetime).
 		Str("base", r.Database).
-		Str("mess", r.Mess).
-		Msg("mess from database")
-
-}
-
-func GetLog() {
-	// Открываем файл для чтения
-	f, err := os.Open(fileNameLog)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to open log file")
-		return
-	}
-	defer f.Close()
-
-	// Читаем данные из файла
-	var logs []MessStructLog
-	decoder := json.NewDecoder(f)
-	for {
-		var r MessStructLog
-		err := decoder.Decode(&r)
-		if err != nil {
-			break
-		}
-		logs = append(logs, r)
+		Str("mess", r.Mess)
+	if r.RequestID != "" {
+		event = event.Str("request_id", r.RequestID)
 	}
+	event.Msg("mess from database")
 
-	// Выводим данные в консоль
-	for _, r := range logs {
-		log.Info().
-			Time("datetime", r.Datetime).
-			Str("base", r.Database).
-			Str("mess", r.Mess).
-			Msg("mess from database")
-	}
 }
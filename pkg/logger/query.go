@@ -0,0 +1,218 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Query - отбор записей журнала. Пустые поля не ограничивают отбор.
+type Query struct {
+	Since     time.Time     // не раньше
+	Until     time.Time     // раньше (не включая)
+	Database  string        // источник записи (поле database)
+	Level     zerolog.Level // не ниже; zerolog.NoLevel - любой
+	Text      string        // подстрока сообщения без учёта регистра
+	RequestID string
+	Limit     int // не больше записей; <= 0 - все
+
+	// Продолжение страницы: записи со временем Until тоже отбираются,
+	// кроме первых Skip из них (от новых к старым), уже полученных.
+	Skip int
+}
+
+// EntryLevel - уровень записи; в записях до появления поля level - error.
+func (e MessStructLog) EntryLevel() zerolog.Level {
+	if e.Level == "" {
+		return zerolog.ErrorLevel
+	}
+	l, err := zerolog.ParseLevel(e.Level)
+	if err != nil {
+		return zerolog.ErrorLevel
+	}
+	return l
+}
+
+// Match сообщает, подходит ли запись e под отбор.
+func (q Query) Match(e MessStructLog) bool {
+	switch {
+	case !q.Since.IsZero() && e.Datetime.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !e.Datetime.Before(q.Until):
+		return false
+	case q.Database != "" && !strings.EqualFold(e.Database, q.Database):
+		return false
+	case q.Level != zerolog.NoLevel && e.EntryLevel() < q.Level:
+		return false
+	case q.RequestID != "" && e.RequestID != q.RequestID:
+		return false
+	case q.Text != "" && !strings.Contains(strings.ToLower(e.Mess), strings.ToLower(q.Text)):
+		return false
+	}
+	return true
+}
+
+// Search возвращает записи журнала, подходящие под q, от новых к старым.
+// Читаются текущий файл и старые сегменты (в том числе сжатые), начиная
+// с новых; сегменты, целиком более старые, чем q.Since, не открываются.
+func Search(q Query) ([]MessStructLog, error) {
+	if q.Skip <= 0 || q.Until.IsZero() {
+		return search(q)
+	}
+
+	// Записи со временем Until - самые новые в результате: первые Skip
+	// из них отбрасываются.
+	until, skip := q.Until, q.Skip
+	q.Until = until.Add(time.Nanosecond)
+	if q.Limit > 0 {
+		q.Limit += skip
+	}
+	result, err := search(q)
+	if err != nil {
+		return nil, err
+	}
+	for skip > 0 && len(result) > 0 && result[0].Datetime.Equal(until) {
+		result = result[1:]
+		skip--
+	}
+	if q.Limit > 0 && len(result) > q.Limit-q.Skip {
+		result = result[:q.Limit-q.Skip]
+	}
+	return result, nil
+}
+
+// Поиск без учёта Skip.
+func search(q Query) ([]MessStructLog, error) {
+	w, err := output()
+	if err != nil {
+		return nil, err
+	}
+	segments, err := Segments(w.Path())
+	if err != nil {
+		return nil, err
+	}
+	files := append(segments, w.Path())
+
+	var result []MessStructLog
+	for i := len(files) - 1; i >= 0; i-- {
+		// Сегмент назван временем ротации: все его записи старше этого времени.
+		if !q.Since.IsZero() && i < len(files)-1 && segmentTime(files[i], w.Path()).Before(q.Since) {
+			break
+		}
+		found, err := searchFile(files[i], q, q.Limit-len(result))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, found...)
+		if q.Limit > 0 && len(result) >= q.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// Время ротации сегмента name журнала path.
+func segmentTime(name, path string) time.Time {
+	ts := strings.TrimSuffix(strings.TrimPrefix(name, path+"."), ".gz")
+	t, _ := time.Parse(segmentTimeFormat, ts)
+	return t
+}
+
+// Последние limit подходящих записей файла name от новых к старым
+// (limit <= 0 - все). Отсутствующий файл (удалён ротацией) - пустой.
+func searchFile(name string, q Query, limit int) ([]MessStructLog, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	// Кольцевой буфер последних limit записей.
+	var found []MessStructLog
+	next := 0
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		var e MessStructLog
+		if json.Unmarshal(sc.Bytes(), &e) != nil || !q.Match(e) {
+			continue
+		}
+		if limit <= 0 || len(found) < limit {
+			found = append(found, e)
+			continue
+		}
+		found[next] = e
+		next = (next + 1) % limit
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	// От новых к старым.
+	result := make([]MessStructLog, 0, len(found))
+	for i := len(found) - 1; i >= 0; i-- {
+		result = append(result, found[(next+i)%len(found)])
+	}
+	return result, nil
+}
+
+// Размер очереди подписчика; отстающий подписчик отключается.
+const followBuffer = 256
+
+var (
+	followMu  sync.Mutex
+	followers = map[chan MessStructLog]struct{}{}
+)
+
+// Follow возвращает канал новых записей журнала. Канал закрывается вызовом
+// cancel или если подписчик не успевает читать записи.
+func Follow() (<-chan MessStructLog, func()) {
+	ch := make(chan MessStructLog, followBuffer)
+	followMu.Lock()
+	followers[ch] = struct{}{}
+	followMu.Unlock()
+
+	cancel := func() {
+		followMu.Lock()
+		defer followMu.Unlock()
+		if _, ok := followers[ch]; ok {
+			delete(followers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// Передача записи подписчикам Follow.
+func publish(e MessStructLog) {
+	followMu.Lock()
+	defer followMu.Unlock()
+	for ch := range followers {
+		select {
+		case ch <- e:
+		default:
+			delete(followers, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"GoNews/pkg/response"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestIDHeader - заголовок с ID запроса: принимается от клиента
+// (или прокси) и возвращается в ответе.
+const RequestIDHeader = "X-Request-ID"
+
+// Максимальная длина ID запроса от клиента.
+const maxRequestIDLen = 128
+
+type requestKey struct{}
+
+// Данные запроса в контексте: ID и поля для журнала, добавленные обработчиками.
+type requestInfo struct {
+	id     string
+	mu     sync.Mutex
+	fields map[string]string
+}
+
+// RequestID возвращает ID запроса HTTP из контекста, "" - вне запроса.
+func RequestID(ctx context.Context) string {
+	if ri, ok := ctx.Value(requestKey{}).(*requestInfo); ok {
+		return ri.id
+	}
+	return ""
+}
+
+// Annotate добавляет поле key в запись журнала о запросе
+// (например, клиента после аутентификации).
+func Annotate(ctx context.Context, key, value string) {
+	ri, ok := ctx.Value(requestKey{}).(*requestInfo)
+	if !ok {
+		return
+	}
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	ri.fields[key] = value
+}
+
+// Middleware назначает запросу ID (из заголовка X-Request-ID или новый),
+// возвращает его в ответе и после обработки пишет запись о запросе в zerolog:
+// метод, путь, код ответа, размер, длительность и адрес клиента.
+// Уровень записи: 5xx - error, 4xx - warn, остальные - info.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		ri := &requestInfo{id: id, fields: map[string]string{}}
+		w.Header().Set(RequestIDHeader, id)
+
+		rec := response.NewRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestKey{}, ri)))
+
+		var event *zerolog.Event
+		switch {
+		case rec.Status >= 500:
+			event = log.Error()
+		case rec.Status >= 400:
+			event = log.Warn()
+		default:
+			event = log.Info()
+		}
+		event = event.
+			Str("request_id", id).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Str("query", r.URL.RawQuery).
+			Int("status", rec.Status).
+			Int64("bytes", rec.Bytes).
+			Dur("duration", time.Since(start)).
+			Str("remote_addr", r.RemoteAddr).
+			Str("user_agent", r.UserAgent())
+		ri.mu.Lock()
+		for k, v := range ri.fields {
+			event = event.Str(k, v)
+		}
+		ri.mu.Unlock()
+		event.Msg("http request")
+	})
+}
+
+// ID от клиента принимается, если он не длиннее maxRequestIDLen
+// и состоит из печатных символов ASCII.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,223 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Формат времени в имени сегмента журнала: <файл>.<время>[.gz].
+// Имена сегментов упорядочены по времени.
+const segmentTimeFormat = "20060102T150405.000"
+
+// RotateOptions - параметры ротации файла журнала.
+type RotateOptions struct {
+	MaxSize  int64         // размер файла, после которого начинается новый сегмент, байт; 0 - без ограничения
+	Every    time.Duration // новый сегмент в начале каждого периода (сутки и т.п.); 0 - без ротации по времени
+	Keep     int           // число хранимых старых сегментов; 0 - все
+	Compress bool          // сжимать старые сегменты gzip
+}
+
+// DefaultRotateOptions - ротация по умолчанию: 10 МБ или сутки, 7 сегментов в gzip.
+func DefaultRotateOptions() RotateOptions {
+	return RotateOptions{MaxSize: 10 << 20, Every: 24 * time.Hour, Keep: 7, Compress: true}
+}
+
+// Writer - файл журнала с ротацией. Безопасен для использования из
+// нескольких горутин; файл остаётся открытым между записями.
+type Writer struct {
+	mu     sync.Mutex
+	path   string
+	opts   RotateOptions
+	f      *os.File
+	size   int64
+	period time.Time // начало периода ротации текущего сегмента
+
+	bg sync.Mutex // сжатие и удаление старых сегментов по одному
+}
+
+// OpenWriter открывает (создаёт) файл журнала path для дозаписи.
+func OpenWriter(path string, opts RotateOptions) (*Writer, error) {
+	w := &Writer{path: path, opts: opts}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Открытие текущего сегмента. Период существующего файла определяется
+// по времени последней записи в него.
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f, w.size = f, info.Size()
+	w.period = w.periodOf(info.ModTime())
+	return nil
+}
+
+func (w *Writer) periodOf(t time.Time) time.Time {
+	if w.opts.Every <= 0 {
+		return time.Time{}
+	}
+	return t.Truncate(w.opts.Every)
+}
+
+// Write дописывает p в журнал, при необходимости начиная новый сегмент.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	now := time.Now()
+	full := w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize
+	if full || !w.periodOf(now).Equal(w.period) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Переименование текущего сегмента и открытие нового; сжатие
+// и удаление старых сегментов - в фоне.
+func (w *Writer) rotate(now time.Time) error {
+	if w.size > 0 {
+		if err := w.f.Close(); err != nil {
+			return err
+		}
+		w.f = nil
+		name := w.path + "." + now.UTC().Format(segmentTimeFormat)
+		for i := 1; exists(name) || exists(name+".gz"); i++ {
+			name = w.path + "." + now.UTC().Add(time.Duration(i)*time.Millisecond).Format(segmentTimeFormat)
+		}
+		if err := os.Rename(w.path, name); err != nil {
+			return err
+		}
+		if err := w.open(); err != nil {
+			return err
+		}
+		go w.cleanup(name)
+	}
+	w.period = w.periodOf(now)
+	return nil
+}
+
+// Сжатие сегмента name и удаление сегментов сверх Keep.
+func (w *Writer) cleanup(name string) {
+	w.bg.Lock()
+	defer w.bg.Unlock()
+
+	if w.opts.Compress {
+		if err := compress(name); err != nil {
+			os.Stderr.WriteString("logger: compress " + name + ": " + err.Error() + "\n")
+		}
+	}
+	if w.opts.Keep <= 0 {
+		return
+	}
+	segments, err := Segments(w.path)
+	if err != nil {
+		return
+	}
+	for len(segments) > w.opts.Keep {
+		os.Remove(segments[0])
+		segments = segments[1:]
+	}
+}
+
+// Close закрывает файл журнала, дожидаясь фонового сжатия сегментов.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.bg.Lock()
+	defer w.bg.Unlock()
+
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// Path - путь к текущему сегменту журнала.
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// Segments возвращает старые сегменты журнала path (сжатые и нет)
+// от старых к новым, без текущего файла.
+func Segments(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var segments []string
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(m, path+"."), ".gz")
+		if _, err := time.Parse(segmentTimeFormat, ts); err == nil {
+			segments = append(segments, m)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return strings.TrimSuffix(segments[i], ".gz") < strings.TrimSuffix(segments[j], ".gz")
+	})
+	return segments, nil
+}
+
+// Сжатие файла name в name.gz с удалением исходного файла.
+func compress(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz.tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(name+".gz.tmp", name+".gz")
+	}
+	if err != nil {
+		os.Remove(name + ".gz.tmp")
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"GoNews/pkg/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	httpRequests = NewCounterVec("gonews_http_requests_total",
+		"HTTP requests by method, route template and status code.",
+		"method", "route", "status")
+	httpDuration = NewHistogramVec("gonews_http_request_duration_seconds",
+		"HTTP request duration by method and route template.",
+		DefBuckets, "method", "route")
+)
+
+// Middleware считает запросы HTTP и их длительность по методу, шаблону
+// маршрута gorilla/mux (например, /posts/{id:[0-9]+}) и коду ответа.
+// Запросы вне маршрутов учитываются с маршрутом "unmatched".
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := response.NewRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		route := "unmatched"
+		if cur := mux.CurrentRoute(r); cur != nil {
+			if tpl, err := cur.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		httpRequests.Inc(r.Method, route, strconv.Itoa(rec.Status))
+		httpDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
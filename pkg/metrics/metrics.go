@@ -0,0 +1,247 @@
+// Пакет metrics - метрики сервера в текстовом формате Prometheus (GET /metrics).
+//
+// Собираются: запросы HTTP по маршруту, методу и коду ответа (Middleware),
+// вызовы методов storage.Interface по БД (Instrument) и состояние пулов
+// соединений БД (RegisterPools). Формат экспозиции реализован здесь же,
+// без клиентской библиотеки Prometheus.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Типы метрик.
+const (
+	Counter   = "counter"
+	Gauge     = "gauge"
+	Histogram = "histogram"
+)
+
+// DefBuckets - границы корзин гистограммы длительности по умолчанию, секунды.
+var DefBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Метрика в реестре.
+type family interface {
+	write(w *bufio.Writer)
+}
+
+var registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+func register(f family) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.families = append(registry.families, f)
+}
+
+// Handler отдаёт все метрики в текстовом формате Prometheus.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registry.mu.Lock()
+		families := append([]family(nil), registry.families...)
+		registry.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		for _, f := range families {
+			f.write(bw)
+		}
+		bw.Flush()
+	})
+}
+
+// Ряд метрики: значения меток и накопленные значения.
+type series struct {
+	labels []string
+	value  float64  // счётчик
+	counts []uint64 // гистограмма: наблюдений в каждой корзине (не накопительно)
+	sum    float64
+	count  uint64
+}
+
+// Vec - метрика с метками: счётчик или гистограмма.
+type Vec struct {
+	name, help, typ string
+	labels          []string
+	buckets         []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// NewCounterVec регистрирует счётчик с метками labels.
+func NewCounterVec(name, help string, labels ...string) *Vec {
+	v := &Vec{name: name, help: help, typ: Counter, labels: labels, series: map[string]*series{}}
+	register(v)
+	return v
+}
+
+// NewHistogramVec регистрирует гистограмму с корзинами buckets и метками labels.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *Vec {
+	v := &Vec{name: name, help: help, typ: Histogram, labels: labels, buckets: buckets, series: map[string]*series{}}
+	register(v)
+	return v
+}
+
+// Ряд для значений меток; вызывается под v.mu.
+func (v *Vec) get(values []string) *series {
+	key := strings.Join(values, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), values...)}
+		if v.typ == Histogram {
+			s.counts = make([]uint64, len(v.buckets))
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+// Add увеличивает счётчик с метками values на delta.
+func (v *Vec) Add(delta float64, values ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(values).value += delta
+}
+
+// Inc увеличивает счётчик с метками values на 1.
+func (v *Vec) Inc(values ...string) {
+	v.Add(1, values...)
+}
+
+// Observe добавляет наблюдение x в гистограмму с метками values.
+func (v *Vec) Observe(x float64, values ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s := v.get(values)
+	for i, b := range v.buckets {
+		if x <= b {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += x
+	s.count++
+}
+
+func (v *Vec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	writeHeader(w, v.name, v.help, v.typ)
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := v.series[k]
+		if v.typ != Histogram {
+			writeSample(w, v.name, v.labels, s.labels, "", "", s.value)
+			continue
+		}
+		var cum uint64
+		for i, b := range v.buckets {
+			cum += s.counts[i]
+			writeSample(w, v.name+"_bucket", v.labels, s.labels, "le", formatFloat(b), float64(cum))
+		}
+		writeSample(w, v.name+"_bucket", v.labels, s.labels, "le", "+Inf", float64(s.count))
+		writeSample(w, v.name+"_sum", v.labels, s.labels, "", "", s.sum)
+		writeSample(w, v.name+"_count", v.labels, s.labels, "", "", float64(s.count))
+	}
+}
+
+// Sample - значение метрики, вычисляемой при запросе.
+type Sample struct {
+	Labels []string // значения меток в порядке их объявления
+	Value  float64
+}
+
+// Метрика, значения которой вычисляет функция при каждом запросе /metrics.
+type funcFamily struct {
+	name, help, typ string
+	labels          []string
+	fn              func() []Sample
+}
+
+// NewFunc регистрирует метрику типа typ (Counter или Gauge), значения
+// которой возвращает fn при каждом запросе /metrics.
+func NewFunc(name, help, typ string, labels []string, fn func() []Sample) {
+	register(&funcFamily{name: name, help: help, typ: typ, labels: labels, fn: fn})
+}
+
+func (f *funcFamily) write(w *bufio.Writer) {
+	samples := f.fn()
+	if len(samples) == 0 {
+		return
+	}
+	writeHeader(w, f.name, f.help, f.typ)
+	for _, s := range samples {
+		writeSample(w, f.name, f.labels, s.Labels, "", "", s.Value)
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help), name, typ)
+}
+
+// Строка ряда: имя{метки} значение; extraName - дополнительная метка (le).
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			val := ""
+			if i < len(values) {
+				val = values[i]
+			}
+			fmt.Fprintf(w, "%s=%q", l, escapeLabel(val))
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=%q", extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// Значение метки без символов, которые %q экранировал бы не по правилам
+// формата (допустимы только \\, \" и \n).
+func escapeLabel(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' && r != '\n' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, +1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
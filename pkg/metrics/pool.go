@@ -0,0 +1,67 @@
+package metrics
+
+import "sync"
+
+// PoolStats - состояние пула соединений с БД.
+type PoolStats struct {
+	Pool     string // имя пула: primary, адрес реплики и т.п.
+	Open     int64  // открытых соединений
+	Idle     int64  // свободных соединений
+	InUse    int64  // занятых соединений
+	Max      int64  // наибольшее число соединений
+	Misses   int64  // получений соединения, когда свободного не было (с запуска)
+	Timeouts int64  // неудачных получений соединения: тайм-аут, отмена (с запуска)
+}
+
+// PoolReporter - БД, сообщающая состояние своих пулов соединений.
+type PoolReporter interface {
+	PoolStats() []PoolStats
+}
+
+var pools struct {
+	mu        sync.Mutex
+	backends  []string
+	reporters []PoolReporter
+}
+
+// RegisterPools добавляет пулы соединений БД backend (GetInform()) в /metrics.
+func RegisterPools(backend string, r PoolReporter) {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+	pools.backends = append(pools.backends, backend)
+	pools.reporters = append(pools.reporters, r)
+}
+
+// Значения одного поля PoolStats всех зарегистрированных пулов.
+func poolSamples(field func(PoolStats) int64) func() []Sample {
+	return func() []Sample {
+		pools.mu.Lock()
+		defer pools.mu.Unlock()
+		var samples []Sample
+		for i, r := range pools.reporters {
+			for _, st := range r.PoolStats() {
+				samples = append(samples, Sample{
+					Labels: []string{pools.backends[i], st.Pool},
+					Value:  float64(field(st)),
+				})
+			}
+		}
+		return samples
+	}
+}
+
+func init() {
+	labels := []string{"backend", "pool"}
+	NewFunc("gonews_db_pool_open_connections", "Open connections in the database pool.", Gauge, labels,
+		poolSamples(func(s PoolStats) int64 { return s.Open }))
+	NewFunc("gonews_db_pool_idle_connections", "Idle connections in the database pool.", Gauge, labels,
+		poolSamples(func(s PoolStats) int64 { return s.Idle }))
+	NewFunc("gonews_db_pool_in_use_connections", "Connections in use in the database pool.", Gauge, labels,
+		poolSamples(func(s PoolStats) int64 { return s.InUse }))
+	NewFunc("gonews_db_pool_max_connections", "Maximum size of the database pool.", Gauge, labels,
+		poolSamples(func(s PoolStats) int64 { return s.Max }))
+	NewFunc("gonews_db_pool_misses_total", "Connection acquisitions that found no idle connection.", Counter, labels,
+		poolSamples(func(s PoolStats) int64 { return s.Misses }))
+	NewFunc("gonews_db_pool_timeouts_total", "Connection acquisitions that timed out or were canceled.", Counter, labels,
+		poolSamples(func(s PoolStats) int64 { return s.Timeouts }))
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"GoNews/pkg/storage"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	storageOps = NewCounterVec("gonews_storage_operations_total",
+		"Storage method calls by backend, method and result (ok, not_found, error).",
+		"backend", "method", "result")
+	storageDuration = NewHistogramVec("gonews_storage_operation_duration_seconds",
+		"Storage method call duration by backend and method.",
+		DefBuckets, "backend", "method")
+)
+
+// Store - обёртка БД, считающая вызовы методов storage.Interface
+// и их длительность. Метка backend - GetInform() обёрнутой БД.
+type Store struct {
+	storage.Interface
+	backend string
+}
+
+// Instrument оборачивает db для сбора метрик вызовов.
+func Instrument(db storage.Interface) *Store {
+	return &Store{Interface: db, backend: db.GetInform()}
+}
+
+// Bind - копия обёртки над хранилищем, привязанным к ctx.
+func (s *Store) Bind(ctx context.Context) storage.Interface {
+	return &Store{Interface: storage.Bind(s.Interface, ctx), backend: s.backend}
+}
+
+// Учёт вызова method, начатого в start и завершённого с ошибкой err.
+func (s *Store) observe(method string, start time.Time, err error) {
+	result := "ok"
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		result = "not_found"
+	case err != nil:
+		result = "error"
+	}
+	storageOps.Inc(s.backend, method, result)
+	storageDuration.Observe(time.Since(start).Seconds(), s.backend, method)
+}
+
+// WithTx учитывает транзакцию целиком (WithTx) и операции в ней (Tx.<метод>).
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Tx) error) (err error) {
+	defer func(start time.Time) { s.observe("WithTx", start, err) }(time.Now())
+	return s.Interface.WithTx(ctx, func(tx storage.Tx) error {
+		return fn(&instrumentedTx{Tx: tx, s: s})
+	})
+}
+
+func (s *Store) Authors() (authors []storage.Author, err error) {
+	defer func(start time.Time) { s.observe("Authors", start, err) }(time.Now())
+	return s.Interface.Authors()
+}
+
+func (s *Store) AuthorByID(id int64) (author storage.Author, err error) {
+	defer func(start time.Time) { s.observe("AuthorByID", start, err) }(time.Now())
+	return s.Interface.AuthorByID(id)
+}
+
+func (s *Store) AddAuthor(author storage.Author) (id int64, err error) {
+	defer func(start time.Time) { s.observe("AddAuthor", start, err) }(time.Now())
+	return s.Interface.AddAuthor(author)
+}
+
+func (s *Store) UpdateAuthor(author storage.Author) (id int64, err error) {
+	defer func(start time.Time) { s.observe("UpdateAuthor", start, err) }(time.Now())
+	return s.Interface.UpdateAuthor(author)
+}
+
+func (s *Store) PatchAuthor(id int64, fields map[string]interface{}) (n int64, err error) {
+	defer func(start time.Time) { s.observe("PatchAuthor", start, err) }(time.Now())
+	return s.Interface.PatchAuthor(id, fields)
+}
+
+func (s *Store) DeleteAuthor(author storage.Author) (id int64, err error) {
+	defer func(start time.Time) { s.observe("DeleteAuthor", start, err) }(time.Now())
+	return s.Interface.DeleteAuthor(author)
+}
+
+func (s *Store) AuthorsBatch(op storage.BatchOp, mode storage.BatchMode, authors []storage.Author) (res []storage.BatchResult, err error) {
+	defer func(start time.Time) { s.observe("AuthorsBatch", start, err) }(time.Now())
+	return s.Interface.AuthorsBatch(op, mode, authors)
+}
+
+func (s *Store) Posts() (posts []storage.Post, err error) {
+	defer func(start time.Time) { s.observe("Posts", start, err) }(time.Now())
+	return s.Interface.Posts()
+}
+
+func (s *Store) PostByID(id int64) (post storage.Post, err error) {
+	defer func(start time.Time) { s.observe("PostByID", start, err) }(time.Now())
+	return s.Interface.PostByID(id)
+}
+
+func (s *Store) AddPost(post storage.Post) (id int64, err error) {
+	defer func(start time.Time) { s.observe("AddPost", start, err) }(time.Now())
+	return s.Interface.AddPost(post)
+}
+
+func (s *Store) UpdatePost(post storage.Post) (id int64, err error) {
+	defer func(start time.Time) { s.observe("UpdatePost", start, err) }(time.Now())
+	return s.Interface.UpdatePost(post)
+}
+
+func (s *Store) PatchPost(id int64, fields map[string]interface{}) (n int64, err error) {
+	defer func(start time.Time) { s.observe("PatchPost", start, err) }(time.Now())
+	return s.Interface.PatchPost(id, fields)
+}
+
+func (s *Store) DeletePost(post storage.Post) (id int64, err error) {
+	defer func(start time.Time) { s.observe("DeletePost", start, err) }(time.Now())
+	return s.Interface.DeletePost(post)
+}
+
+func (s *Store) PostsBatch(op storage.BatchOp, mode storage.BatchMode, posts []storage.Post) (res []storage.BatchResult, err error) {
+	defer func(start time.Time) { s.observe("PostsBatch", start, err) }(time.Now())
+	return s.Interface.PostsBatch(op, mode, posts)
+}
+
+// Транзакция, учитывающая свои операции с методом "Tx.<метод>".
+type instrumentedTx struct {
+	storage.Tx
+	s *Store
+}
+
+func (t *instrumentedTx) AuthorByID(id int64) (author storage.Author, err error) {
+	defer func(start time.Time) { t.s.observe("Tx.AuthorByID", start, err) }(time.Now())
+	return t.Tx.AuthorByID(id)
+}
+
+func (t *instrumentedTx) AddAuthor(author storage.Author) (id int64, err error) {
+	defer func(start time.Time) { t.s.observe("Tx.AddAuthor", start, err) }(time.Now())
+	return t.Tx.AddAuthor(author)
+}
+
+func (t *instrumentedTx) UpdateAuthor(author storage.Author) (id int64, err error) {
+	defer func(start time.Time) { t.s.observe("Tx.UpdateAuthor", start, err) }(time.Now())
+	return t.Tx.UpdateAuthor(author)
+}
+
+func (t *instrumentedTx) DeleteAuthor(author storage.Author) (id int64, err error) {
+	defer func(start time.Time) { t.s.observe("Tx.DeleteAuthor", start, err) }(time.Now())
+	return t.Tx.DeleteAuthor(author)
+}
+
+func (t *instrumentedTx) PostByID(id int64) (post storage.Post, err error) {
+	defer func(start time.Time) { t.s.observe("Tx.PostByID", start, err) }(time.Now())
+	return t.Tx.PostByID(id)
+}
+
+func (t *instrumentedTx) AddPost(post storage.Post) (id int64, err error) {
+	defer func(start time.Time) { t.s.observe("Tx.AddPost", start, err) }(time.Now())
+	return t.Tx.AddPost(post)
+}
+
+func (t *instrumentedTx) UpdatePost(post storage.Post) (id int64, err error) {
+	defer func(start time.Time) { t.s.observe("Tx.UpdatePost", start, err) }(time.Now())
+	return t.Tx.UpdatePost(post)
+}
+
+func (t *instrumentedTx) DeletePost(post storage.Post) (id int64, err error) {
+	defer func(start time.Time) { t.s.observe("Tx.DeletePost", start, err) }(time.Now())
+	return t.Tx.DeletePost(post)
+}
@@ -0,0 +1,345 @@
+// Пакет migrate переносит данные между двумя реализациями storage.Interface.
+//
+// Сначала переносятся авторы, затем публикации, в порядке возрастания ID,
+// пакетами через AuthorsBatch/PostsBatch. ID записей сохраняются, поэтому
+// связи публикаций с авторами не нарушаются. После каждого пакета состояние
+// записывается в файл контрольной точки, что позволяет продолжить перенос
+// между теми же БД после сбоя (контрольная точка другой пары БД отклоняется).
+// Записи, которые уже есть в целевой БД с теми же данными, пропускаются.
+package migrate
+
+import (
+	"GoNews/pkg/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// DefaultBatchSize - размер пакета по умолчанию.
+const DefaultBatchSize = 500
+
+// Этапы переноса в контрольной точке.
+const (
+	stageAuthors = "authors"
+	stagePosts   = "posts"
+	stageDone    = "done"
+)
+
+// Options - параметры переноса.
+type Options struct {
+	BatchSize  int                                  // число записей в пакете
+	Checkpoint string                               // файл контрольной точки, "" - без продолжения
+	Source     string                               // исходная БД в контрольной точке, "" - GetInform()
+	Target     string                               // целевая БД в контрольной точке, "" - GetInform()
+	Progress   func(entity string, done, total int) // вызывается после каждого пакета
+}
+
+// EntityReport - результат переноса и сверки одной сущности.
+type EntityReport struct {
+	Source     int     `json:"source"`               // записей в исходной БД
+	Target     int     `json:"target"`               // записей в целевой БД
+	Copied     int     `json:"copied"`               // перенесено в этом запуске
+	Skipped    int     `json:"skipped"`              // уже были в целевой БД
+	Missing    []int64 `json:"missing,omitempty"`    // ID, отсутствующие в целевой БД
+	Mismatched []int64 `json:"mismatched,omitempty"` // ID с отличающимися данными
+}
+
+// Report - отчёт о переносе.
+type Report struct {
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+	Authors EntityReport `json:"authors"`
+	Posts   EntityReport `json:"posts"`
+	OK      bool         `json:"ok"` // все записи источника есть в целевой БД без отличий
+}
+
+// Контрольная точка: пара БД, этап и последний перенесённый ID.
+type checkpoint struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Stage  string `json:"stage"`
+	LastID int64  `json:"last_id"`
+}
+
+// Описание переносимой сущности для общего цикла переноса.
+type entity struct {
+	name  string
+	ids   []int64                                         // ID записей источника по возрастанию
+	batch func(lo, hi int) ([]storage.BatchResult, error) // создание записей [lo, hi) в целевой БД
+	same  func(i int) (bool, error)                       // запись i уже есть в целевой БД с теми же данными
+}
+
+// Run переносит авторов и публикации из from в to и сверяет результат.
+func Run(ctx context.Context, from, to storage.Interface, opts Options) (Report, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	report := Report{From: from.GetInform(), To: to.GetInform()}
+	if opts.Source == "" {
+		opts.Source = report.From
+	}
+	if opts.Target == "" {
+		opts.Target = report.To
+	}
+
+	cp, err := loadCheckpoint(opts.Checkpoint, opts.Source, opts.Target)
+	if err != nil {
+		return report, err
+	}
+
+	if cp.Stage == stageAuthors {
+		authors, err := from.Authors()
+		if err != nil {
+			return report, fmt.Errorf("read authors: %w", err)
+		}
+		sort.Slice(authors, func(i, j int) bool { return authors[i].ID < authors[j].ID })
+		authors = authors[firstAfter(len(authors), func(i int) int64 { return authors[i].ID }, cp.LastID):]
+
+		e := entity{
+			name: stageAuthors,
+			ids:  make([]int64, len(authors)),
+			batch: func(lo, hi int) ([]storage.BatchResult, error) {
+				return to.AuthorsBatch(storage.BatchCreate, storage.BatchBestEffort, authors[lo:hi])
+			},
+			same: func(i int) (bool, error) {
+				existing, err := to.AuthorByID(authors[i].ID)
+				if err != nil {
+					return false, err
+				}
+				return sameAuthor(existing, authors[i]), nil
+			},
+		}
+		for i := range authors {
+			e.ids[i] = authors[i].ID
+		}
+
+		if err := copyEntity(ctx, e, opts, &cp, &report.Authors); err != nil {
+			return report, err
+		}
+		cp.Stage, cp.LastID = stagePosts, 0
+		if err := saveCheckpoint(opts.Checkpoint, cp); err != nil {
+			return report, err
+		}
+	}
+
+	if cp.Stage == stagePosts {
+		posts, err := from.Posts()
+		if err != nil {
+			return report, fmt.Errorf("read posts: %w", err)
+		}
+		sort.Slice(posts, func(i, j int) bool { return posts[i].ID < posts[j].ID })
+		posts = posts[firstAfter(len(posts), func(i int) int64 { return posts[i].ID }, cp.LastID):]
+
+		e := entity{
+			name: stagePosts,
+			ids:  make([]int64, len(posts)),
+			batch: func(lo, hi int) ([]storage.BatchResult, error) {
+				return to.PostsBatch(storage.BatchCreate, storage.BatchBestEffort, posts[lo:hi])
+			},
+			same: func(i int) (bool, error) {
+				existing, err := to.PostByID(posts[i].ID)
+				if err != nil {
+					return false, err
+				}
+				return samePost(existing, posts[i]), nil
+			},
+		}
+		for i := range posts {
+			e.ids[i] = posts[i].ID
+		}
+
+		if err := copyEntity(ctx, e, opts, &cp, &report.Posts); err != nil {
+			return report, err
+		}
+		cp.Stage, cp.LastID = stageDone, 0
+		if err := saveCheckpoint(opts.Checkpoint, cp); err != nil {
+			return report, err
+		}
+	}
+
+	verified, err := Verify(from, to)
+	if err != nil {
+		return report, err
+	}
+	verified.Authors.Copied, verified.Authors.Skipped = report.Authors.Copied, report.Authors.Skipped
+	verified.Posts.Copied, verified.Posts.Skipped = report.Posts.Copied, report.Posts.Skipped
+
+	if verified.OK && opts.Checkpoint != "" {
+		if err := os.Remove(opts.Checkpoint); err != nil && !os.IsNotExist(err) {
+			return verified, err
+		}
+	}
+
+	return verified, nil
+}
+
+// Перенос записей сущности пакетами с сохранением контрольной точки.
+func copyEntity(ctx context.Context, e entity, opts Options, cp *checkpoint, rep *EntityReport) error {
+	for lo := 0; lo < len(e.ids); lo += opts.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		hi := lo + opts.BatchSize
+		if hi > len(e.ids) {
+			hi = len(e.ids)
+		}
+
+		results, err := e.batch(lo, hi)
+		if err != nil {
+			return fmt.Errorf("%s batch starting at id %d: %w", e.name, e.ids[lo], err)
+		}
+
+		for j, res := range results {
+			i := lo + j
+			if res.Err == "" {
+				if res.ID != e.ids[i] {
+					return fmt.Errorf("%s id %d: target assigned id %d, ids must be preserved", e.name, e.ids[i], res.ID)
+				}
+				rep.Copied++
+				continue
+			}
+
+			same, err := e.same(i)
+			if err != nil || !same {
+				return fmt.Errorf("%s id %d: %s (resume with the same checkpoint after fixing)", e.name, e.ids[i], res.Err)
+			}
+			rep.Skipped++
+		}
+
+		cp.Stage, cp.LastID = e.name, e.ids[hi-1]
+		if err := saveCheckpoint(opts.Checkpoint, *cp); err != nil {
+			return err
+		}
+		if opts.Progress != nil {
+			opts.Progress(e.name, hi, len(e.ids))
+		}
+	}
+	return nil
+}
+
+// Verify сверяет авторов и публикации источника с целевой БД.
+func Verify(from, to storage.Interface) (Report, error) {
+	report := Report{From: from.GetInform(), To: to.GetInform()}
+
+	srcAuthors, err := from.Authors()
+	if err != nil {
+		return report, fmt.Errorf("read source authors: %w", err)
+	}
+	dstAuthors, err := to.Authors()
+	if err != nil {
+		return report, fmt.Errorf("read target authors: %w", err)
+	}
+	authors := make(map[int64]storage.Author, len(dstAuthors))
+	for _, a := range dstAuthors {
+		authors[a.ID] = a
+	}
+	report.Authors.Source, report.Authors.Target = len(srcAuthors), len(dstAuthors)
+	for _, a := range srcAuthors {
+		if dst, ok := authors[a.ID]; !ok {
+			report.Authors.Missing = append(report.Authors.Missing, a.ID)
+		} else if !sameAuthor(dst, a) {
+			report.Authors.Mismatched = append(report.Authors.Mismatched, a.ID)
+		}
+	}
+
+	srcPosts, err := from.Posts()
+	if err != nil {
+		return report, fmt.Errorf("read source posts: %w", err)
+	}
+	dstPosts, err := to.Posts()
+	if err != nil {
+		return report, fmt.Errorf("read target posts: %w", err)
+	}
+	posts := make(map[int64]storage.Post, len(dstPosts))
+	for _, p := range dstPosts {
+		posts[p.ID] = p
+	}
+	report.Posts.Source, report.Posts.Target = len(srcPosts), len(dstPosts)
+	for _, p := range srcPosts {
+		if dst, ok := posts[p.ID]; !ok {
+			report.Posts.Missing = append(report.Posts.Missing, p.ID)
+		} else if !samePost(dst, p) {
+			report.Posts.Mismatched = append(report.Posts.Mismatched, p.ID)
+		}
+	}
+
+	sortIDs(report.Authors.Missing, report.Authors.Mismatched, report.Posts.Missing, report.Posts.Mismatched)
+	report.OK = len(report.Authors.Missing)+len(report.Authors.Mismatched)+
+		len(report.Posts.Missing)+len(report.Posts.Mismatched) == 0
+
+	return report, nil
+}
+
+func sameAuthor(a, b storage.Author) bool {
+	return a.ID == b.ID && a.Name == b.Name
+}
+
+// Сравниваются хранимые поля, вычисляемые (author_name, *_txt) не учитываются.
+func samePost(a, b storage.Post) bool {
+	return a.ID == b.ID && a.AuthorID == b.AuthorID && a.Title == b.Title &&
+		a.Content == b.Content && a.CreatedAt == b.CreatedAt && a.PublishedAt == b.PublishedAt
+}
+
+// Индекс первого элемента с ID больше lastID в отсортированном списке.
+func firstAfter(n int, id func(i int) int64, lastID int64) int {
+	return sort.Search(n, func(i int) bool { return id(i) > lastID })
+}
+
+func sortIDs(lists ...[]int64) {
+	for _, ids := range lists {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+}
+
+// Загрузка контрольной точки переноса из from в to. Отсутствие файла -
+// перенос с начала; контрольная точка другой пары БД - ошибка: продолжение
+// с её позиции пропустило бы записи.
+func loadCheckpoint(filename, from, to string) (checkpoint, error) {
+	cp := checkpoint{From: from, To: to, Stage: stageAuthors}
+	if filename == "" {
+		return cp, nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	var saved checkpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return cp, fmt.Errorf("checkpoint %s: %w", filename, err)
+	}
+	if saved.From != from || saved.To != to {
+		return cp, fmt.Errorf("checkpoint %s belongs to another run (%q -> %q), remove it or pass another -checkpoint",
+			filename, saved.From, saved.To)
+	}
+	if saved.Stage != stageDone {
+		// Завершённый перенос, сверка которого не прошла, выполняется заново.
+		cp = saved
+	}
+	return cp, nil
+}
+
+// Атомарная запись контрольной точки (через временный файл).
+func saveCheckpoint(filename string, cp checkpoint) error {
+	if filename == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,59 @@
+// Пакет ratelimit ограничивает частоту запросов клиентов к API.
+//
+// Используется алгоритм token bucket: у каждого клиента (ключ API, JWT sub
+// или IP-адрес) есть отдельные корзины для чтения и для изменений. Корзина
+// вмещает Burst маркеров и пополняется со скоростью Rate маркеров в секунду;
+// каждый запрос забирает один маркер. Корзины хранятся в памяти процесса
+// (Memory) или в Redis (storage/redis.Limiter) - общие для нескольких
+// экземпляров сервера.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit - параметры корзины.
+type Limit struct {
+	Rate  float64 // маркеров в секунду; <= 0 - без ограничения
+	Burst int     // ёмкость корзины
+}
+
+// Unlimited сообщает, отключено ли ограничение.
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// Result - решение по запросу.
+type Result struct {
+	Allowed    bool
+	Remaining  int           // маркеров осталось после запроса
+	RetryAfter time.Duration // через сколько появится маркер, если запрос отклонён
+}
+
+// Limiter - хранилище корзин.
+type Limiter interface {
+	// Take забирает маркер из корзины key с параметрами l.
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
+
+// Пополнение корзины: tokens маркеров на момент last, через elapsed.
+// Возвращает новое число маркеров и решение по запросу.
+func take(tokens float64, elapsed time.Duration, l Limit) (float64, Result) {
+	if elapsed > 0 {
+		tokens = math.Min(float64(l.Burst), tokens+elapsed.Seconds()*l.Rate)
+	}
+	if tokens >= 1 {
+		tokens--
+		return tokens, Result{Allowed: true, Remaining: int(tokens)}
+	}
+	wait := time.Duration((1 - tokens) / l.Rate * float64(time.Second))
+	return tokens, Result{RetryAfter: wait}
+}
+
+// Время, за которое пустая корзина заполняется полностью:
+// по его истечении корзину можно забыть.
+func fillTime(l Limit) time.Duration {
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
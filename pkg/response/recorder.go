@@ -0,0 +1,45 @@
+// Пакет response - обёртка http.ResponseWriter, запоминающая код и размер
+// ответа, для промежуточных обработчиков (журнал запросов, метрики, трассировка).
+package response
+
+import "net/http"
+
+// Recorder запоминает код и размер ответа; поддерживает потоковые ответы
+// (/events, /import, follow журнала).
+type Recorder struct {
+	http.ResponseWriter
+	Status int   // код ответа; без WriteHeader - 200
+	Bytes  int64 // записано байт тела
+
+	wroteHeader bool
+}
+
+// NewRecorder оборачивает w. Если w уже Recorder (его создал внешний
+// промежуточный обработчик), возвращается он же: на запрос одна обёртка.
+func NewRecorder(w http.ResponseWriter) *Recorder {
+	if rec, ok := w.(*Recorder); ok {
+		return rec
+	}
+	return &Recorder{ResponseWriter: w, Status: http.StatusOK}
+}
+
+func (rec *Recorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.Status, rec.wroteHeader = code, true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *Recorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.Bytes += int64(n)
+	return n, err
+}
+
+// Flush нужен потоковым ответам.
+func (rec *Recorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
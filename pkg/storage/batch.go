@@ -0,0 +1,101 @@
+package storage
+
+import "errors"
+
+// BatchOp - операция пакетной обработки.
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchMode - режим пакетной обработки.
+type BatchMode string
+
+const (
+	// BatchAtomic - все элементы применяются вместе или ни один.
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort - применяются все элементы без ошибок.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// ErrBatchAborted - пакет в режиме BatchAtomic не применён из-за ошибки в элементе.
+var ErrBatchAborted = errors.New("batch aborted, no changes applied")
+
+// Ошибка элемента, который был корректен, но не применён из-за отката пакета.
+const errNotApplied = "not applied: batch rolled back"
+
+// BatchResult - результат обработки одного элемента пакета.
+type BatchResult struct {
+	Index int    `json:"index"`
+	ID    int64  `json:"id"`
+	Err   string `json:"err,omitempty"`
+}
+
+// Valid проверяет, что операция и режим пакета известны.
+func (op BatchOp) Valid() bool {
+	return op == BatchCreate || op == BatchUpdate || op == BatchDelete
+}
+
+// Valid проверяет, что режим пакета известен.
+func (m BatchMode) Valid() bool {
+	return m == BatchAtomic || m == BatchBestEffort
+}
+
+// RolledBack сообщает, что элемент был корректен, но не применён из-за отката пакета.
+func (r BatchResult) RolledBack() bool {
+	return r.Err == errNotApplied
+}
+
+// NewBatchResults создаёт результаты для пакета из n элементов.
+func NewBatchResults(n int) []BatchResult {
+	results := make([]BatchResult, n)
+	for i := range results {
+		results[i].Index = i
+	}
+	return results
+}
+
+// BatchFailed сообщает, есть ли в пакете элементы с ошибкой.
+func BatchFailed(results []BatchResult) bool {
+	for _, r := range results {
+		if r.Err != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// AbortBatch отмечает успешные элементы пакета как неприменённые
+// и возвращает ErrBatchAborted.
+func AbortBatch(results []BatchResult) error {
+	for i := range results {
+		if results[i].Err == "" {
+			results[i].ID = 0
+			results[i].Err = errNotApplied
+		}
+	}
+	return ErrBatchAborted
+}
+
+// RunBatch последовательно выполняет fn для n элементов.
+// В режиме BatchAtomic обработка останавливается на первой ошибке,
+// необработанные элементы отмечаются как неприменённые.
+func RunBatch(n int, mode BatchMode, fn func(i int) (int64, error)) []BatchResult {
+	results := NewBatchResults(n)
+	for i := 0; i < n; i++ {
+		id, err := fn(i)
+		if err != nil {
+			results[i].Err = err.Error()
+			if mode == BatchAtomic {
+				AbortBatch(results[i+1:])
+				break
+			}
+			continue
+		}
+		results[i].ID = id
+	}
+	return results
+}
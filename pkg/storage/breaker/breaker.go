@@ -0,0 +1,276 @@
+// Пакет breaker - автоматический выключатель (circuit breaker) для storage.Interface.
+//
+// Обёртка считает ошибки БД подряд. После Config.Failures ошибок цепь
+// размыкается: вызовы сразу завершаются ошибкой storage.ErrUnavailable
+// (API отвечает 503 с Retry-After), не дожидаясь тайм-аутов недоступной БД.
+// Через Config.OpenTimeout цепь становится полуоткрытой: до Config.Probes
+// запросов пропускаются в БД как пробные. Успешная проба замыкает цепь,
+// ошибка снова размыкает её с удвоенным временем ожидания (не дольше
+// Config.MaxOpenTimeout), если БД не отвечает на Ping. Переподключение выполняют сами клиенты БД
+// (пулы pgx, MongoDB, Redis), выключатель лишь не нагружает их, пока
+// сервер БД недоступен.
+//
+// Ошибками БД не считаются storage.ErrNotFound, storage.ErrBatchAborted,
+// storage.ErrTxUnsupported и отмена запроса клиентом. Остальные ошибки могут
+// быть вызваны самими запросами (нарушение ограничений, повтор ID), поэтому
+// перед размыканием - после Config.Failures ошибок или неудачной пробы -
+// проверяется Ping: если БД отвечает, цепь замыкается (остаётся замкнутой).
+// Проверка выполняется в фоне и не задерживает запрос, вызвавший её.
+//
+// Переходы состояний записываются в журнал (пакет logger). Состояние
+// и число отклонённых вызовов публикуются в /metrics.
+package breaker
+
+import (
+	"GoNews/pkg/logger"
+	"GoNews/pkg/metrics"
+	"GoNews/pkg/storage"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// State - состояние цепи.
+type State int
+
+const (
+	Closed   State = iota // запросы идут в БД
+	Open                  // запросы отклоняются
+	HalfOpen              // пробные запросы идут в БД, остальные отклоняются
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Config - параметры выключателя.
+type Config struct {
+	Failures       int           // ошибок подряд до размыкания
+	OpenTimeout    time.Duration // время до первой пробы
+	MaxOpenTimeout time.Duration // наибольшее время до пробы при неудачных пробах подряд
+	Probes         int           // одновременных пробных запросов
+}
+
+// DefaultConfig - параметры по умолчанию.
+func DefaultConfig() Config {
+	return Config{
+		Failures:       5,
+		OpenTimeout:    5 * time.Second,
+		MaxOpenTimeout: time.Minute,
+		Probes:         1,
+	}
+}
+
+// Время ожидания Ping при проверке, что ошибки вызваны недоступностью БД.
+const pingTimeout = 2 * time.Second
+
+// Retry-After для вызовов, отклонённых в полуоткрытом состоянии.
+const probeRetryAfter = time.Second
+
+// OpenError - вызов отклонён разомкнутой цепью.
+type OpenError struct {
+	Backend string
+	State   State
+	Wait    time.Duration // до следующей пробы
+	Cause   error         // последняя ошибка БД
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%v: %s circuit %s, retry in %s (last error: %v)",
+		storage.ErrUnavailable, e.Backend, e.State, e.Wait.Round(time.Millisecond), e.Cause)
+}
+
+// Unwrap - storage.ErrUnavailable.
+func (e *OpenError) Unwrap() error { return storage.ErrUnavailable }
+
+// RetryAfter - время до следующей пробы (для заголовка Retry-After).
+func (e *OpenError) RetryAfter() time.Duration { return e.Wait }
+
+var rejected = metrics.NewCounterVec("gonews_storage_circuit_rejected_total",
+	"Storage calls rejected by the open circuit breaker.", "backend")
+
+// Цепи для gonews_storage_circuit_state.
+var circuits struct {
+	mu   sync.Mutex
+	list []*circuit
+}
+
+func init() {
+	metrics.NewFunc("gonews_storage_circuit_state", "Storage circuit breaker state: 0 closed, 1 open, 2 half-open.",
+		metrics.Gauge, []string{"backend"}, func() []metrics.Sample {
+			circuits.mu.Lock()
+			defer circuits.mu.Unlock()
+			var samples []metrics.Sample
+			for _, c := range circuits.list {
+				c.mu.Lock()
+				samples = append(samples, metrics.Sample{Labels: []string{c.backend}, Value: float64(c.state)})
+				c.mu.Unlock()
+			}
+			return samples
+		})
+}
+
+// Состояние цепи, общее для обёртки и её копий Bind.
+type circuit struct {
+	cfg     Config
+	backend string
+	ping    func(ctx context.Context) error
+
+	mu       sync.Mutex
+	state    State
+	failures int           // ошибок подряд в состоянии Closed
+	lastErr  error         // последняя ошибка БД
+	wait     time.Duration // текущее время до пробы
+	retryAt  time.Time     // время перехода Open -> HalfOpen
+	probes   int           // пробных запросов в работе
+	checking bool          // идёт проверка Ping перед размыканием
+}
+
+// Разрешение вызова. probe - вызов пробный, его итог меняет состояние цепи.
+func (c *circuit) allow() (probe bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.state == Open && !now.Before(c.retryAt) {
+		c.transition(HalfOpen, fmt.Sprintf("probing after %s", c.wait))
+	}
+	switch c.state {
+	case Open:
+		rejected.Inc(c.backend)
+		return false, &OpenError{Backend: c.backend, State: Open, Wait: c.retryAt.Sub(now), Cause: c.lastErr}
+	case HalfOpen:
+		if c.probes >= c.cfg.Probes {
+			rejected.Inc(c.backend)
+			return false, &OpenError{Backend: c.backend, State: HalfOpen, Wait: probeRetryAfter, Cause: c.lastErr}
+		}
+		c.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// Учёт итога вызова, разрешённого allow.
+func (c *circuit) record(probe bool, err error) {
+	failed := isFailure(err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if probe {
+		c.probes--
+		if c.state != HalfOpen {
+			return
+		}
+		if failed {
+			c.lastErr = err
+			c.verify(fmt.Sprintf("probe failed: %v", err))
+			return
+		}
+		c.failures = 0
+		c.wait = 0
+		c.transition(Closed, "probe succeeded")
+		return
+	}
+
+	if c.state != Closed {
+		return
+	}
+	if !failed {
+		c.failures = 0
+		return
+	}
+	c.failures++
+	c.lastErr = err
+	if c.failures < c.cfg.Failures {
+		return
+	}
+	c.verify(fmt.Sprintf("%d consecutive errors, last: %v", c.failures, err))
+}
+
+// Проверка Ping перед размыканием цепи в фоне: ошибки могут быть вызваны
+// самими запросами. Если БД отвечает, цепь замыкается (остаётся замкнутой),
+// иначе размыкается - из полуоткрытого состояния с удвоенным временем
+// ожидания. Вызывается под c.mu.
+func (c *circuit) verify(reason string) {
+	if c.checking {
+		return
+	}
+	c.checking = true
+	from := c.state
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		perr := c.ping(ctx)
+		cancel()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.checking = false
+		if c.state != from {
+			// Пока шла проверка, цепь замкнула успешная проба.
+			return
+		}
+
+		if perr == nil {
+			c.failures = 0
+			if from == HalfOpen {
+				c.wait = 0
+				c.transition(Closed, reason+", but the database answers ping")
+				return
+			}
+			logger.SetLogLevel(context.Background(), zerolog.WarnLevel, time.Now(), c.backend,
+				fmt.Sprintf("circuit breaker: %s, but the database answers ping; circuit stays closed", reason))
+			return
+		}
+		wait := c.cfg.OpenTimeout
+		if from == HalfOpen {
+			wait = c.wait * 2
+		}
+		c.open(wait, fmt.Sprintf("%s; ping: %v", reason, perr))
+	}()
+}
+
+// Размыкание цепи на время wait (не больше MaxOpenTimeout). Вызывается под c.mu.
+func (c *circuit) open(wait time.Duration, reason string) {
+	if wait < c.cfg.OpenTimeout {
+		wait = c.cfg.OpenTimeout
+	}
+	if c.cfg.MaxOpenTimeout > 0 && wait > c.cfg.MaxOpenTimeout {
+		wait = c.cfg.MaxOpenTimeout
+	}
+	c.wait = wait
+	c.retryAt = time.Now().Add(wait)
+	c.transition(Open, fmt.Sprintf("%s; next probe in %s", reason, wait))
+}
+
+// Смена состояния с записью в журнал. Вызывается под c.mu.
+func (c *circuit) transition(to State, reason string) {
+	from := c.state
+	c.state = to
+	level := zerolog.InfoLevel
+	if to == Open {
+		level = zerolog.ErrorLevel
+	}
+	logger.SetLogLevel(context.Background(), level, time.Now(), c.backend,
+		fmt.Sprintf("circuit breaker %s -> %s: %s", from, to, reason))
+}
+
+// Ошибка вызова говорит о недоступности БД.
+func isFailure(err error) bool {
+	return err != nil &&
+		!errors.Is(err, storage.ErrNotFound) &&
+		!errors.Is(err, storage.ErrBatchAborted) &&
+		!errors.Is(err, storage.ErrTxUnsupported) &&
+		!errors.Is(err, context.Canceled)
+}
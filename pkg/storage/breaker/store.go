@@ -0,0 +1,169 @@
+package breaker
+
+import (
+	"GoNews/pkg/storage"
+	"context"
+)
+
+// Store - БД с автоматическим выключателем. Ping, GetInform и Close
+// выполняются всегда и на состояние цепи не влияют.
+type Store struct {
+	storage.Interface
+	c *circuit
+}
+
+// New оборачивает db выключателем с параметрами cfg.
+func New(db storage.Interface, cfg Config) *Store {
+	if cfg.Failures <= 0 {
+		cfg.Failures = 1
+	}
+	if cfg.Probes <= 0 {
+		cfg.Probes = 1
+	}
+	c := &circuit{cfg: cfg, backend: db.GetInform(), ping: db.Ping}
+	circuits.mu.Lock()
+	circuits.list = append(circuits.list, c)
+	circuits.mu.Unlock()
+	return &Store{Interface: db, c: c}
+}
+
+// Bind - копия обёртки над хранилищем, привязанным к ctx, с той же цепью.
+func (s *Store) Bind(ctx context.Context) storage.Interface {
+	return &Store{Interface: storage.Bind(s.Interface, ctx), c: s.c}
+}
+
+// WithTx учитывает транзакцию целиком; операции внутри неё не проверяются.
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Tx) error) (err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.WithTx(ctx, fn)
+}
+
+func (s *Store) Authors() (authors []storage.Author, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.Authors()
+}
+
+func (s *Store) AuthorByID(id int64) (author storage.Author, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return author, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.AuthorByID(id)
+}
+
+func (s *Store) AddAuthor(author storage.Author) (id int64, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.AddAuthor(author)
+}
+
+func (s *Store) UpdateAuthor(author storage.Author) (id int64, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.UpdateAuthor(author)
+}
+
+func (s *Store) PatchAuthor(id int64, fields map[string]interface{}) (n int64, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.PatchAuthor(id, fields)
+}
+
+func (s *Store) DeleteAuthor(author storage.Author) (id int64, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.DeleteAuthor(author)
+}
+
+func (s *Store) AuthorsBatch(op storage.BatchOp, mode storage.BatchMode, authors []storage.Author) (res []storage.BatchResult, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.AuthorsBatch(op, mode, authors)
+}
+
+func (s *Store) Posts() (posts []storage.Post, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.Posts()
+}
+
+func (s *Store) PostByID(id int64) (post storage.Post, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return post, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.PostByID(id)
+}
+
+func (s *Store) AddPost(post storage.Post) (id int64, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.AddPost(post)
+}
+
+func (s *Store) UpdatePost(post storage.Post) (id int64, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.UpdatePost(post)
+}
+
+func (s *Store) PatchPost(id int64, fields map[string]interface{}) (n int64, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.PatchPost(id, fields)
+}
+
+func (s *Store) DeletePost(post storage.Post) (id int64, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.DeletePost(post)
+}
+
+func (s *Store) PostsBatch(op storage.BatchOp, mode storage.BatchMode, posts []storage.Post) (res []storage.BatchResult, err error) {
+	probe, err := s.c.allow()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { s.c.record(probe, err) }()
+	return s.Interface.PostsBatch(op, mode, posts)
+}
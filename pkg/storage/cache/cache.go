@@ -0,0 +1,256 @@
+// Пакет cache - кэширующая обёртка над storage.Interface.
+//
+// Кэшируются результаты Posts() и PostByID на время TTL. Любое изменение
+// публикаций или авторов (имя автора входит в публикацию) сбрасывает весь кэш
+// увеличением номера поколения, который входит в имена ключей. Удаление
+// отдельных ключей не подходит: промах, прочитавший из БД прежнее значение
+// до изменения, записал бы его в кэш уже после удаления; с поколением такая
+// запись попадает в ключ прежнего поколения и больше не читается.
+// Одновременные промахи по одному ключу выполняют один запрос к БД; он
+// выполняется в основной БД (storage.WithPrimary), а не на реплике, выбранной
+// для первого из клиентов: иначе отстающее значение попало бы в кэш и
+// вернулось клиенту, только что изменившему данные. Общий запрос не привязан
+// к запросу первого клиента (ограничен loadTimeout): отключение этого клиента
+// не прерывает его для остальных.
+// Ошибки кэша не влияют на ответ: запрос выполняется в БД.
+//
+// Счётчики попаданий, промахов и ошибок публикуются через expvar
+// (GET /debug/vars, переменная "cache").
+package cache
+
+import (
+	"GoNews/pkg/storage"
+	"context"
+	"encoding/json"
+	"expvar"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// Backend - хранилище кэша.
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+	Incr(ctx context.Context, key string) (int64, error)
+	Close() error
+}
+
+// Префикс ключей кэша, чтобы не пересекаться с данными при -typebd redis.
+const (
+	keyPrefix = "gonews:cache:"
+	genKey    = keyPrefix + "gen"
+)
+
+// Время выполнения общего запроса к БД при промахе.
+const loadTimeout = 10 * time.Second
+
+// Счётчики кэша.
+var metrics = expvar.NewMap("cache")
+
+// Stats - счётчики кэша.
+type Stats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Errors int64 `json:"errors"`
+}
+
+// ReadStats возвращает текущие значения счётчиков.
+func ReadStats() Stats {
+	get := func(name string) int64 {
+		if v, ok := metrics.Get(name).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	return Stats{Hits: get("hits"), Misses: get("misses"), Errors: get("errors")}
+}
+
+// Store - БД с кэшем публикаций.
+type Store struct {
+	storage.Interface
+	root  storage.Interface // хранилище без привязки к запросу (Bind)
+	cache Backend
+	ttl   time.Duration
+	group *group          // общая для привязанных копий (Bind)
+	ctx   context.Context // контекст чтения кэша (Bind), nil - context.Background()
+}
+
+// New оборачивает db кэшем c с временем жизни записей ttl.
+func New(db storage.Interface, c Backend, ttl time.Duration) *Store {
+	return &Store{Interface: db, root: db, cache: c, ttl: ttl, group: &group{}}
+}
+
+// Bind - копия обёртки над хранилищем, привязанным к ctx.
+func (s *Store) Bind(ctx context.Context) storage.Interface {
+	return &Store{Interface: storage.Bind(s.root, ctx), root: s.root, cache: s.cache, ttl: s.ttl, group: s.group, ctx: ctx}
+}
+
+func (s *Store) Close() error {
+	err := s.Interface.Close()
+	if cerr := s.cache.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Post - публикация.
+func (s *Store) Posts() ([]storage.Post, error) {
+	var posts []storage.Post
+	err := s.cached("posts", &posts, func(db storage.Interface) (interface{}, error) {
+		return db.Posts()
+	})
+	return posts, err
+}
+
+func (s *Store) PostByID(id int64) (storage.Post, error) {
+	var post storage.Post
+	err := s.cached("post:"+strconv.FormatInt(id, 10), &post, func(db storage.Interface) (interface{}, error) {
+		return db.PostByID(id)
+	})
+	return post, err
+}
+
+func (s *Store) AddPost(post storage.Post) (int64, error) {
+	defer s.invalidateAll()
+	return s.Interface.AddPost(post)
+}
+
+func (s *Store) UpdatePost(post storage.Post) (int64, error) {
+	defer s.invalidateAll()
+	return s.Interface.UpdatePost(post)
+}
+
+func (s *Store) PatchPost(id int64, fields map[string]interface{}) (int64, error) {
+	defer s.invalidateAll()
+	return s.Interface.PatchPost(id, fields)
+}
+
+func (s *Store) DeletePost(post storage.Post) (int64, error) {
+	defer s.invalidateAll()
+	return s.Interface.DeletePost(post)
+}
+
+func (s *Store) PostsBatch(op storage.BatchOp, mode storage.BatchMode, posts []storage.Post) ([]storage.BatchResult, error) {
+	defer s.invalidateAll()
+	return s.Interface.PostsBatch(op, mode, posts)
+}
+
+// Author - автор. Имя автора входит в публикации, поэтому изменения
+// авторов сбрасывают кэш публикаций.
+func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
+	defer s.invalidateAll()
+	return s.Interface.UpdateAuthor(author)
+}
+
+func (s *Store) PatchAuthor(id int64, fields map[string]interface{}) (int64, error) {
+	defer s.invalidateAll()
+	return s.Interface.PatchAuthor(id, fields)
+}
+
+func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
+	defer s.invalidateAll()
+	return s.Interface.DeleteAuthor(author)
+}
+
+func (s *Store) AuthorsBatch(op storage.BatchOp, mode storage.BatchMode, authors []storage.Author) ([]storage.BatchResult, error) {
+	defer s.invalidateAll()
+	return s.Interface.AuthorsBatch(op, mode, authors)
+}
+
+// Транзакция может изменить любые записи.
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Tx) error) error {
+	defer s.invalidateAll()
+	return s.Interface.WithTx(ctx, fn)
+}
+
+// Чтение значения name в dst из кэша или, при промахе, из БД через load.
+// Без кэша load читает через хранилище запроса, при промахе - в основной БД.
+// Каждый вызывающий получает свою копию данных (декодируется из JSON),
+// поэтому результат можно изменять.
+func (s *Store) cached(name string, dst interface{}, load func(db storage.Interface) (interface{}, error)) error {
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	gen, err := s.generation(ctx)
+	if err != nil {
+		metrics.Add("errors", 1)
+		return decodeLoaded(s.Interface, load, dst)
+	}
+	key := keyPrefix + strconv.FormatInt(gen, 10) + ":" + name
+
+	val, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		metrics.Add("errors", 1)
+	}
+	if ok {
+		if err := json.Unmarshal(val, dst); err == nil {
+			metrics.Add("hits", 1)
+			return nil
+		}
+		metrics.Add("errors", 1)
+	}
+	metrics.Add("misses", 1)
+
+	// Один запрос к БД на ключ при одновременных промахах.
+	val, err = s.group.do(ctx, key, func() ([]byte, error) {
+		lctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+		defer cancel()
+		v, err := load(storage.Bind(s.root, storage.WithPrimary(lctx)))
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.cache.Set(lctx, key, data, s.jitteredTTL()); err != nil {
+			metrics.Add("errors", 1)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(val, dst)
+}
+
+// Чтение из БД без кэша.
+func decodeLoaded(db storage.Interface, load func(db storage.Interface) (interface{}, error), dst interface{}) error {
+	v, err := load(db)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// Текущее поколение кэша (0, если счётчик ещё не создан).
+func (s *Store) generation(ctx context.Context) (int64, error) {
+	val, ok, err := s.cache.Get(ctx, genKey)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return strconv.ParseInt(string(val), 10, 64)
+}
+
+// Сброс всего кэша: ключи прежнего поколения больше не читаются и удаляются по TTL.
+func (s *Store) invalidateAll() {
+	if _, err := s.cache.Incr(context.Background(), genKey); err != nil {
+		metrics.Add("errors", 1)
+	}
+}
+
+// TTL со случайной добавкой до 10%, чтобы записи не истекали одновременно.
+func (s *Store) jitteredTTL() time.Duration {
+	if s.ttl <= 0 {
+		return 0
+	}
+	return s.ttl + time.Duration(rand.Int63n(int64(s.ttl)/10+1))
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// Выполняющийся запрос к БД по ключу.
+type call struct {
+	done chan struct{} // закрывается по завершении запроса
+	val  []byte
+	err  error
+}
+
+// Объединение одновременных запросов по одному ключу (защита от лавины промахов):
+// первый вызов запускает fn, все вызовы ждут его результата. fn выполняется
+// отдельно от вызывающих, поэтому отмена запроса первого клиента не прерывает
+// ожидание остальных; каждый вызов перестаёт ждать при отмене своего ctx.
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+func (g *group) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		g.calls[key] = c
+		go func() {
+			c.val, c.err = fn()
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(c.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
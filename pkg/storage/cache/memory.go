@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Memory - кэш в памяти процесса.
+type Memory struct {
+	mu    sync.Mutex
+	items map[string]item
+}
+
+type item struct {
+	val     []byte
+	expires time.Time // нулевое значение - без срока
+}
+
+// NewMemory создаёт кэш в памяти.
+func NewMemory() *Memory {
+	return &Memory{items: make(map[string]item)}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it, ok := m.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !it.expires.IsZero() && time.Now().After(it.expires) {
+		delete(m.items, key)
+		return nil, false, nil
+	}
+	return it.val, true, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, val []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it := item{val: val}
+	if ttl > 0 {
+		it.expires = time.Now().Add(ttl)
+	}
+	m.items[key] = it
+	return nil
+}
+
+func (m *Memory) Del(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.items, key)
+	}
+	return nil
+}
+
+// Incr увеличивает счётчик. При смене поколения удаляются все записи,
+// так как истёкшие ключи прежних поколений в памяти не удаляются сами.
+func (m *Memory) Incr(_ context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	if it, ok := m.items[key]; ok {
+		n, _ = strconv.ParseInt(string(it.val), 10, 64)
+	}
+	n++
+
+	if key == genKey {
+		m.items = make(map[string]item)
+	}
+	m.items[key] = item{val: []byte(strconv.FormatInt(n, 10))}
+	return n, nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,65 @@
+package memdb
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/storage"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Ключи API.
+type keyTable struct {
+	mu   sync.Mutex
+	keys map[int64]auth.Key
+	seq  int64
+}
+
+func newKeyTable() *keyTable {
+	return &keyTable{keys: map[int64]auth.Key{}}
+}
+
+func (s *Store) APIKeys() ([]auth.Key, error) {
+	s.keys.mu.Lock()
+	defer s.keys.mu.Unlock()
+
+	data := make([]auth.Key, 0, len(s.keys.keys))
+	for _, k := range s.keys.keys {
+		data = append(data, k)
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID < data[j].ID })
+	return data, nil
+}
+
+func (s *Store) APIKeyByHash(hash string) (auth.Key, error) {
+	s.keys.mu.Lock()
+	defer s.keys.mu.Unlock()
+
+	for _, k := range s.keys.keys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+	return auth.Key{}, fmt.Errorf("API key %w", storage.ErrNotFound)
+}
+
+func (s *Store) AddAPIKey(k auth.Key) (int64, error) {
+	s.keys.mu.Lock()
+	defer s.keys.mu.Unlock()
+
+	s.keys.seq++
+	k.ID = s.keys.seq
+	s.keys.keys[k.ID] = k
+	return k.ID, nil
+}
+
+func (s *Store) DeleteAPIKey(id int64) error {
+	s.keys.mu.Lock()
+	defer s.keys.mu.Unlock()
+
+	if _, ok := s.keys.keys[id]; !ok {
+		return fmt.Errorf("API key %v %w", id, storage.ErrNotFound)
+	}
+	delete(s.keys.keys, id)
+	return nil
+}
@@ -2,16 +2,19 @@ package memdb
 
 import (
 	"GoNews/pkg/storage"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"time"
+	"sync"
 )
 
 // Хранилище данных.
 type Store struct {
+	mu        sync.RWMutex
 	AuthorsDB map[int64]storage.Author
 	PostsDB   map[int64]storage.Post
+	hooks     *webhookTables // подписки и доставки webhooks
+	keys      *keyTable      // ключи API
+	users     *userTable     // пользователи API и их роли
 }
 
 func (s *Store) GetInform() string {
@@ -23,6 +26,9 @@ func New() (*Store, error) {
 	s := Store{
 		AuthorsDB: map[int64]storage.Author{},
 		PostsDB:   map[int64]storage.Post{},
+		hooks:     newWebhookTables(),
+		keys:      newKeyTable(),
+		users:     newUserTable(),
 	}
 
 	fmt.Println("Loaded bd: ", s.GetInform())
@@ -30,11 +36,116 @@ func New() (*Store, error) {
 	return &s, nil
 }
 
-func (s *Store) Close() {
+// Ping - БД в памяти доступна всегда.
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Store) Close() error {
+	return nil
+}
+
+// Копии таблиц для изменений с возможностью отката.
+func (s *Store) copyTables() (map[int64]storage.Author, map[int64]storage.Post) {
+	authors := make(map[int64]storage.Author, len(s.AuthorsDB))
+	for k, v := range s.AuthorsDB {
+		authors[k] = v
+	}
+	posts := make(map[int64]storage.Post, len(s.PostsDB))
+	for k, v := range s.PostsDB {
+		posts[k] = v
+	}
+	return authors, posts
+}
+
+// WithTx выполняет fn над копиями таблиц (copy-on-write) и при успехе
+// заменяет ими таблицы хранилища. На время fn хранилище заблокировано,
+// поэтому внутри fn нужно использовать только tx.
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &Store{}
+	tx.AuthorsDB, tx.PostsDB = s.copyTables()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.AuthorsDB, s.PostsDB = tx.AuthorsDB, tx.PostsDB
+	return nil
+}
+
+// Выполнение пакета под блокировкой хранилища.
+// В режиме BatchAtomic операции применяются к копиям таблиц,
+// при ошибке исходные таблицы остаются без изменений.
+func (s *Store) batch(n int, mode storage.BatchMode, fn func(i int) (int64, error)) ([]storage.BatchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	authors, posts := s.AuthorsDB, s.PostsDB
+	if mode == storage.BatchAtomic {
+		s.AuthorsDB, s.PostsDB = s.copyTables()
+	}
+
+	results := storage.RunBatch(n, mode, fn)
+	if mode == storage.BatchAtomic && storage.BatchFailed(results) {
+		s.AuthorsDB, s.PostsDB = authors, posts
+		return results, storage.AbortBatch(results)
+	}
+
+	return results, nil
+}
+
+// ID новой записи без ID (как последовательность PostgreSQL): следующий
+// после наибольшего. Вызывается под блокировкой хранилища.
+func (s *Store) nextAuthorID() int64 {
+	var max int64
+	for id := range s.AuthorsDB {
+		if id > max {
+			max = id
+		}
+	}
+	return max + 1
+}
+
+func (s *Store) nextPostID() int64 {
+	var max int64
+	for id := range s.PostsDB {
+		if id > max {
+			max = id
+		}
+	}
+	return max + 1
+}
+
+// Snapshot - авторы и публикации под одной блокировкой.
+func (s *Store) Snapshot(ctx context.Context) ([]storage.Author, []storage.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	authors := make([]storage.Author, 0, len(s.AuthorsDB))
+	for _, v := range s.AuthorsDB {
+		authors = append(authors, v)
+	}
+	posts := make([]storage.Post, 0, len(s.PostsDB))
+	for _, v := range s.PostsDB {
+		if author, ok := s.AuthorsDB[v.AuthorID]; ok {
+			v.AuthorName = author.Name
+		}
+		posts = append(posts, v)
+	}
+	return authors, posts, nil
 }
 
 // Author - автор.
 func (s *Store) Authors() ([]storage.Author, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	var data []storage.Author
 
 	for _, v := range s.AuthorsDB {
@@ -43,7 +154,27 @@ func (s *Store) Authors() ([]storage.Author, error) {
 	return data, nil
 }
 
+func (s *Store) AuthorByID(id int64) (storage.Author, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	author, ok := s.AuthorsDB[id]
+	if !ok {
+		return storage.Author{}, fmt.Errorf("Id: %v %w", id, storage.ErrNotFound)
+	}
+	return author, nil
+}
+
 func (s *Store) AddAuthor(author storage.Author) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addAuthor(author)
+}
+
+func (s *Store) addAuthor(author storage.Author) (int64, error) {
+	if author.ID == 0 {
+		author.ID = s.nextAuthorID()
+	}
 	if _, ok := s.AuthorsDB[author.ID]; ok {
 		return 0, fmt.Errorf("Id: %v already exist", author.ID)
 	} else {
@@ -53,6 +184,12 @@ func (s *Store) AddAuthor(author storage.Author) (int64, error) {
 }
 
 func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.updateAuthor(author)
+}
+
+func (s *Store) updateAuthor(author storage.Author) (int64, error) {
 	if _, ok := s.AuthorsDB[author.ID]; !ok {
 		return 0, fmt.Errorf("Id: %v not exist", author.ID)
 	} else {
@@ -61,7 +198,28 @@ func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
 	}
 }
 
+func (s *Store) PatchAuthor(id int64, fields map[string]interface{}) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	author, ok := s.AuthorsDB[id]
+	if !ok {
+		return 0, fmt.Errorf("Id: %v %w", id, storage.ErrNotFound)
+	}
+	if err := storage.ApplyAuthorFields(&author, fields); err != nil {
+		return 0, err
+	}
+	s.AuthorsDB[id] = author
+	return id, nil
+}
+
 func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteAuthor(author)
+}
+
+func (s *Store) deleteAuthor(author storage.Author) (int64, error) {
 	if _, ok := s.AuthorsDB[author.ID]; !ok {
 		return 0, fmt.Errorf("Id: %v not exist", author.ID)
 	} else {
@@ -70,24 +228,11 @@ func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
 	}
 }
 
-func (s *Store) InsertInitDataFromFileAuthors(filename string) error {
-
-	file, _ := ioutil.ReadFile(filename)
-	data := []storage.Author{}
-	err := json.Unmarshal([]byte(file), &data)
-	if err != nil {
-		return err
-	}
-
-	for i := 0; i < len(data); i++ {
-		s.AuthorsDB[data[i].ID] = data[i]
-	}
-
-	return nil
-}
-
 // Post - публикация.
 func (s *Store) Posts() ([]storage.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	var data []storage.Post
 
 	for _, v := range s.PostsDB {
@@ -96,18 +241,35 @@ func (s *Store) Posts() ([]storage.Post, error) {
 			v.AuthorName = s.AuthorsDB[v.AuthorID].Name
 		}
 
-		dt_CreatedAt := time.Unix(v.CreatedAt/1000, 0)
-		v.CreatedAtTxt = dt_CreatedAt.Format("2006-01-02 15:04:05.000")
-
-		dt_PublishedAtTxt := time.Unix(v.PublishedAt/1000, 0)
-		v.PublishedAtTxt = dt_PublishedAtTxt.Format("2006-01-02 15:04:05.000")
-
 		data = append(data, v)
 	}
 	return data, nil
 }
 
+func (s *Store) PostByID(id int64) (storage.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	post, ok := s.PostsDB[id]
+	if !ok {
+		return storage.Post{}, fmt.Errorf("Id: %v %w", id, storage.ErrNotFound)
+	}
+	if author, ok := s.AuthorsDB[post.AuthorID]; ok {
+		post.AuthorName = author.Name
+	}
+	return post, nil
+}
+
 func (s *Store) AddPost(post storage.Post) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addPost(post)
+}
+
+func (s *Store) addPost(post storage.Post) (int64, error) {
+	if post.ID == 0 {
+		post.ID = s.nextPostID()
+	}
 	if _, ok := s.PostsDB[post.ID]; ok {
 		return 0, fmt.Errorf("Id: %v already exist", post.ID)
 	} else {
@@ -122,6 +284,12 @@ func (s *Store) AddPost(post storage.Post) (int64, error) {
 }
 
 func (s *Store) UpdatePost(post storage.Post) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.updatePost(post)
+}
+
+func (s *Store) updatePost(post storage.Post) (int64, error) {
 	if _, ok := s.PostsDB[post.ID]; !ok {
 		return 0, fmt.Errorf("Id: %v not exist", post.ID)
 	} else {
@@ -135,7 +303,31 @@ func (s *Store) UpdatePost(post storage.Post) (int64, error) {
 	}
 }
 
+func (s *Store) PatchPost(id int64, fields map[string]interface{}) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.PostsDB[id]
+	if !ok {
+		return 0, fmt.Errorf("Id: %v %w", id, storage.ErrNotFound)
+	}
+	if err := storage.ApplyPostFields(&post, fields); err != nil {
+		return 0, err
+	}
+	if _, ok := s.AuthorsDB[post.AuthorID]; !ok {
+		return 0, fmt.Errorf("Author with id: %v not exist", post.AuthorID)
+	}
+	s.PostsDB[id] = post
+	return id, nil
+}
+
 func (s *Store) DeletePost(post storage.Post) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deletePost(post)
+}
+
+func (s *Store) deletePost(post storage.Post) (int64, error) {
 	if _, ok := s.PostsDB[post.ID]; !ok {
 		return 0, fmt.Errorf("Id: %v not exist", post.ID)
 	} else {
@@ -144,17 +336,30 @@ func (s *Store) DeletePost(post storage.Post) (int64, error) {
 	}
 }
 
-func (s *Store) InsertInitDataFromFilePosts(filename string) error {
-	file, _ := ioutil.ReadFile(filename)
-	data := []storage.Post{}
-	err := json.Unmarshal([]byte(file), &data)
-	if err != nil {
-		return err
-	}
-
-	for i := 0; i < len(data); i++ {
-		s.PostsDB[data[i].ID] = data[i]
-	}
+func (s *Store) AuthorsBatch(op storage.BatchOp, mode storage.BatchMode, authors []storage.Author) ([]storage.BatchResult, error) {
+	return s.batch(len(authors), mode, func(i int) (int64, error) {
+		switch op {
+		case storage.BatchCreate:
+			return s.addAuthor(authors[i])
+		case storage.BatchUpdate:
+			return s.updateAuthor(authors[i])
+		case storage.BatchDelete:
+			return s.deleteAuthor(authors[i])
+		}
+		return 0, fmt.Errorf("unknown batch operation: %v", op)
+	})
+}
 
-	return nil
+func (s *Store) PostsBatch(op storage.BatchOp, mode storage.BatchMode, posts []storage.Post) ([]storage.BatchResult, error) {
+	return s.batch(len(posts), mode, func(i int) (int64, error) {
+		switch op {
+		case storage.BatchCreate:
+			return s.addPost(posts[i])
+		case storage.BatchUpdate:
+			return s.updatePost(posts[i])
+		case storage.BatchDelete:
+			return s.deletePost(posts[i])
+		}
+		return 0, fmt.Errorf("unknown batch operation: %v", op)
+	})
 }
@@ -0,0 +1,97 @@
+package memdb
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/storage"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Пользователи API и их роли.
+type userTable struct {
+	mu    sync.Mutex
+	users map[int64]auth.User
+	seq   int64
+}
+
+func newUserTable() *userTable {
+	return &userTable{users: map[int64]auth.User{}}
+}
+
+func (s *Store) Users() ([]auth.User, error) {
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	data := make([]auth.User, 0, len(s.users.users))
+	for _, u := range s.users.users {
+		data = append(data, u)
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID < data[j].ID })
+	return data, nil
+}
+
+func (s *Store) UserByID(id int64) (auth.User, error) {
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	u, ok := s.users.users[id]
+	if !ok {
+		return u, fmt.Errorf("user %v %w", id, storage.ErrNotFound)
+	}
+	return u, nil
+}
+
+func (s *Store) UserByName(name string) (auth.User, error) {
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	for _, u := range s.users.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+	return auth.User{}, fmt.Errorf("user %q %w", name, storage.ErrNotFound)
+}
+
+func (s *Store) AddUser(u auth.User) (int64, error) {
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	for _, other := range s.users.users {
+		if other.Name == u.Name {
+			return 0, fmt.Errorf("%q: %w", u.Name, auth.ErrUserExists)
+		}
+	}
+	s.users.seq++
+	u.ID = s.users.seq
+	s.users.users[u.ID] = u
+	return u.ID, nil
+}
+
+func (s *Store) UpdateUser(u auth.User) error {
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	if _, ok := s.users.users[u.ID]; !ok {
+		return fmt.Errorf("user %v %w", u.ID, storage.ErrNotFound)
+	}
+	for _, other := range s.users.users {
+		if other.Name == u.Name && other.ID != u.ID {
+			return fmt.Errorf("%q: %w", u.Name, auth.ErrUserExists)
+		}
+	}
+	s.users.users[u.ID] = u
+	return nil
+}
+
+func (s *Store) DeleteUser(id int64) error {
+	s.users.mu.Lock()
+	defer s.users.mu.Unlock()
+
+	if _, ok := s.users.users[id]; !ok {
+		return fmt.Errorf("user %v %w", id, storage.ErrNotFound)
+	}
+	delete(s.users.users, id)
+	return nil
+}
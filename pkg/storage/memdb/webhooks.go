@@ -0,0 +1,173 @@
+package memdb
+
+import (
+	"GoNews/pkg/storage"
+	"GoNews/pkg/webhooks"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Подписки и доставки webhooks.
+type webhookTables struct {
+	mu          sync.Mutex
+	subs        map[int64]webhooks.Subscription
+	deliveries  map[int64]webhooks.Delivery
+	subSeq      int64
+	deliverySeq int64
+}
+
+func newWebhookTables() *webhookTables {
+	return &webhookTables{
+		subs:       map[int64]webhooks.Subscription{},
+		deliveries: map[int64]webhooks.Delivery{},
+	}
+}
+
+func (s *Store) Webhooks() ([]webhooks.Subscription, error) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	data := make([]webhooks.Subscription, 0, len(s.hooks.subs))
+	for _, sub := range s.hooks.subs {
+		data = append(data, copySubscription(sub))
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID < data[j].ID })
+	return data, nil
+}
+
+func (s *Store) WebhookByID(id int64) (webhooks.Subscription, error) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	sub, ok := s.hooks.subs[id]
+	if !ok {
+		return sub, fmt.Errorf("webhook %v %w", id, storage.ErrNotFound)
+	}
+	return copySubscription(sub), nil
+}
+
+func (s *Store) AddWebhook(sub webhooks.Subscription) (int64, error) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	s.hooks.subSeq++
+	sub.ID = s.hooks.subSeq
+	s.hooks.subs[sub.ID] = copySubscription(sub)
+	return sub.ID, nil
+}
+
+func (s *Store) UpdateWebhook(sub webhooks.Subscription) error {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	if _, ok := s.hooks.subs[sub.ID]; !ok {
+		return fmt.Errorf("webhook %v %w", sub.ID, storage.ErrNotFound)
+	}
+	s.hooks.subs[sub.ID] = copySubscription(sub)
+	return nil
+}
+
+func (s *Store) DeleteWebhook(id int64) error {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	if _, ok := s.hooks.subs[id]; !ok {
+		return fmt.Errorf("webhook %v %w", id, storage.ErrNotFound)
+	}
+	delete(s.hooks.subs, id)
+	return nil
+}
+
+func (s *Store) AddDelivery(d webhooks.Delivery) (int64, error) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	s.hooks.deliverySeq++
+	d.ID = s.hooks.deliverySeq
+	s.hooks.deliveries[d.ID] = d
+	return d.ID, nil
+}
+
+func (s *Store) UpdateDelivery(d webhooks.Delivery) error {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	if _, ok := s.hooks.deliveries[d.ID]; !ok {
+		return fmt.Errorf("delivery %v %w", d.ID, storage.ErrNotFound)
+	}
+	s.hooks.deliveries[d.ID] = d
+	return nil
+}
+
+func (s *Store) DeliveryByID(id int64) (webhooks.Delivery, error) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	d, ok := s.hooks.deliveries[id]
+	if !ok {
+		return d, fmt.Errorf("delivery %v %w", id, storage.ErrNotFound)
+	}
+	return d, nil
+}
+
+func (s *Store) DueDeliveries(now int64, limit int) ([]webhooks.Delivery, error) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	var data []webhooks.Delivery
+	for _, d := range s.hooks.deliveries {
+		if d.Status == webhooks.StatusPending && d.NextAttemptAt <= now {
+			data = append(data, d)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool {
+		if data[i].NextAttemptAt != data[j].NextAttemptAt {
+			return data[i].NextAttemptAt < data[j].NextAttemptAt
+		}
+		return data[i].ID < data[j].ID
+	})
+	if limit > 0 && len(data) > limit {
+		data = data[:limit]
+	}
+	return data, nil
+}
+
+func (s *Store) Deliveries(f webhooks.DeliveryFilter) ([]webhooks.Delivery, error) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	var data []webhooks.Delivery
+	for _, d := range s.hooks.deliveries {
+		if (f.SubscriptionID == 0 || d.SubscriptionID == f.SubscriptionID) &&
+			(f.Status == "" || d.Status == f.Status) &&
+			(f.BeforeID == 0 || d.ID < f.BeforeID) {
+			data = append(data, d)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID > data[j].ID })
+	if f.Limit > 0 && len(data) > f.Limit {
+		data = data[:f.Limit]
+	}
+	return data, nil
+}
+
+func (s *Store) DeleteDeliveries(before int64) (int64, error) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+
+	var n int64
+	for id, d := range s.hooks.deliveries {
+		if d.Status != webhooks.StatusPending && d.UpdatedAt < before {
+			delete(s.hooks.deliveries, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Копия подписки без общего с хранилищем списка событий.
+func copySubscription(sub webhooks.Subscription) webhooks.Subscription {
+	sub.Events = append([]string(nil), sub.Events...)
+	return sub
+}
@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/storage"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Коллекция ключей API.
+const collectionAPIKeys = "api_keys"
+
+func (s *Store) APIKeys() ([]auth.Key, error) {
+	ctx := context.Background()
+	cur, err := s.db.Database(databaseName).Collection(collectionAPIKeys).Find(ctx, bson.M{},
+		options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var data []auth.Key
+	err = cur.All(ctx, &data)
+	return data, err
+}
+
+func (s *Store) APIKeyByHash(hash string) (auth.Key, error) {
+	var k auth.Key
+	err := s.db.Database(databaseName).Collection(collectionAPIKeys).FindOne(context.Background(), bson.M{"hash": hash}).Decode(&k)
+	if err == mongo.ErrNoDocuments {
+		return k, fmt.Errorf("API key %w", storage.ErrNotFound)
+	}
+	return k, err
+}
+
+func (s *Store) AddAPIKey(k auth.Key) (int64, error) {
+	ctx := context.Background()
+	id, err := s.nextID(ctx, collectionAPIKeys)
+	if err != nil {
+		return 0, err
+	}
+	k.ID = id
+	_, err = s.db.Database(databaseName).Collection(collectionAPIKeys).InsertOne(ctx, k)
+	return id, err
+}
+
+func (s *Store) DeleteAPIKey(id int64) error {
+	result, err := s.db.Database(databaseName).Collection(collectionAPIKeys).DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("API key %v %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
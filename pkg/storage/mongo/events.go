@@ -0,0 +1,142 @@
+package mongo
+
+import (
+	"GoNews/pkg/events"
+	"GoNews/pkg/storage"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Изменение документа в change stream.
+type changeDoc struct {
+	OperationType string `bson:"operationType"`
+	NS            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID int64 `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument             bson.Raw            `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.Raw            `bson:"fullDocumentBeforeChange"`
+	ClusterTime              primitive.Timestamp `bson:"clusterTime"`
+}
+
+// Subscribe возвращает события изменений после afterID (токен продолжения
+// change stream). Change streams работают только на наборе реплик.
+// Состояние до изменения доступно, если для коллекций включены
+// pre-images (MongoDB 6.0+); подписка пытается их включить.
+func (s *Store) Subscribe(ctx context.Context, afterID string) (<-chan events.Event, error) {
+	db := s.db.Database(databaseName)
+
+	for _, coll := range []string{collectionAuthors, collectionPosts} {
+		// Ошибка не мешает подписке: без pre-images Before будет пустым.
+		db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: coll},
+			{Key: "changeStreamPreAndPostImages", Value: bson.D{{Key: "enabled", Value: true}}},
+		})
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.D{
+		{Key: "ns.coll", Value: bson.D{{Key: "$in", Value: bson.A{collectionAuthors, collectionPosts}}}},
+		{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace", "delete"}}}},
+	}}}}
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	if afterID != "" {
+		opts.SetResumeAfter(bson.M{"_data": afterID})
+	}
+
+	stream, err := db.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan events.Event)
+	go func() {
+		defer close(ch)
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			var change changeDoc
+			if err := stream.Decode(&change); err != nil {
+				return
+			}
+			e, err := changeEvent(change)
+			if err != nil {
+				return
+			}
+			e.ID = stream.ResumeToken().Lookup("_data").StringValue()
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Преобразование изменения документа в событие.
+func changeEvent(c changeDoc) (events.Event, error) {
+	op := events.OpUpdate
+	switch c.OperationType {
+	case "insert":
+		op = events.OpCreate
+	case "delete":
+		op = events.OpDelete
+	}
+
+	var e events.Event
+	var err error
+	switch c.NS.Coll {
+	case collectionAuthors:
+		var before, after *storage.Author
+		if before, err = decodeAuthor(c.FullDocumentBeforeChange); err != nil {
+			return e, err
+		}
+		if after, err = decodeAuthor(c.FullDocument); err != nil {
+			return e, err
+		}
+		e, err = events.AuthorEvent(op, before, after)
+	default:
+		var before, after *storage.Post
+		if before, err = decodePost(c.FullDocumentBeforeChange); err != nil {
+			return e, err
+		}
+		if after, err = decodePost(c.FullDocument); err != nil {
+			return e, err
+		}
+		e, err = events.PostEvent(op, before, after)
+	}
+	e.EntityID = c.DocumentKey.ID
+	e.Time = time.Unix(int64(c.ClusterTime.T), 0)
+	return e, err
+}
+
+func decodeAuthor(raw bson.Raw) (*storage.Author, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var a storage.Author
+	if err := bson.Unmarshal(raw, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func decodePost(raw bson.Raw) (*storage.Post, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var p storage.Post
+	if err := bson.Unmarshal(raw, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
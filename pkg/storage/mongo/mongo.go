@@ -3,13 +3,15 @@ package mongo
 import (
 	"GoNews/pkg/storage"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 const (
@@ -18,9 +20,16 @@ const (
 	collectionPosts   = "posts"   // имя коллекции в учебной БД
 )
 
+// Время ожидания завершения операций при закрытии клиента.
+const disconnectTimeout = 10 * time.Second
+
 // Хранилище данных.
 type Store struct {
-	db *mongo.Client
+	db    *mongo.Client
+	ctx   context.Context // контекст сессии внутри WithTx
+	bound context.Context // контекст запросов вне транзакции (Bind); внутри WithTx - контекст WithTx
+	pools *poolSet        // состояние пулов соединений
+	txn   bool            // транзакции доступны (replica set или mongos)
 }
 
 func (s *Store) GetInform() string {
@@ -30,7 +39,9 @@ func (s *Store) GetInform() string {
 // Конструктор объекта хранилища.
 func New(constr string) (*Store, error) {
 	// подключение к СУБД MongoDB
-	mongoOpts := options.Client().ApplyURI(constr)
+	pools := newPoolSet()
+	mongoOpts := options.Client().ApplyURI(constr).SetPoolMonitor(pools.monitor()).
+		SetMonitor((&commandTracer{}).monitor())
 	db, err := mongo.Connect(context.Background(), mongoOpts)
 	if err != nil {
 		return nil, err
@@ -41,17 +52,160 @@ func New(constr string) (*Store, error) {
 		return nil, err
 	}
 
+	txn, err := supportsTx(db)
+	if err != nil {
+		return nil, err
+	}
+
 	s := Store{
-		db: db,
+		db:    db,
+		pools: pools,
+		txn:   txn,
 	}
 
 	fmt.Println("Loaded bd: ", s.GetInform())
+	if !txn {
+		fmt.Println("MongoDB: standalone server, transactions are unavailable " +
+			"(batch mode atomic, /import, restore and /authors:withPosts require a replica set)")
+	}
 
 	return &s, nil
 }
 
-func (s *Store) Close() {
-	s.db.Disconnect(context.Background())
+// Транзакции доступны в replica set (ответ hello содержит setName)
+// и через mongos (msg "isdbgrid"), но не на отдельном сервере.
+func supportsTx(db *mongo.Client) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	admin := db.Database("admin")
+	err := admin.RunCommand(context.Background(), bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		// hello - с MongoDB 4.4.2, раньше - isMaster.
+		err = admin.RunCommand(context.Background(), bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	}
+	if err != nil {
+		return false, err
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
+
+// Ошибка операции, требующей транзакции, на отдельном сервере.
+func errNoTx() error {
+	return fmt.Errorf("MongoDB: %w: a replica set or mongos is required", storage.ErrTxUnsupported)
+}
+
+// Ping проверяет соединение с основным сервером.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx, readpref.Primary())
+}
+
+// Version - версия сервера MongoDB.
+func (s *Store) Version(ctx context.Context) (string, error) {
+	var info struct {
+		Version string `bson:"version"`
+	}
+	err := s.db.Database(databaseName).RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&info)
+	return info.Version, err
+}
+
+// Close закрывает соединения клиента, дожидаясь завершения операций
+// (не дольше disconnectTimeout).
+func (s *Store) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), disconnectTimeout)
+	defer cancel()
+	return s.db.Disconnect(ctx)
+}
+
+// Bind - копия хранилища, выполняющая операции с контекстом ctx.
+func (s *Store) Bind(ctx context.Context) storage.Interface {
+	c := *s
+	c.bound = ctx
+	return &c
+}
+
+// Контекст операций с БД: внутри WithTx - контекст сессии с транзакцией.
+func (s *Store) opCtx() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	if s.bound != nil {
+		return s.bound
+	}
+	return context.Background()
+}
+
+// WithTx выполняет fn в транзакции MongoDB (сессия с WithTransaction).
+// Транзакции поддерживаются только в replica set или через mongos, на отдельном
+// сервере возвращается storage.ErrTxUnsupported.
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Tx) error) error {
+	if s.ctx != nil {
+		// Уже внутри транзакции: MongoDB не поддерживает вложенные транзакции.
+		return fn(s)
+	}
+	if !s.txn {
+		return errNoTx()
+	}
+
+	return s.db.UseSession(ctx, func(sc mongo.SessionContext) error {
+		_, err := sc.WithTransaction(sc, func(sc mongo.SessionContext) (interface{}, error) {
+			return nil, fn(&Store{db: s.db, ctx: sc, bound: ctx, pools: s.pools, txn: s.txn})
+		})
+		return err
+	})
+}
+
+// Snapshot читает авторов и публикации в транзакции с read concern
+// "snapshot": обе выборки видят один момент. На отдельном сервере
+// транзакций нет - storage.ErrTxUnsupported.
+func (s *Store) Snapshot(ctx context.Context) (authors []storage.Author, posts []storage.Post, err error) {
+	if !s.txn {
+		return nil, nil, errNoTx()
+	}
+
+	opts := options.Transaction().SetReadConcern(readconcern.Snapshot())
+	err = s.db.UseSession(ctx, func(sc mongo.SessionContext) error {
+		_, err := sc.WithTransaction(sc, func(sc mongo.SessionContext) (interface{}, error) {
+			tx := &Store{db: s.db, ctx: sc, bound: ctx, pools: s.pools, txn: s.txn}
+			var err error
+			if authors, err = tx.Authors(); err != nil {
+				return nil, err
+			}
+			posts, err = tx.Posts()
+			return nil, err
+		}, opts)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return authors, posts, nil
+}
+
+// ID новой записи collectionName без ID (как последовательность PostgreSQL):
+// следующее значение счётчика в counters, не занятое записью с ID, заданным
+// клиентом. Счётчик изменяется вне транзакции (как nextval): при откате
+// значение не возвращается, одновременные транзакции на нём не конфликтуют.
+func (s *Store) newRecordID(collectionName string) (int64, error) {
+	seqCtx := s.bound
+	if seqCtx == nil {
+		seqCtx = context.Background()
+	}
+	collection := s.db.Database(databaseName).Collection(collectionName)
+	for {
+		id, err := s.nextID(seqCtx, collectionName)
+		if err != nil {
+			return 0, err
+		}
+		n, err := collection.CountDocuments(s.opCtx(), bson.M{"_id": id})
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return id, nil
+		}
+	}
 }
 
 // Author - автор.
@@ -60,23 +214,44 @@ func (s *Store) Authors() ([]storage.Author, error) {
 	var authors []storage.Author
 
 	collection := s.db.Database(databaseName).Collection(collectionAuthors)
-	cursor, err := collection.Find(context.Background(), bson.M{})
+	cursor, err := collection.Find(s.opCtx(), bson.M{})
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(s.opCtx())
 
-	if err = cursor.All(context.Background(), &authors); err != nil {
+	if err = cursor.All(s.opCtx(), &authors); err != nil {
 		return nil, err
 	}
 
 	return authors, nil
 }
 
+func (s *Store) AuthorByID(id int64) (storage.Author, error) {
+
+	var author storage.Author
+
+	collection := s.db.Database(databaseName).Collection(collectionAuthors)
+	err := collection.FindOne(s.opCtx(), bson.M{"_id": id}).Decode(&author)
+	if err == mongo.ErrNoDocuments {
+		return author, fmt.Errorf("Id: %v %w", id, storage.ErrNotFound)
+	}
+
+	return author, err
+}
+
 func (s *Store) AddAuthor(author storage.Author) (int64, error) {
 
+	if author.ID == 0 {
+		id, err := s.newRecordID(collectionAuthors)
+		if err != nil {
+			return 0, err
+		}
+		author.ID = id
+	}
+
 	collection := s.db.Database(databaseName).Collection(collectionAuthors)
-	_, err := collection.InsertOne(context.Background(), author)
+	_, err := collection.InsertOne(s.opCtx(), author)
 	if err != nil {
 		return 0, err
 	}
@@ -91,7 +266,7 @@ func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
 	id_doc := bson.M{"_id": author.ID}
 
 	collection := s.db.Database(databaseName).Collection(collectionAuthors)
-	result, err := collection.UpdateOne(context.Background(), id_doc, bson.M{"$set": doc})
+	result, err := collection.UpdateOne(s.opCtx(), id_doc, bson.M{"$set": doc})
 	if err != nil {
 		return 0, err
 	}
@@ -102,11 +277,15 @@ func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
 	return author.ID, nil
 }
 
+func (s *Store) PatchAuthor(id int64, fields map[string]interface{}) (int64, error) {
+	return s.patch(collectionAuthors, id, fields)
+}
+
 func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
 	id_doc := bson.M{"_id": author.ID}
 
 	collection := s.db.Database(databaseName).Collection(collectionAuthors)
-	result, err := collection.DeleteOne(context.Background(), id_doc)
+	result, err := collection.DeleteOne(s.opCtx(), id_doc)
 	if err != nil {
 		return 0, err
 	}
@@ -117,42 +296,29 @@ func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
 	return author.ID, nil
 }
 
-func (s *Store) InsertInitDataFromFileAuthors(filename string) error {
-
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
-	}
-
-	var authors []storage.Author
-	err = json.Unmarshal(data, &authors)
-	if err != nil {
-		return err
-	}
-
-	var documents []interface{}
-	for _, author := range authors {
-		documents = append(documents, author)
-	}
+// Post - публикация.
+func (s *Store) Posts() ([]storage.Post, error) {
+	return s.findPosts(bson.M{})
+}
 
-	collection := s.db.Database(databaseName).Collection(collectionAuthors)
-	_, err = collection.DeleteMany(context.Background(), bson.M{})
+func (s *Store) PostByID(id int64) (storage.Post, error) {
+	posts, err := s.findPosts(bson.M{"_id": id})
 	if err != nil {
-		return err
+		return storage.Post{}, err
 	}
-
-	_, err = collection.InsertMany(context.Background(), documents)
-	if err != nil {
-		return err
+	if len(posts) == 0 {
+		return storage.Post{}, fmt.Errorf("Id: %v %w", id, storage.ErrNotFound)
 	}
-
-	return nil
+	return posts[0], nil
 }
 
-// Post - публикация.
-func (s *Store) Posts() ([]storage.Post, error) {
+// Выборка публикаций по фильтру match с именем автора.
+func (s *Store) findPosts(match bson.M) ([]storage.Post, error) {
 
 	pipeline := bson.A{
+		bson.M{
+			"$match": match,
+		},
 		bson.M{
 			"$lookup": bson.M{
 				"from":         "authors",
@@ -170,40 +336,26 @@ func (s *Store) Posts() ([]storage.Post, error) {
 		bson.M{
 			"$project": bson.M{
 
-				"_id":         1,
-				"author_id":   1,
-				"author_name": bson.M{"$ifNull": []interface{}{"$author.name", "None"}},
-				"title":       1,
-				"content":     1,
-				"created_at":  1,
-				"created_at_txt": bson.M{
-					"$cond": bson.M{
-						"if":   bson.M{"$gt": []interface{}{"$created_at", nil}},
-						"then": bson.M{"$dateToString": bson.M{"format": "%d.%m.%Y %H:%M:%S", "date": bson.M{"$toDate": bson.M{"$multiply": []interface{}{"$created_at", 1}}}}},
-						"else": "",
-					},
-				},
+				"_id":          1,
+				"author_id":    1,
+				"author_name":  bson.M{"$ifNull": []interface{}{"$author.name", "None"}},
+				"title":        1,
+				"content":      1,
+				"created_at":   1,
 				"published_at": 1,
-				"published_at_txt": bson.M{
-					"$cond": bson.M{
-						"if":   bson.M{"$gt": []interface{}{"$published_at", nil}},
-						"then": bson.M{"$dateToString": bson.M{"format": "%d.%m.%Y %H:%M:%S", "date": bson.M{"$toDate": bson.M{"$multiply": []interface{}{"$published_at", 1}}}}},
-						"else": "",
-					},
-				},
 			},
 		},
 	}
 	var posts []storage.Post
 
 	collection := s.db.Database(databaseName).Collection(collectionPosts)
-	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	cursor, err := collection.Aggregate(s.opCtx(), pipeline)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(s.opCtx())
 
-	if err = cursor.All(context.Background(), &posts); err != nil {
+	if err = cursor.All(s.opCtx(), &posts); err != nil {
 		return nil, err
 	}
 
@@ -213,8 +365,16 @@ func (s *Store) Posts() ([]storage.Post, error) {
 
 func (s *Store) AddPost(post storage.Post) (int64, error) {
 
+	if post.ID == 0 {
+		id, err := s.newRecordID(collectionPosts)
+		if err != nil {
+			return 0, err
+		}
+		post.ID = id
+	}
+
 	collection := s.db.Database(databaseName).Collection(collectionPosts)
-	_, err := collection.InsertOne(context.Background(), post)
+	_, err := collection.InsertOne(s.opCtx(), post)
 	if err != nil {
 		return 0, err
 	}
@@ -234,7 +394,7 @@ func (s *Store) UpdatePost(post storage.Post) (int64, error) {
 	id_doc := bson.M{"_id": post.ID}
 
 	collection := s.db.Database(databaseName).Collection(collectionPosts)
-	result, err := collection.UpdateOne(context.Background(), id_doc, bson.M{"$set": doc})
+	result, err := collection.UpdateOne(s.opCtx(), id_doc, bson.M{"$set": doc})
 	//fmt.Printf("%#v\n", result)
 	if err != nil {
 		return 0, err
@@ -246,12 +406,33 @@ func (s *Store) UpdatePost(post storage.Post) (int64, error) {
 	return post.ID, nil
 }
 
+func (s *Store) PatchPost(id int64, fields map[string]interface{}) (int64, error) {
+	return s.patch(collectionPosts, id, fields)
+}
+
+// Изменение только переданных полей документа ($set).
+// Имена полей в JSON и в документе совпадают.
+func (s *Store) patch(collectionName string, id int64, fields map[string]interface{}) (int64, error) {
+	id_doc := bson.M{"_id": id}
+
+	collection := s.db.Database(databaseName).Collection(collectionName)
+	result, err := collection.UpdateOne(s.opCtx(), id_doc, bson.M{"$set": bson.M(fields)})
+	if err != nil {
+		return 0, err
+	}
+	if result.MatchedCount == 0 {
+		return 0, fmt.Errorf("PATCH Id: %v %w", id_doc, storage.ErrNotFound)
+	}
+
+	return id, nil
+}
+
 func (s *Store) DeletePost(post storage.Post) (int64, error) {
 
 	id_doc := bson.M{"_id": post.ID}
 
 	collection := s.db.Database(databaseName).Collection(collectionPosts)
-	result, err := collection.DeleteOne(context.Background(), id_doc)
+	result, err := collection.DeleteOne(s.opCtx(), id_doc)
 	if err != nil {
 		return 0, err
 	}
@@ -262,44 +443,152 @@ func (s *Store) DeletePost(post storage.Post) (int64, error) {
 	return post.ID, nil
 }
 
-func (s *Store) InsertInitDataFromFilePosts(filename string) error {
-
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
+// Пакетная обработка документов коллекции.
+// Наличие документов проверяется одним запросом до записи. В режиме BatchAtomic
+// запись выполняется упорядоченным BulkWrite внутри транзакции (требуется
+// replica set или mongos, иначе storage.ErrTxUnsupported), в режиме
+// BatchBestEffort - неупорядоченным BulkWrite.
+func (s *Store) batch(collectionName string, op storage.BatchOp, mode storage.BatchMode, ids []int64, model func(i int) mongo.WriteModel) ([]storage.BatchResult, error) {
+	if !op.Valid() {
+		return nil, fmt.Errorf("unknown batch operation: %v", op)
+	}
+	if mode == storage.BatchAtomic && !s.txn && s.ctx == nil {
+		return nil, errNoTx()
 	}
 
-	var posts []storage.Post
-	err = json.Unmarshal(data, &posts)
+	ctx := s.opCtx()
+	collection := s.db.Database(databaseName).Collection(collectionName)
+	results := storage.NewBatchResults(len(ids))
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"_id": 1}))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var found []struct {
+		ID int64 `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	exists := make(map[int64]bool, len(found))
+	for _, doc := range found {
+		exists[doc.ID] = true
 	}
 
-	var documents []interface{}
-	for _, post := range posts {
+	var models []mongo.WriteModel
+	var modelIndex []int // индекс элемента пакета для каждой модели
+	seen := make(map[int64]bool, len(ids))
+	for i, id := range ids {
+		switch {
+		case seen[id]:
+			results[i].Err = fmt.Sprintf("Id: %v duplicated in batch", id)
+		case op == storage.BatchCreate && exists[id]:
+			results[i].Err = fmt.Sprintf("INSERT Id: %v exist", id)
+		case op != storage.BatchCreate && !exists[id]:
+			results[i].Err = fmt.Sprintf("Id: %v not exist", id)
+		default:
+			models = append(models, model(i))
+			modelIndex = append(modelIndex, i)
+		}
+		seen[id] = true
+	}
+	if mode == storage.BatchAtomic && storage.BatchFailed(results) {
+		return results, storage.AbortBatch(results)
+	}
 
-		doc := bson.M{
-			"_id":          post.ID,
-			"author_id":    post.AuthorID,
-			"title":        post.Title,
-			"content":      post.Content,
-			"created_at":   post.CreatedAt,
-			"published_at": post.PublishedAt,
+	if len(models) > 0 {
+		if mode == storage.BatchAtomic {
+			err = s.db.UseSession(ctx, func(sc mongo.SessionContext) error {
+				_, err := sc.WithTransaction(sc, func(sc mongo.SessionContext) (interface{}, error) {
+					return collection.BulkWrite(sc, models, options.BulkWrite().SetOrdered(true))
+				})
+				return err
+			})
+			if err != nil {
+				results[modelIndex[0]].Err = err.Error()
+				return results, storage.AbortBatch(results)
+			}
+		} else {
+			_, err = collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+			var bulkErr mongo.BulkWriteException
+			if errors.As(err, &bulkErr) {
+				for _, we := range bulkErr.WriteErrors {
+					results[modelIndex[we.Index]].Err = we.Message
+				}
+			} else if err != nil {
+				return nil, err
+			}
 		}
+	}
 
-		documents = append(documents, doc)
+	for _, i := range modelIndex {
+		if results[i].Err == "" {
+			results[i].ID = ids[i]
+		}
 	}
 
-	collection := s.db.Database(databaseName).Collection(collectionPosts)
-	_, err = collection.DeleteMany(context.Background(), bson.M{})
-	if err != nil {
-		return err
+	return results, nil
+}
+
+func (s *Store) AuthorsBatch(op storage.BatchOp, mode storage.BatchMode, authors []storage.Author) ([]storage.BatchResult, error) {
+	authors = append([]storage.Author(nil), authors...)
+	ids := make([]int64, len(authors))
+	for i := range authors {
+		if op == storage.BatchCreate && authors[i].ID == 0 {
+			id, err := s.newRecordID(collectionAuthors)
+			if err != nil {
+				return nil, err
+			}
+			authors[i].ID = id
+		}
+		ids[i] = authors[i].ID
 	}
 
-	_, err = collection.InsertMany(context.Background(), documents)
-	if err != nil {
-		return err
+	return s.batch(collectionAuthors, op, mode, ids, func(i int) mongo.WriteModel {
+		id_doc := bson.M{"_id": authors[i].ID}
+		switch op {
+		case storage.BatchCreate:
+			return mongo.NewInsertOneModel().SetDocument(authors[i])
+		case storage.BatchUpdate:
+			return mongo.NewUpdateOneModel().SetFilter(id_doc).SetUpdate(bson.M{"$set": bson.M{"name": authors[i].Name}})
+		default:
+			return mongo.NewDeleteOneModel().SetFilter(id_doc)
+		}
+	})
+}
+
+func (s *Store) PostsBatch(op storage.BatchOp, mode storage.BatchMode, posts []storage.Post) ([]storage.BatchResult, error) {
+	posts = append([]storage.Post(nil), posts...)
+	ids := make([]int64, len(posts))
+	for i := range posts {
+		if op == storage.BatchCreate && posts[i].ID == 0 {
+			id, err := s.newRecordID(collectionPosts)
+			if err != nil {
+				return nil, err
+			}
+			posts[i].ID = id
+		}
+		ids[i] = posts[i].ID
 	}
 
-	return nil
+	return s.batch(collectionPosts, op, mode, ids, func(i int) mongo.WriteModel {
+		post := posts[i]
+		id_doc := bson.M{"_id": post.ID}
+		doc := bson.M{
+			"author_id":    post.AuthorID,
+			"title":        post.Title,
+			"content":      post.Content,
+			"created_at":   post.CreatedAt,
+			"published_at": post.PublishedAt,
+		}
+		switch op {
+		case storage.BatchCreate:
+			doc["_id"] = post.ID
+			return mongo.NewInsertOneModel().SetDocument(doc)
+		case storage.BatchUpdate:
+			return mongo.NewUpdateOneModel().SetFilter(id_doc).SetUpdate(bson.M{"$set": doc})
+		default:
+			return mongo.NewDeleteOneModel().SetFilter(id_doc)
+		}
+	})
 }
@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"GoNews/pkg/metrics"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Пулы соединений клиента MongoDB по адресам серверов. Драйвер не даёт
+// их состояние напрямую: оно собирается из событий PoolMonitor.
+type poolSet struct {
+	mu    sync.Mutex
+	pools map[string]*metrics.PoolStats
+}
+
+func newPoolSet() *poolSet {
+	return &poolSet{pools: map[string]*metrics.PoolStats{}}
+}
+
+// Монитор пулов для options.Client().SetPoolMonitor.
+func (ps *poolSet) monitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: ps.event}
+}
+
+func (ps *poolSet) event(e *event.PoolEvent) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.pools[e.Address]
+	if !ok {
+		p = &metrics.PoolStats{Pool: e.Address}
+		ps.pools[e.Address] = p
+	}
+	switch e.Type {
+	case event.PoolCreated:
+		if e.PoolOptions != nil {
+			p.Max = int64(e.PoolOptions.MaxPoolSize)
+		}
+	case event.ConnectionCreated:
+		// Новое соединение создаётся, когда свободного в пуле нет.
+		p.Open++
+		p.Idle++
+		p.Misses++
+	case event.ConnectionClosed:
+		p.Open--
+		p.Idle--
+	case event.GetSucceeded:
+		p.Idle--
+		p.InUse++
+	case event.ConnectionReturned:
+		p.Idle++
+		p.InUse--
+	case event.GetFailed:
+		p.Timeouts++
+	case event.PoolClosedEvent:
+		delete(ps.pools, e.Address)
+	}
+}
+
+// PoolStats - состояние пулов соединений клиента MongoDB (для /metrics).
+func (s *Store) PoolStats() []metrics.PoolStats {
+	s.pools.mu.Lock()
+	defer s.pools.mu.Unlock()
+	stats := make([]metrics.PoolStats, 0, len(s.pools.pools))
+	for _, p := range s.pools.pools {
+		stats = append(stats, *p)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Pool < stats[j].Pool })
+	return stats
+}
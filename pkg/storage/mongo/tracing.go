@@ -0,0 +1,57 @@
+package mongo
+
+import (
+	"GoNews/pkg/tracing"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Спаны команд MongoDB по событиям CommandMonitor: спан начинается
+// при отправке команды и завершается при ответе или ошибке.
+type commandTracer struct {
+	spans sync.Map // RequestID команды -> *tracing.Span
+}
+
+// Монитор команд для options.Client().SetMonitor.
+func (t *commandTracer) monitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: t.started,
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			t.finished(e.RequestID, e.Duration, "")
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			t.finished(e.RequestID, e.Duration, e.Failure)
+		},
+	}
+}
+
+func (t *commandTracer) started(ctx context.Context, e *event.CommandStartedEvent) {
+	if !tracing.Enabled() {
+		return
+	}
+	_, span := tracing.Start(ctx, "mongodb "+e.CommandName, tracing.KindClient)
+	span.SetAttr("db.system", "mongodb")
+	span.SetAttr("db.name", e.DatabaseName)
+	span.SetAttr("db.operation", e.CommandName)
+	if coll, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+		span.SetAttr("db.mongodb.collection", coll)
+	}
+	t.spans.Store(e.RequestID, span)
+}
+
+func (t *commandTracer) finished(requestID int64, d time.Duration, failure string) {
+	v, ok := t.spans.Load(requestID)
+	if !ok {
+		return
+	}
+	t.spans.Delete(requestID)
+	span := v.(*tracing.Span)
+	if failure != "" {
+		span.SetError(errors.New(failure))
+	}
+	span.FinishAt(span.Start.Add(d))
+}
@@ -0,0 +1,94 @@
+package mongo
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/storage"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Коллекция пользователей API; имя уникально (индекс создаётся при добавлении).
+const collectionUsers = "users"
+
+func (s *Store) Users() ([]auth.User, error) {
+	ctx := context.Background()
+	cur, err := s.db.Database(databaseName).Collection(collectionUsers).Find(ctx, bson.M{},
+		options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var data []auth.User
+	err = cur.All(ctx, &data)
+	return data, err
+}
+
+func (s *Store) UserByID(id int64) (auth.User, error) {
+	var u auth.User
+	err := s.db.Database(databaseName).Collection(collectionUsers).FindOne(context.Background(), bson.M{"_id": id}).Decode(&u)
+	if err == mongo.ErrNoDocuments {
+		return u, fmt.Errorf("user %v %w", id, storage.ErrNotFound)
+	}
+	return u, err
+}
+
+func (s *Store) UserByName(name string) (auth.User, error) {
+	var u auth.User
+	err := s.db.Database(databaseName).Collection(collectionUsers).FindOne(context.Background(), bson.M{"name": name}).Decode(&u)
+	if err == mongo.ErrNoDocuments {
+		return u, fmt.Errorf("user %q %w", name, storage.ErrNotFound)
+	}
+	return u, err
+}
+
+func (s *Store) AddUser(u auth.User) (int64, error) {
+	ctx := context.Background()
+	coll := s.db.Database(databaseName).Collection(collectionUsers)
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"name": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return 0, err
+	}
+	id, err := s.nextID(ctx, collectionUsers)
+	if err != nil {
+		return 0, err
+	}
+	u.ID = id
+	_, err = coll.InsertOne(ctx, u)
+	return id, userError(u, err)
+}
+
+func (s *Store) UpdateUser(u auth.User) error {
+	result, err := s.db.Database(databaseName).Collection(collectionUsers).ReplaceOne(context.Background(), bson.M{"_id": u.ID}, u)
+	if err != nil {
+		return userError(u, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user %v %w", u.ID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) DeleteUser(id int64) error {
+	result, err := s.db.Database(databaseName).Collection(collectionUsers).DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("user %v %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// Нарушение уникальности имени - auth.ErrUserExists.
+func userError(u auth.User, err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%q: %w", u.Name, auth.ErrUserExists)
+	}
+	return err
+}
@@ -0,0 +1,168 @@
+package mongo
+
+import (
+	"GoNews/pkg/storage"
+	"GoNews/pkg/webhooks"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Коллекции подписок и доставок webhooks; ID выдаются счётчиками в коллекции counters.
+const (
+	collectionWebhooks   = "webhooks"
+	collectionDeliveries = "webhook_deliveries"
+	collectionCounters   = "counters"
+)
+
+// Следующее значение счётчика name.
+func (s *Store) nextID(ctx context.Context, name string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := s.db.Database(databaseName).Collection(collectionCounters).FindOneAndUpdate(ctx,
+		bson.M{"_id": name},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	return counter.Seq, err
+}
+
+func (s *Store) Webhooks() ([]webhooks.Subscription, error) {
+	ctx := context.Background()
+	cur, err := s.db.Database(databaseName).Collection(collectionWebhooks).Find(ctx, bson.M{},
+		options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var data []webhooks.Subscription
+	err = cur.All(ctx, &data)
+	return data, err
+}
+
+func (s *Store) WebhookByID(id int64) (webhooks.Subscription, error) {
+	var sub webhooks.Subscription
+	err := s.db.Database(databaseName).Collection(collectionWebhooks).FindOne(context.Background(), bson.M{"_id": id}).Decode(&sub)
+	if err == mongo.ErrNoDocuments {
+		return sub, fmt.Errorf("webhook %v %w", id, storage.ErrNotFound)
+	}
+	return sub, err
+}
+
+func (s *Store) AddWebhook(sub webhooks.Subscription) (int64, error) {
+	ctx := context.Background()
+	id, err := s.nextID(ctx, collectionWebhooks)
+	if err != nil {
+		return 0, err
+	}
+	sub.ID = id
+	_, err = s.db.Database(databaseName).Collection(collectionWebhooks).InsertOne(ctx, sub)
+	return id, err
+}
+
+func (s *Store) UpdateWebhook(sub webhooks.Subscription) error {
+	result, err := s.db.Database(databaseName).Collection(collectionWebhooks).ReplaceOne(context.Background(), bson.M{"_id": sub.ID}, sub)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("webhook %v %w", sub.ID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) DeleteWebhook(id int64) error {
+	result, err := s.db.Database(databaseName).Collection(collectionWebhooks).DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("webhook %v %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) AddDelivery(d webhooks.Delivery) (int64, error) {
+	ctx := context.Background()
+	id, err := s.nextID(ctx, collectionDeliveries)
+	if err != nil {
+		return 0, err
+	}
+	d.ID = id
+	_, err = s.db.Database(databaseName).Collection(collectionDeliveries).InsertOne(ctx, d)
+	return id, err
+}
+
+func (s *Store) UpdateDelivery(d webhooks.Delivery) error {
+	result, err := s.db.Database(databaseName).Collection(collectionDeliveries).ReplaceOne(context.Background(), bson.M{"_id": d.ID}, d)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("delivery %v %w", d.ID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) DeliveryByID(id int64) (webhooks.Delivery, error) {
+	var d webhooks.Delivery
+	err := s.db.Database(databaseName).Collection(collectionDeliveries).FindOne(context.Background(), bson.M{"_id": id}).Decode(&d)
+	if err == mongo.ErrNoDocuments {
+		return d, fmt.Errorf("delivery %v %w", id, storage.ErrNotFound)
+	}
+	return d, err
+}
+
+func (s *Store) DueDeliveries(now int64, limit int) ([]webhooks.Delivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	return s.findDeliveries(bson.M{
+		"status":          webhooks.StatusPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}, opts)
+}
+
+func (s *Store) Deliveries(f webhooks.DeliveryFilter) ([]webhooks.Delivery, error) {
+	filter := bson.M{}
+	if f.SubscriptionID != 0 {
+		filter["subscription_id"] = f.SubscriptionID
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if f.BeforeID != 0 {
+		filter["_id"] = bson.M{"$lt": f.BeforeID}
+	}
+	opts := options.Find().SetSort(bson.M{"_id": -1})
+	if f.Limit > 0 {
+		opts.SetLimit(int64(f.Limit))
+	}
+	return s.findDeliveries(filter, opts)
+}
+
+func (s *Store) DeleteDeliveries(before int64) (int64, error) {
+	result, err := s.db.Database(databaseName).Collection(collectionDeliveries).DeleteMany(context.Background(), bson.M{
+		"status":     bson.M{"$ne": webhooks.StatusPending},
+		"updated_at": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (s *Store) findDeliveries(filter bson.M, opts *options.FindOptions) ([]webhooks.Delivery, error) {
+	ctx := context.Background()
+	cur, err := s.db.Database(databaseName).Collection(collectionDeliveries).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var data []webhooks.Delivery
+	err = cur.All(ctx, &data)
+	return data, err
+}
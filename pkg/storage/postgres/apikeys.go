@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/storage"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Ключи API хранятся в таблице api_keys основной БД (см. schema.sql).
+
+func (s *Store) APIKeys() ([]auth.Key, error) {
+	rows, err := s.pool.Query(context.Background(), `SELECT id, name, prefix, hash, user_id, created_at FROM api_keys ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []auth.Key
+	for rows.Next() {
+		var k auth.Key
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, &k.UserID, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		data = append(data, k)
+	}
+	return data, rows.Err()
+}
+
+func (s *Store) APIKeyByHash(hash string) (auth.Key, error) {
+	var k auth.Key
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT id, name, prefix, hash, user_id, created_at FROM api_keys WHERE hash = $1;`, hash).
+		Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, &k.UserID, &k.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return k, fmt.Errorf("API key %w", storage.ErrNotFound)
+	}
+	return k, err
+}
+
+func (s *Store) AddAPIKey(k auth.Key) (int64, error) {
+	var id int64
+	err := s.pool.QueryRow(context.Background(), `
+		INSERT INTO api_keys (name, prefix, hash, user_id, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id;`,
+		k.Name, k.Prefix, k.Hash, k.UserID, k.CreatedAt).Scan(&id)
+	return id, err
+}
+
+func (s *Store) DeleteAPIKey(id int64) error {
+	tag, err := s.pool.Exec(context.Background(), `DELETE FROM api_keys WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("API key %v %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
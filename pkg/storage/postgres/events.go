@@ -0,0 +1,349 @@
+package postgres
+
+import (
+	"GoNews/pkg/events"
+	"GoNews/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Канал уведомлений о новых событиях (см. events_func_capture в schema.sql).
+const eventsChannel = "gonews_events"
+
+// Сколько событий читается одним запросом.
+const eventsPage = 100
+
+// Интервал повторного чтения, пока события завершённых транзакций ждут
+// завершения более старой транзакции (её завершение может не вызвать NOTIFY).
+const eventsPoll = time.Second
+
+// Размер очереди подписчика. Подписчик, не успевающий забирать события,
+// отключается (канал закрывается) и может продолжить с последнего ID.
+const subscriberBuffer = 256
+
+// Позиция в потоке событий. ID строк назначаются при вставке, а видны они
+// после COMMIT, поэтому события упорядочены по транзакции, записавшей их
+// (txid), и по ID внутри неё. Читаются только события транзакций старше
+// самой старой незавершённой: позже перед позицией ничего не появится.
+type eventCursor struct {
+	tx int64
+	id int64
+}
+
+func (c eventCursor) after(o eventCursor) bool {
+	return c.tx > o.tx || c.tx == o.tx && c.id > o.id
+}
+
+// Граница чтения без верхнего предела.
+var endCursor = eventCursor{tx: math.MaxInt64, id: math.MaxInt64}
+
+// Событие с его позицией.
+type feedEvent struct {
+	c eventCursor
+	e events.Event
+}
+
+// Общие методы соединения и пула pgx для чтения событий.
+type eventQuerier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Subscribe возвращает события изменений после afterID (ID строки таблицы events).
+// Все подписки процесса получают новые события от одного соединения с LISTEN
+// (eventFeed); пропущенные события до позиции общего потока читаются через пул.
+func (s *Store) Subscribe(ctx context.Context, afterID string) (<-chan events.Event, error) {
+	var after int64
+	if afterID != "" {
+		var err error
+		if after, err = strconv.ParseInt(afterID, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid event id %q", afterID)
+		}
+	}
+
+	feed, live, pos, err := s.events.join(ctx)
+	if err != nil {
+		return nil, err
+	}
+	last, minID := pos, int64(0)
+	if afterID != "" {
+		if last, minID, err = resumeCursor(ctx, s.pool, after); err != nil {
+			feed.leave(live)
+			return nil, err
+		}
+	}
+
+	ch := make(chan events.Event)
+	go func() {
+		defer close(ch)
+		defer feed.leave(live)
+
+		// События до позиции общего потока на момент подписки.
+		for pos.after(last) {
+			batch, _, err := readEvents(ctx, s.pool, last, pos, minID)
+			if err != nil {
+				// Клиент продолжит с последнего ID.
+				return
+			}
+			if len(batch) == 0 {
+				break
+			}
+			for _, fe := range batch {
+				select {
+				case ch <- fe.e:
+				case <-ctx.Done():
+					return
+				}
+				last = fe.c
+			}
+		}
+
+		for {
+			select {
+			case fe, ok := <-live:
+				if !ok {
+					// Общий поток остановлен или подписчик отстал.
+					return
+				}
+				if !fe.c.after(last) || fe.c.id <= minID {
+					continue
+				}
+				select {
+				case ch <- fe.e:
+				case <-ctx.Done():
+					return
+				}
+				last = fe.c
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Позиция продолжения после события after. Если оно удалено, события
+// читаются с первого сохранившегося после него (minID - нижняя граница ID).
+func resumeCursor(ctx context.Context, q eventQuerier, after int64) (c eventCursor, minID int64, err error) {
+	err = q.QueryRow(ctx, `SELECT txid, id FROM events WHERE id = $1;`, after).Scan(&c.tx, &c.id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return eventCursor{}, after, nil
+	}
+	return c, 0, err
+}
+
+// Чтение событий после from до to включительно с ID больше minID, не больше
+// eventsPage. pending - есть события, ждущие завершения более старых транзакций.
+func readEvents(ctx context.Context, q eventQuerier, from, to eventCursor, minID int64) (batch []feedEvent, pending bool, err error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, txid, txid_snapshot_xmin(txid_current_snapshot()),
+			entity, op, entity_id, before, after, created_at
+		FROM events
+		WHERE (txid, id) > ($1, $2) AND (txid, id) <= ($3, $4) AND id > $5
+		ORDER BY txid, id
+		LIMIT $6;`, from.tx, from.id, to.tx, to.id, minID, eventsPage)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fe feedEvent
+		var xmin int64
+		var op string
+		if err := rows.Scan(&fe.c.id, &fe.c.tx, &xmin, &fe.e.Entity, &op, &fe.e.EntityID, &fe.e.Before, &fe.e.After, &fe.e.Time); err != nil {
+			return nil, false, err
+		}
+		if fe.c.tx >= xmin {
+			// Транзакция tx или более старая ещё не завершена.
+			return batch, true, nil
+		}
+		fe.e.ID = strconv.FormatInt(fe.c.id, 10)
+		fe.e.Op = events.Op(op)
+		batch = append(batch, fe)
+	}
+	return batch, false, rows.Err()
+}
+
+// Общий поток событий процесса: одно соединение с LISTEN, события которого
+// рассылаются подписчикам в памяти. Запускается первой подпиской,
+// останавливается при Close или ошибке соединения (подписки закрываются
+// и продолжаются клиентами с последнего ID).
+type eventHub struct {
+	config *pgx.ConnConfig
+
+	mu     sync.Mutex
+	feed   *eventFeed // nil - не запущен
+	closed bool
+}
+
+type eventFeed struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu   sync.Mutex
+	pos  eventCursor // последнее разосланное событие
+	subs map[chan feedEvent]struct{}
+}
+
+func newEventHub(config *pgx.ConnConfig) *eventHub {
+	return &eventHub{config: config}
+}
+
+// Подключение к общему потоку (с запуском при необходимости). Подписчик
+// получает в live события после pos.
+func (h *eventHub) join(ctx context.Context) (feed *eventFeed, live chan feedEvent, pos eventCursor, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, pos, errors.New("event feed closed")
+	}
+	if h.feed == nil {
+		if h.feed, err = h.start(ctx); err != nil {
+			return nil, nil, pos, err
+		}
+	}
+
+	feed = h.feed
+	live = make(chan feedEvent, subscriberBuffer)
+	feed.mu.Lock()
+	feed.subs[live] = struct{}{}
+	pos = feed.pos
+	feed.mu.Unlock()
+	return feed, live, pos, nil
+}
+
+// Запуск общего потока. Вызывается под h.mu.
+func (h *eventHub) start(ctx context.Context) (*eventFeed, error) {
+	conn, err := pgx.ConnectConfig(ctx, h.config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+eventsChannel); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+	// Позиция определяется после LISTEN, чтобы не пропустить события:
+	// перед транзакциями, ещё не видимыми целиком.
+	var pos eventCursor
+	if err := conn.QueryRow(ctx, `SELECT txid_snapshot_xmin(txid_current_snapshot());`).Scan(&pos.tx); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+
+	fctx, cancel := context.WithCancel(context.Background())
+	f := &eventFeed{cancel: cancel, done: make(chan struct{}), pos: pos, subs: make(map[chan feedEvent]struct{})}
+	go func() {
+		defer close(f.done)
+		defer conn.Close(context.Background())
+		err := f.run(fctx, conn)
+
+		h.mu.Lock()
+		if h.feed == f {
+			h.feed = nil
+		}
+		h.mu.Unlock()
+		f.stop()
+		if fctx.Err() == nil {
+			logger.SetLog(time.Now(), "PostgreSQL events", fmt.Sprintf("event feed stopped: %v", err))
+		}
+	}()
+	return f, nil
+}
+
+// Чтение новых событий и рассылка подписчикам до ошибки или отмены ctx.
+func (f *eventFeed) run(ctx context.Context, conn *pgx.Conn) error {
+	f.mu.Lock()
+	last := f.pos
+	f.mu.Unlock()
+
+	for {
+		batch, pending, err := readEvents(ctx, conn, last, endCursor, 0)
+		if err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			last = batch[len(batch)-1].c
+			f.publish(batch)
+		}
+		if len(batch) == eventsPage && !pending {
+			continue
+		}
+		if err := waitEvents(ctx, conn, pending); err != nil {
+			return err
+		}
+	}
+}
+
+// Рассылка событий. Подписчик, чья очередь заполнена, отключается.
+func (f *eventFeed) publish(batch []feedEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, fe := range batch {
+		for ch := range f.subs {
+			select {
+			case ch <- fe:
+			default:
+				delete(f.subs, ch)
+				close(ch)
+			}
+		}
+	}
+	f.pos = batch[len(batch)-1].c
+}
+
+// Отключение подписчика.
+func (f *eventFeed) leave(ch chan feedEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subs[ch]; ok {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+// Отключение всех подписчиков.
+func (f *eventFeed) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+// Остановка общего потока; новые подписки отклоняются.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	h.closed = true
+	f := h.feed
+	h.mu.Unlock()
+	if f != nil {
+		f.cancel()
+		<-f.done
+	}
+}
+
+// Ожидание уведомления; при pending - не дольше eventsPoll.
+func waitEvents(ctx context.Context, conn *pgx.Conn, pending bool) error {
+	if !pending {
+		_, err := conn.WaitForNotification(ctx)
+		return err
+	}
+	wctx, cancel := context.WithTimeout(ctx, eventsPoll)
+	defer cancel()
+	_, err := conn.WaitForNotification(wctx)
+	if err != nil && ctx.Err() == nil && wctx.Err() != nil {
+		// Истёк eventsPoll: соединение остаётся рабочим.
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"GoNews/pkg/metrics"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PoolStats - состояние пулов соединений основной БД и реплик (для /metrics).
+func (s *Store) PoolStats() []metrics.PoolStats {
+	stats := []metrics.PoolStats{poolStats("primary", s.pool)}
+	if s.replicas != nil {
+		for _, r := range s.replicas.replicas {
+			stats = append(stats, poolStats(r.name, r.pool))
+		}
+	}
+	return stats
+}
+
+func poolStats(name string, p *pgxpool.Pool) metrics.PoolStats {
+	st := p.Stat()
+	return metrics.PoolStats{
+		Pool:     name,
+		Open:     int64(st.TotalConns()),
+		Idle:     int64(st.IdleConns()),
+		InUse:    int64(st.AcquiredConns()),
+		Max:      int64(st.MaxConns()),
+		Misses:   st.EmptyAcquireCount(),
+		Timeouts: st.CanceledAcquireCount(),
+	}
+}
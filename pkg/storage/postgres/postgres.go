@@ -4,15 +4,28 @@ import (
 	"GoNews/pkg/storage"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 // Хранилище данных.
 type Store struct {
-	db *pgxpool.Pool
+	pool     *pgxpool.Pool
+	db       querier         // пул соединений или транзакция внутри WithTx
+	replicas *replicaSet     // реплики для чтения, nil - нет или внутри WithTx
+	events   *eventHub       // общий поток событий для Subscribe
+	ctx      context.Context // контекст запросов (Bind, WithTx), nil - context.Background()
+}
+
+// Общие методы пула соединений и транзакции pgx.
+type querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
 }
 
 func (s *Store) GetInform() string {
@@ -21,12 +34,14 @@ func (s *Store) GetInform() string {
 
 // Конструктор объекта хранилища.
 func New(constr string) (*Store, error) {
-	db, err := pgxpool.Connect(context.Background(), constr)
+	db, err := connect(constr)
 	if err != nil {
 		return nil, err
 	}
 	s := Store{
-		db: db,
+		pool:   db,
+		db:     db,
+		events: newEventHub(db.Config().ConnConfig),
 	}
 
 	fmt.Println("Loaded bd: ", s.GetInform())
@@ -34,8 +49,97 @@ func New(constr string) (*Store, error) {
 	return &s, nil
 }
 
-func (s *Store) Close() {
-	s.db.Close()
+// Ping проверяет соединение с основной БД.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// Version - версия сервера PostgreSQL.
+func (s *Store) Version(ctx context.Context) (string, error) {
+	var v string
+	err := s.pool.QueryRow(ctx, `SHOW server_version;`).Scan(&v)
+	return v, err
+}
+
+// Close закрывает пулы соединений основной БД и реплик, дожидаясь
+// возврата занятых соединений.
+func (s *Store) Close() error {
+	s.events.close()
+	if s.replicas != nil {
+		s.replicas.close()
+	}
+	s.pool.Close()
+	return nil
+}
+
+// Bind - копия хранилища, выполняющая запросы с контекстом ctx.
+func (s *Store) Bind(ctx context.Context) storage.Interface {
+	c := *s
+	c.ctx = ctx
+	return &c
+}
+
+// Контекст запросов к БД.
+func (s *Store) opCtx() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return context.Background()
+}
+
+// Соединение для изменения данных: всегда основная БД.
+// Отмечает изменение для маршрутизации последующих чтений того же клиента.
+func (s *Store) writer() querier {
+	if s.replicas != nil {
+		s.replicas.wrote(storage.Client(s.opCtx()))
+	}
+	return s.db
+}
+
+// Хранилище без реплик: чтения, которые являются частью изменения
+// (проверка существования), выполняются в основной БД.
+func (s *Store) primary() *Store {
+	return &Store{pool: s.pool, db: s.db, events: s.events, ctx: s.ctx}
+}
+
+// Выполнение чтения на реплике или, если доступной реплики нет, в основной БД.
+// При ошибке на реплике чтение повторяется в основной БД. Отмена запроса
+// клиентом ошибкой реплики не считается. Чтения с storage.WithPrimary
+// выполняются в основной БД.
+func (s *Store) read(fn func(q querier) error) error {
+	if s.replicas != nil && !storage.Primary(s.opCtx()) {
+		if r := s.replicas.pick(storage.Client(s.opCtx())); r != nil {
+			err := fn(r.pool)
+			if err == nil {
+				return nil
+			}
+			if errors.Is(err, context.Canceled) || s.opCtx().Err() != nil {
+				return err
+			}
+			if s.replicas.setHealthy(r, false) {
+				s.replicas.log(r, fmt.Sprintf("replica read failed, using primary: %v", err))
+			}
+		}
+	}
+	return fn(s.db)
+}
+
+// WithTx выполняет fn в транзакции PostgreSQL.
+// Внутри fn операции выполняются в той же транзакции (вложенный вызов - точка сохранения).
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Tx) error) error {
+	tx, err := s.writer().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&Store{pool: s.pool, db: tx, events: s.events, ctx: ctx}); err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	s.writer()
+	return err
 }
 
 func structToMap(obj interface{}) (newMap map[string]interface{}, err error) {
@@ -49,9 +153,53 @@ func structToMap(obj interface{}) (newMap map[string]interface{}, err error) {
 	return
 }
 
+// Snapshot читает авторов и публикации в одной транзакции REPEATABLE READ
+// основной БД: обе выборки видят один снимок данных.
+func (s *Store) Snapshot(ctx context.Context) (authors []storage.Author, posts []storage.Post, err error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	if authors, err = queryAuthors(ctx, tx, map[string]interface{}{}); err != nil {
+		return nil, nil, err
+	}
+	if posts, err = queryPosts(ctx, tx, map[string]interface{}{}); err != nil {
+		return nil, nil, err
+	}
+	return authors, posts, tx.Commit(ctx)
+}
+
 // Author - автор.
 func (s *Store) Authors() ([]storage.Author, error) {
-	rows, err := s.db.Query(context.Background(), `SELECT * FROM authors_func_view('{}');`)
+	return s.queryAuthors(map[string]interface{}{})
+}
+
+func (s *Store) AuthorByID(id int64) (storage.Author, error) {
+	authors, err := s.queryAuthors(map[string]interface{}{"id": id})
+	if err != nil {
+		return storage.Author{}, err
+	}
+	if len(authors) == 0 {
+		return storage.Author{}, fmt.Errorf("Id: %v %w", id, storage.ErrNotFound)
+	}
+	return authors[0], nil
+}
+
+// Выборка авторов через authors_func_view по фильтру (на реплике, если есть).
+func (s *Store) queryAuthors(filter map[string]interface{}) ([]storage.Author, error) {
+	var authors []storage.Author
+	err := s.read(func(q querier) error {
+		var err error
+		authors, err = queryAuthors(s.opCtx(), q, filter)
+		return err
+	})
+	return authors, err
+}
+
+func queryAuthors(ctx context.Context, q querier, filter map[string]interface{}) ([]storage.Author, error) {
+	rows, err := q.Query(ctx, `SELECT * FROM authors_func_view($1);`, filter)
 
 	if err != nil {
 		return nil, err
@@ -81,7 +229,7 @@ func (s *Store) AddAuthor(author storage.Author) (int64, error) {
 	}
 
 	var jsonResponse storage.SqlResponse
-	err = s.db.QueryRow(context.Background(), `SELECT * FROM authors_func_insert($1);`, jsonRequest).Scan(&jsonResponse)
+	err = s.writer().QueryRow(s.opCtx(), `SELECT * FROM authors_func_insert($1);`, jsonRequest).Scan(&jsonResponse)
 	if err != nil {
 		return 0, err
 	}
@@ -98,7 +246,7 @@ func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
 	}
 
 	var jsonResponse storage.SqlResponse
-	err = s.db.QueryRow(context.Background(), `SELECT * FROM authors_func_update($1);`, jsonRequest).Scan(&jsonResponse)
+	err = s.writer().QueryRow(s.opCtx(), `SELECT * FROM authors_func_update($1);`, jsonRequest).Scan(&jsonResponse)
 	if err != nil {
 		return 0, err
 	}
@@ -108,6 +256,16 @@ func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
 	return jsonResponse.ID, nil
 }
 
+func (s *Store) PatchAuthor(id int64, fields map[string]interface{}) (int64, error) {
+	if _, err := s.primary().AuthorByID(id); err != nil {
+		return 0, err
+	}
+	if len(fields) == 0 {
+		return id, nil
+	}
+	return s.patch(`SELECT * FROM authors_func_update($1);`, id, fields)
+}
+
 func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
 	jsonRequest, err := structToMap(author)
 	if err != nil {
@@ -115,7 +273,7 @@ func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
 	}
 
 	var jsonResponse storage.SqlResponse
-	err = s.db.QueryRow(context.Background(), `SELECT * FROM authors_func_delete($1);`, jsonRequest).Scan(&jsonResponse)
+	err = s.writer().QueryRow(s.opCtx(), `SELECT * FROM authors_func_delete($1);`, jsonRequest).Scan(&jsonResponse)
 	if err != nil {
 		return 0, err
 	}
@@ -125,39 +283,36 @@ func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
 	return jsonResponse.ID, nil
 }
 
-func (s *Store) InsertInitDataFromFileAuthors(filename string) error {
-
-	var jsonData []map[string]interface{}
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
-	}
+// Post - публикация.
+func (s *Store) Posts() ([]storage.Post, error) {
+	return s.queryPosts(map[string]interface{}{})
+}
 
-	err = json.Unmarshal(data, &jsonData)
+func (s *Store) PostByID(id int64) (storage.Post, error) {
+	posts, err := s.queryPosts(map[string]interface{}{"id": id})
 	if err != nil {
-		return err
+		return storage.Post{}, err
 	}
-
-	for _, item := range jsonData {
-
-		var jsonResponse storage.SqlResponse
-		err = s.db.QueryRow(context.Background(), `SELECT * FROM authors_func_insert($1);`, item).Scan(&jsonResponse)
-		if err != nil {
-			return err
-		}
-		if jsonResponse.Err != "" {
-			return fmt.Errorf(jsonResponse.Err)
-		}
-
+	if len(posts) == 0 {
+		return storage.Post{}, fmt.Errorf("Id: %v %w", id, storage.ErrNotFound)
 	}
+	return posts[0], nil
+}
 
-	return nil
+// Выборка публикаций через posts_func_view по фильтру (на реплике, если есть).
+func (s *Store) queryPosts(filter map[string]interface{}) ([]storage.Post, error) {
+	var posts []storage.Post
+	err := s.read(func(q querier) error {
+		var err error
+		posts, err = queryPosts(s.opCtx(), q, filter)
+		return err
+	})
+	return posts, err
 }
 
-// Post - публикация.
-func (s *Store) Posts() ([]storage.Post, error) {
+func queryPosts(ctx context.Context, q querier, filter map[string]interface{}) ([]storage.Post, error) {
 
-	rows, err := s.db.Query(context.Background(), `SELECT * FROM posts_func_view('{}');`)
+	rows, err := q.Query(ctx, `SELECT * FROM posts_func_view($1);`, filter)
 
 	if err != nil {
 		return nil, err
@@ -167,6 +322,9 @@ func (s *Store) Posts() ([]storage.Post, error) {
 
 	for rows.Next() {
 		var t storage.Post
+		// Текстовые поля времени формируются на уровне API (storage.TimeFormat),
+		// значения из posts_func_view не используются.
+		var createdAtTxt, publishedAtTxt string
 		err = rows.Scan(
 			&t.ID,
 			&t.AuthorID,
@@ -174,9 +332,9 @@ func (s *Store) Posts() ([]storage.Post, error) {
 			&t.Title,
 			&t.Content,
 			&t.CreatedAt,
-			&t.CreatedAtTxt,
+			&createdAtTxt,
 			&t.PublishedAt,
-			&t.PublishedAtTxt,
+			&publishedAtTxt,
 		)
 		if err != nil {
 			return nil, err
@@ -195,7 +353,7 @@ func (s *Store) AddPost(post storage.Post) (int64, error) {
 	}
 
 	var jsonResponse storage.SqlResponse
-	err = s.db.QueryRow(context.Background(), `SELECT * FROM posts_func_insert($1);`, jsonRequest).Scan(&jsonResponse)
+	err = s.writer().QueryRow(s.opCtx(), `SELECT * FROM posts_func_insert($1);`, jsonRequest).Scan(&jsonResponse)
 	if err != nil {
 		return 0, err
 	}
@@ -213,7 +371,35 @@ func (s *Store) UpdatePost(post storage.Post) (int64, error) {
 	}
 
 	var jsonResponse storage.SqlResponse
-	err = s.db.QueryRow(context.Background(), `SELECT * FROM posts_func_update($1);`, jsonRequest).Scan(&jsonResponse)
+	err = s.writer().QueryRow(s.opCtx(), `SELECT * FROM posts_func_update($1);`, jsonRequest).Scan(&jsonResponse)
+	if err != nil {
+		return 0, err
+	}
+	if jsonResponse.Err != "" {
+		return 0, fmt.Errorf(jsonResponse.Err)
+	}
+	return jsonResponse.ID, nil
+}
+
+func (s *Store) PatchPost(id int64, fields map[string]interface{}) (int64, error) {
+	if _, err := s.primary().PostByID(id); err != nil {
+		return 0, err
+	}
+	if len(fields) == 0 {
+		return id, nil
+	}
+	return s.patch(`SELECT * FROM posts_func_update($1);`, id, fields)
+}
+
+// Функции *_func_update изменяют только поля, присутствующие в json_data.
+func (s *Store) patch(sql string, id int64, fields map[string]interface{}) (int64, error) {
+	jsonRequest := map[string]interface{}{"id": id}
+	for k, v := range fields {
+		jsonRequest[k] = v
+	}
+
+	var jsonResponse storage.SqlResponse
+	err := s.writer().QueryRow(s.opCtx(), sql, jsonRequest).Scan(&jsonResponse)
 	if err != nil {
 		return 0, err
 	}
@@ -231,7 +417,7 @@ func (s *Store) DeletePost(post storage.Post) (int64, error) {
 	}
 
 	var jsonResponse storage.SqlResponse
-	err = s.db.QueryRow(context.Background(), `SELECT * FROM posts_func_delete($1);`, jsonRequest).Scan(&jsonResponse)
+	err = s.writer().QueryRow(s.opCtx(), `SELECT * FROM posts_func_delete($1);`, jsonRequest).Scan(&jsonResponse)
 	if err != nil {
 		return 0, err
 	}
@@ -241,29 +427,84 @@ func (s *Store) DeletePost(post storage.Post) (int64, error) {
 	return jsonResponse.ID, nil
 }
 
-func (s *Store) InsertInitDataFromFilePosts(filename string) error {
+// Функции БД для операций пакетной обработки.
+var batchFuncs = map[storage.BatchOp]string{
+	storage.BatchCreate: "insert",
+	storage.BatchUpdate: "update",
+	storage.BatchDelete: "delete",
+}
 
-	var jsonData []map[string]interface{}
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
+// Пакетный вызов функции <table>_func_<op> для каждого элемента одним pgx.Batch.
+// Функции перехватывают исключения и возвращают ошибку в ответе, поэтому ошибка
+// элемента не прерывает пакет. В режиме BatchAtomic пакет выполняется в транзакции,
+// которая откатывается при ошибке любого элемента.
+func (s *Store) batch(table string, op storage.BatchOp, mode storage.BatchMode, items []interface{}) ([]storage.BatchResult, error) {
+	funcName, ok := batchFuncs[op]
+	if !ok {
+		return nil, fmt.Errorf("unknown batch operation: %v", op)
 	}
+	sql := fmt.Sprintf(`SELECT * FROM %s_func_%s($1);`, table, funcName)
 
-	err = json.Unmarshal(data, &jsonData)
-	if err != nil {
-		return err
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		jsonRequest, err := structToMap(item)
+		if err != nil {
+			return nil, err
+		}
+		batch.Queue(sql, jsonRequest)
 	}
 
-	for _, item := range jsonData {
-		var jsonResponse storage.SqlResponse
-		err = s.db.QueryRow(context.Background(), `SELECT * FROM posts_func_insert($1);`, item).Scan(&jsonResponse)
-		if err != nil {
-			return err
+	ctx := s.opCtx()
+	results := storage.NewBatchResults(len(items))
+
+	send := func(br pgx.BatchResults) error {
+		defer br.Close()
+		for i := range items {
+			var jsonResponse storage.SqlResponse
+			if err := br.QueryRow().Scan(&jsonResponse); err != nil {
+				return err
+			}
+			results[i].ID = jsonResponse.ID
+			results[i].Err = jsonResponse.Err
 		}
-		if jsonResponse.Err != "" {
-			return fmt.Errorf(jsonResponse.Err)
+		return br.Close()
+	}
+
+	if mode != storage.BatchAtomic {
+		if err := send(s.writer().SendBatch(ctx, batch)); err != nil {
+			return nil, err
 		}
+		return results, nil
 	}
 
-	return nil
+	tx, err := s.writer().Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := send(tx.SendBatch(ctx, batch)); err != nil {
+		return nil, err
+	}
+	if storage.BatchFailed(results) {
+		return results, storage.AbortBatch(results)
+	}
+
+	return results, tx.Commit(ctx)
+}
+
+func (s *Store) AuthorsBatch(op storage.BatchOp, mode storage.BatchMode, authors []storage.Author) ([]storage.BatchResult, error) {
+	items := make([]interface{}, len(authors))
+	for i := range authors {
+		items[i] = authors[i]
+	}
+	return s.batch("authors", op, mode, items)
+}
+
+func (s *Store) PostsBatch(op storage.BatchOp, mode storage.BatchMode, posts []storage.Post) ([]storage.BatchResult, error) {
+	items := make([]interface{}, len(posts))
+	for i := range posts {
+		items[i] = posts[i]
+	}
+	return s.batch("posts", op, mode, items)
 }
@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"GoNews/pkg/logger"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ReplicaOptions - параметры маршрутизации чтений на реплики.
+type ReplicaOptions struct {
+	MaxLag         time.Duration // реплика с большим отставанием не используется
+	ReadYourWrites time.Duration // столько времени после изменения чтения клиента идут в основную БД
+	CheckInterval  time.Duration // период проверки состояния реплик
+}
+
+// DefaultReplicaOptions - параметры по умолчанию.
+func DefaultReplicaOptions() ReplicaOptions {
+	return ReplicaOptions{
+		MaxLag:         5 * time.Second,
+		ReadYourWrites: 5 * time.Second,
+		CheckInterval:  time.Second,
+	}
+}
+
+// Отставание реплики: 0, если все полученные изменения применены
+// (иначе время последней транзакции растёт при отсутствии записи в основную БД).
+const lagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8;`
+
+// Реплика и её последнее известное состояние.
+type replica struct {
+	name    string // хост:порт для журнала
+	pool    *pgxpool.Pool
+	healthy int32 // 1 - доступна и отставание не больше MaxLag
+}
+
+// Набор реплик основной БД.
+type replicaSet struct {
+	opts     ReplicaOptions
+	replicas []*replica
+	next     uint32 // счётчик для циклического выбора
+	stop     chan struct{}
+
+	mu     sync.Mutex
+	writes map[string]time.Time // время последнего изменения по клиентам (storage.Client)
+}
+
+// NewWithReplicas - конструктор хранилища с репликами для чтения.
+// Изменения и транзакции выполняются в основной БД, как и чтения клиента
+// (storage.WithClient) в течение opts.ReadYourWrites после его изменения;
+// остальные чтения распределяются по доступным репликам по кругу. Если
+// доступных реплик нет, чтение идёт в основную БД.
+func NewWithReplicas(constr string, replicaConstrs []string, opts ReplicaOptions) (*Store, error) {
+	s, err := New(constr)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &replicaSet{opts: opts, stop: make(chan struct{}), writes: make(map[string]time.Time)}
+	for _, rc := range replicaConstrs {
+		cfg, err := pgxpool.ParseConfig(rc)
+		if err != nil {
+			rs.close()
+			s.Close()
+			return nil, fmt.Errorf("replica: %w", err)
+		}
+		// Недоступная при запуске реплика не мешает работе: она будет
+		// использоваться, когда проверка состояния её обнаружит.
+		cfg.LazyConnect = true
+		traceQueries(cfg)
+		pool, err := pgxpool.ConnectConfig(context.Background(), cfg)
+		if err != nil {
+			rs.close()
+			s.Close()
+			return nil, fmt.Errorf("replica: %w", err)
+		}
+		name := fmt.Sprintf("%s:%d", cfg.ConnConfig.Host, cfg.ConnConfig.Port)
+		rs.replicas = append(rs.replicas, &replica{name: name, pool: pool})
+	}
+
+	rs.check()
+	go rs.run()
+
+	s.replicas = rs
+	fmt.Println("Loaded bd replicas: ", len(rs.replicas))
+
+	return s, nil
+}
+
+// Периодическая проверка состояния реплик.
+func (rs *replicaSet) run() {
+	ticker := time.NewTicker(rs.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rs.stop:
+			return
+		case <-ticker.C:
+			rs.check()
+			rs.forget()
+		}
+	}
+}
+
+// Проверка доступности и отставания каждой реплики.
+// Изменение состояния записывается в журнал.
+func (rs *replicaSet) check() {
+	for _, r := range rs.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), rs.opts.CheckInterval+time.Second)
+		var lagSeconds float64
+		err := r.pool.QueryRow(ctx, lagQuery).Scan(&lagSeconds)
+		cancel()
+
+		lag := time.Duration(lagSeconds * float64(time.Second))
+		healthy := err == nil && lag <= rs.opts.MaxLag
+		if rs.setHealthy(r, healthy) {
+			switch {
+			case err != nil:
+				rs.log(r, fmt.Sprintf("replica unavailable: %v", err))
+			case !healthy:
+				rs.log(r, fmt.Sprintf("replica lag %v exceeds %v, reads go elsewhere", lag, rs.opts.MaxLag))
+			default:
+				rs.log(r, fmt.Sprintf("replica available, lag %v", lag))
+			}
+		}
+	}
+}
+
+// Установка состояния реплики; true, если оно изменилось.
+func (rs *replicaSet) setHealthy(r *replica, healthy bool) bool {
+	var v int32
+	if healthy {
+		v = 1
+	}
+	return atomic.SwapInt32(&r.healthy, v) != v
+}
+
+func (rs *replicaSet) log(r *replica, mess string) {
+	logger.SetLog(time.Now(), "PostgreSQL replica "+r.name, mess)
+}
+
+// Выбор реплики для чтения клиента client по кругу среди доступных.
+// nil - читать из основной БД (нет доступных реплик или клиент недавно
+// изменял данные).
+func (rs *replicaSet) pick(client string) *replica {
+	rs.mu.Lock()
+	last, ok := rs.writes[client]
+	rs.mu.Unlock()
+	if ok && time.Since(last) < rs.opts.ReadYourWrites {
+		return nil
+	}
+
+	n := uint32(len(rs.replicas))
+	start := atomic.AddUint32(&rs.next, 1)
+	for i := uint32(0); i < n; i++ {
+		r := rs.replicas[(start+i)%n]
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			return r
+		}
+	}
+	return nil
+}
+
+// Отметка об изменении в основной БД клиентом client.
+func (rs *replicaSet) wrote(client string) {
+	rs.mu.Lock()
+	rs.writes[client] = time.Now()
+	rs.mu.Unlock()
+}
+
+// Удаление отметок старше ReadYourWrites.
+func (rs *replicaSet) forget() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for client, last := range rs.writes {
+		if time.Since(last) >= rs.opts.ReadYourWrites {
+			delete(rs.writes, client)
+		}
+	}
+}
+
+func (rs *replicaSet) close() {
+	close(rs.stop)
+	for _, r := range rs.replicas {
+		r.pool.Close()
+	}
+}
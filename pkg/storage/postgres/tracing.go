@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"GoNews/pkg/tracing"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Подключение к БД constr со спанами запросов.
+func connect(constr string) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(constr)
+	if err != nil {
+		return nil, err
+	}
+	traceQueries(cfg)
+	return pgxpool.ConnectConfig(context.Background(), cfg)
+}
+
+// traceQueries подключает к пулу cfg создание спанов запросов.
+// pgx v4 не имеет перехватчиков запросов: спан создаётся по записи
+// журнала pgx, которая появляется после выполнения запроса и содержит
+// его длительность (time) и текст (sql). Параметры запроса в спан не попадают.
+func traceQueries(cfg *pgxpool.Config) {
+	cfg.ConnConfig.Logger = queryTracer{}
+	cfg.ConnConfig.LogLevel = pgx.LogLevelInfo
+}
+
+type queryTracer struct{}
+
+func (queryTracer) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	sql, ok := data["sql"].(string)
+	if !ok || !tracing.Enabled() {
+		return
+	}
+	end := time.Now()
+	d, _ := data["time"].(time.Duration)
+	_, span := tracing.StartAt(ctx, "postgres "+msg, tracing.KindClient, end.Add(-d))
+	span.SetAttr("db.system", "postgresql")
+	span.SetAttr("db.statement", sql)
+	if n, ok := data["rowCount"].(int); ok {
+		span.SetAttr("db.rows", n)
+	}
+	if err, ok := data["err"].(error); ok {
+		span.SetError(err)
+	}
+	span.FinishAt(end)
+}
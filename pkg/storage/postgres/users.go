@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/storage"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Пользователи API хранятся в таблице users основной БД (см. schema.sql).
+
+const userColumns = `id, name, role, author_id, created_at`
+
+func scanUser(row pgx.Row) (auth.User, error) {
+	var u auth.User
+	err := row.Scan(&u.ID, &u.Name, &u.Role, &u.AuthorID, &u.CreatedAt)
+	return u, err
+}
+
+func (s *Store) Users() ([]auth.User, error) {
+	rows, err := s.pool.Query(context.Background(), `SELECT `+userColumns+` FROM users ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []auth.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, u)
+	}
+	return data, rows.Err()
+}
+
+func (s *Store) UserByID(id int64) (auth.User, error) {
+	u, err := scanUser(s.pool.QueryRow(context.Background(), `SELECT `+userColumns+` FROM users WHERE id = $1;`, id))
+	if err == pgx.ErrNoRows {
+		return u, fmt.Errorf("user %v %w", id, storage.ErrNotFound)
+	}
+	return u, err
+}
+
+func (s *Store) UserByName(name string) (auth.User, error) {
+	u, err := scanUser(s.pool.QueryRow(context.Background(), `SELECT `+userColumns+` FROM users WHERE name = $1;`, name))
+	if err == pgx.ErrNoRows {
+		return u, fmt.Errorf("user %q %w", name, storage.ErrNotFound)
+	}
+	return u, err
+}
+
+func (s *Store) AddUser(u auth.User) (int64, error) {
+	var id int64
+	err := s.pool.QueryRow(context.Background(), `
+		INSERT INTO users (name, role, author_id, created_at) VALUES ($1, $2, $3, $4) RETURNING id;`,
+		u.Name, u.Role, u.AuthorID, u.CreatedAt).Scan(&id)
+	return id, userError(u, err)
+}
+
+func (s *Store) UpdateUser(u auth.User) error {
+	tag, err := s.pool.Exec(context.Background(), `
+		UPDATE users SET name = $2, role = $3, author_id = $4 WHERE id = $1;`,
+		u.ID, u.Name, u.Role, u.AuthorID)
+	if err != nil {
+		return userError(u, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user %v %w", u.ID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) DeleteUser(id int64) error {
+	tag, err := s.pool.Exec(context.Background(), `DELETE FROM users WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user %v %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// Нарушение уникальности имени - auth.ErrUserExists.
+func userError(u auth.User, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return fmt.Errorf("%q: %w", u.Name, auth.ErrUserExists)
+	}
+	return err
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"GoNews/pkg/storage"
+	"GoNews/pkg/webhooks"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Подписки и доставки webhooks хранятся в таблицах webhooks и
+// webhook_deliveries основной БД (см. schema.sql).
+
+const webhookColumns = `id, url, events, secret, active, created_at`
+
+const deliveryColumns = `id, subscription_id, event, event_id, payload, status, attempts,
+	next_attempt_at, response_code, last_error, created_at, updated_at`
+
+func (s *Store) Webhooks() ([]webhooks.Subscription, error) {
+	rows, err := s.pool.Query(context.Background(), `SELECT `+webhookColumns+` FROM webhooks ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []webhooks.Subscription
+	for rows.Next() {
+		sub, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, sub)
+	}
+	return data, rows.Err()
+}
+
+func (s *Store) WebhookByID(id int64) (webhooks.Subscription, error) {
+	sub, err := scanWebhook(s.pool.QueryRow(context.Background(), `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1;`, id))
+	if err == pgx.ErrNoRows {
+		return sub, fmt.Errorf("webhook %v %w", id, storage.ErrNotFound)
+	}
+	return sub, err
+}
+
+func (s *Store) AddWebhook(sub webhooks.Subscription) (int64, error) {
+	var id int64
+	err := s.pool.QueryRow(context.Background(), `
+		INSERT INTO webhooks (url, events, secret, active, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id;`,
+		sub.URL, sub.Events, sub.Secret, sub.Active, sub.CreatedAt).Scan(&id)
+	return id, err
+}
+
+func (s *Store) UpdateWebhook(sub webhooks.Subscription) error {
+	tag, err := s.pool.Exec(context.Background(), `
+		UPDATE webhooks SET url = $2, events = $3, secret = $4, active = $5
+		WHERE id = $1;`,
+		sub.ID, sub.URL, sub.Events, sub.Secret, sub.Active)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook %v %w", sub.ID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) DeleteWebhook(id int64) error {
+	tag, err := s.pool.Exec(context.Background(), `DELETE FROM webhooks WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook %v %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) AddDelivery(d webhooks.Delivery) (int64, error) {
+	var id int64
+	err := s.pool.QueryRow(context.Background(), `
+		INSERT INTO webhook_deliveries (subscription_id, event, event_id, payload, status, attempts,
+			next_attempt_at, response_code, last_error, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id;`,
+		d.SubscriptionID, d.Event, d.EventID, string(d.Payload), d.Status, d.Attempts,
+		d.NextAttemptAt, d.ResponseCode, d.LastError, d.CreatedAt, d.UpdatedAt).Scan(&id)
+	return id, err
+}
+
+func (s *Store) UpdateDelivery(d webhooks.Delivery) error {
+	tag, err := s.pool.Exec(context.Background(), `
+		UPDATE webhook_deliveries SET status = $2, attempts = $3, next_attempt_at = $4,
+			response_code = $5, last_error = $6, updated_at = $7
+		WHERE id = $1;`,
+		d.ID, d.Status, d.Attempts, d.NextAttemptAt, d.ResponseCode, d.LastError, d.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delivery %v %w", d.ID, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) DeliveryByID(id int64) (webhooks.Delivery, error) {
+	d, err := scanDelivery(s.pool.QueryRow(context.Background(), `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1;`, id))
+	if err == pgx.ErrNoRows {
+		return d, fmt.Errorf("delivery %v %w", id, storage.ErrNotFound)
+	}
+	return d, err
+}
+
+func (s *Store) DueDeliveries(now int64, limit int) ([]webhooks.Delivery, error) {
+	return s.queryDeliveries(`
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = $1 AND next_attempt_at <= $2
+		ORDER BY next_attempt_at, id
+		LIMIT $3;`, webhooks.StatusPending, now, limit)
+}
+
+func (s *Store) Deliveries(f webhooks.DeliveryFilter) ([]webhooks.Delivery, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	return s.queryDeliveries(`
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE ($1 = 0 OR subscription_id = $1) AND ($2 = '' OR status = $2) AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT NULLIF($4, -1);`, f.SubscriptionID, f.Status, f.BeforeID, limit)
+}
+
+func (s *Store) DeleteDeliveries(before int64) (int64, error) {
+	tag, err := s.pool.Exec(context.Background(), `
+		DELETE FROM webhook_deliveries WHERE status <> $1 AND updated_at < $2;`,
+		webhooks.StatusPending, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (s *Store) queryDeliveries(sql string, args ...interface{}) ([]webhooks.Delivery, error) {
+	rows, err := s.pool.Query(context.Background(), sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []webhooks.Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, d)
+	}
+	return data, rows.Err()
+}
+
+func scanWebhook(row pgx.Row) (webhooks.Subscription, error) {
+	var sub webhooks.Subscription
+	err := row.Scan(&sub.ID, &sub.URL, &sub.Events, &sub.Secret, &sub.Active, &sub.CreatedAt)
+	return sub, err
+}
+
+func scanDelivery(row pgx.Row) (webhooks.Delivery, error) {
+	var d webhooks.Delivery
+	var payload []byte
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.Event, &d.EventID, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.ResponseCode, &d.LastError, &d.CreatedAt, &d.UpdatedAt)
+	d.Payload = payload
+	return d, err
+}
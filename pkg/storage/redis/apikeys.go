@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Ключи API: JSON-записи в хэше по ID и индекс хэш ключа -> ID.
+const (
+	keyAPIKeys       = "gonews:apikeys"
+	keyAPIKeysByHash = "gonews:apikeys:hash"
+	keyAPIKeysSeq    = "gonews:apikeys:seq"
+)
+
+func (s *Store) APIKeys() ([]auth.Key, error) {
+	vals, err := s.db.HGetAll(context.Background(), keyAPIKeys).Result()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]auth.Key, 0, len(vals))
+	for _, val := range vals {
+		k, err := decodeAPIKey(val)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, k)
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID < data[j].ID })
+	return data, nil
+}
+
+func (s *Store) APIKeyByHash(hash string) (auth.Key, error) {
+	ctx := context.Background()
+	id, err := s.db.HGet(ctx, keyAPIKeysByHash, hash).Result()
+	if err == redis.Nil {
+		return auth.Key{}, fmt.Errorf("API key %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return auth.Key{}, err
+	}
+	val, err := s.db.HGet(ctx, keyAPIKeys, id).Result()
+	if err == redis.Nil {
+		return auth.Key{}, fmt.Errorf("API key %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return auth.Key{}, err
+	}
+	return decodeAPIKey(val)
+}
+
+func (s *Store) AddAPIKey(k auth.Key) (int64, error) {
+	ctx := context.Background()
+	id, err := s.db.Incr(ctx, keyAPIKeysSeq).Result()
+	if err != nil {
+		return 0, err
+	}
+	k.ID = id
+	// Хэш не входит в JSON ключа (json:"-"), поэтому хранится отдельно.
+	val, err := json.Marshal(storedAPIKey{Key: k, Hash: k.Hash})
+	if err != nil {
+		return 0, err
+	}
+	field := strconv.FormatInt(id, 10)
+	_, err = s.db.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, keyAPIKeys, field, val)
+		p.HSet(ctx, keyAPIKeysByHash, k.Hash, field)
+		return nil
+	})
+	return id, err
+}
+
+func (s *Store) DeleteAPIKey(id int64) error {
+	ctx := context.Background()
+	field := strconv.FormatInt(id, 10)
+	val, err := s.db.HGet(ctx, keyAPIKeys, field).Result()
+	if err == redis.Nil {
+		return fmt.Errorf("API key %v %w", id, storage.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	k, err := decodeAPIKey(val)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HDel(ctx, keyAPIKeys, field)
+		p.HDel(ctx, keyAPIKeysByHash, k.Hash)
+		return nil
+	})
+	return err
+}
+
+// Запись ключа API в Redis.
+type storedAPIKey struct {
+	auth.Key
+	Hash string `json:"hash"`
+}
+
+func decodeAPIKey(val string) (auth.Key, error) {
+	var sk storedAPIKey
+	if err := json.Unmarshal([]byte(val), &sk); err != nil {
+		return auth.Key{}, err
+	}
+	sk.Key.Hash = sk.Hash
+	return sk.Key, nil
+}
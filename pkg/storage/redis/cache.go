@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Cache - Redis как кэш перед другой БД (см. пакет storage/cache).
+type Cache struct {
+	db *redis.Client
+}
+
+// Конструктор кэша. Проверяет доступность сервера.
+func NewCache(constr string, password string, number int) (*Cache, error) {
+
+	db := redis.NewClient(&redis.Options{
+		Addr:     constr,
+		Password: password,
+		DB:       number,
+	})
+
+	db.AddHook(commandTracer{})
+
+	if err := db.Ping(context.Background()).Err(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Cache{db: db}, nil
+}
+
+// Get возвращает значение по ключу; ok == false, если ключа нет.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	val, err := c.db.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return val, true, nil
+}
+
+// Set сохраняет значение на время ttl.
+func (c *Cache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	return c.db.Set(ctx, key, val, ttl).Err()
+}
+
+// Del удаляет ключи.
+func (c *Cache) Del(ctx context.Context, keys ...string) error {
+	return c.db.Del(ctx, keys...).Err()
+}
+
+// Incr увеличивает счётчик по ключу и возвращает новое значение.
+func (c *Cache) Incr(ctx context.Context, key string) (int64, error) {
+	return c.db.Incr(ctx, key).Result()
+}
+
+// Close закрывает соединения с сервером.
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"GoNews/pkg/events"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Поток событий изменений и его примерная максимальная длина.
+const (
+	eventsStream    = "gonews:events"
+	eventsMaxLen    = 10000
+	eventsReadBlock = 5 * time.Second
+)
+
+// Events - шина событий изменений на потоке (stream) Redis.
+// ID событий - ID записей потока.
+type Events struct {
+	db *redis.Client
+}
+
+// Конструктор шины событий. Проверяет доступность сервера.
+func NewEvents(constr string, password string, number int) (*Events, error) {
+
+	db := redis.NewClient(&redis.Options{
+		Addr:     constr,
+		Password: password,
+		DB:       number,
+	})
+
+	db.AddHook(commandTracer{})
+
+	if err := db.Ping(context.Background()).Err(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Events{db: db}, nil
+}
+
+// Publish добавляет событие в поток. Старые события удаляются сверх eventsMaxLen.
+func (b *Events) Publish(ctx context.Context, e events.Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.ID = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.db.XAdd(ctx, &redis.XAddArgs{
+		Stream: eventsStream,
+		MaxLen: eventsMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": data},
+	}).Err()
+}
+
+// Subscribe читает события из потока после afterID.
+func (b *Events) Subscribe(ctx context.Context, afterID string) (<-chan events.Event, error) {
+	last := afterID
+	if last == "" {
+		// Позиция последней записи, чтобы не пропустить события между чтениями.
+		msgs, err := b.db.XRevRangeN(ctx, eventsStream, "+", "-", 1).Result()
+		if err != nil {
+			return nil, err
+		}
+		last = "0-0"
+		if len(msgs) > 0 {
+			last = msgs[0].ID
+		}
+	}
+
+	ch := make(chan events.Event)
+	go func() {
+		defer close(ch)
+		for ctx.Err() == nil {
+			streams, err := b.db.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{eventsStream, last},
+				Count:   100,
+				Block:   eventsReadBlock,
+			}).Result()
+			if err == redis.Nil {
+				continue
+			}
+			if err != nil {
+				return
+			}
+			for _, stream := range streams {
+				for _, msg := range stream.Messages {
+					last = msg.ID
+					raw, _ := msg.Values["event"].(string)
+					var e events.Event
+					if err := json.Unmarshal([]byte(raw), &e); err != nil {
+						// Записи другого формата пропускаются.
+						continue
+					}
+					e.ID = msg.ID
+					select {
+					case ch <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Close закрывает соединения с сервером.
+func (b *Events) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,17 @@
+package redis
+
+import "GoNews/pkg/metrics"
+
+// PoolStats - состояние пула соединений клиента Redis (для /metrics).
+func (s *Store) PoolStats() []metrics.PoolStats {
+	st := s.db.PoolStats()
+	return []metrics.PoolStats{{
+		Pool:     "primary",
+		Open:     int64(st.TotalConns),
+		Idle:     int64(st.IdleConns),
+		InUse:    int64(st.TotalConns) - int64(st.IdleConns),
+		Max:      int64(s.db.Options().PoolSize),
+		Misses:   int64(st.Misses),
+		Timeouts: int64(st.Timeouts),
+	}}
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"GoNews/pkg/ratelimit"
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Limiter - корзины ограничения частоты запросов в Redis, общие для
+// нескольких экземпляров сервера (см. пакет ratelimit).
+type Limiter struct {
+	db *redis.Client
+}
+
+// Префикс ключей корзин; корзина - хэш {tokens, ts}.
+const keyRateLimit = "gonews:ratelimit:"
+
+// Пополнение и списание маркера выполняются атомарно одним скриптом.
+// Время берётся у Redis (TIME), чтобы часы экземпляров сервера не влияли
+// на результат; требуется Redis 5 и новее.
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1])
+local ts = tonumber(b[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, math.floor(tokens), wait}
+`)
+
+// Конструктор корзин. Проверяет доступность сервера.
+func NewLimiter(constr string, password string, number int) (*Limiter, error) {
+
+	db := redis.NewClient(&redis.Options{
+		Addr:     constr,
+		Password: password,
+		DB:       number,
+	})
+
+	db.AddHook(commandTracer{})
+
+	if err := db.Ping(context.Background()).Err(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Limiter{db: db}, nil
+}
+
+// Take забирает маркер из корзины key.
+func (l *Limiter) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	if limit.Unlimited() {
+		return ratelimit.Result{Allowed: true, Remaining: limit.Burst}, nil
+	}
+	vals, err := takeScript.Run(ctx, l.db, []string{keyRateLimit + key}, limit.Burst, limit.Rate).Int64Slice()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return ratelimit.Result{
+		Allowed:    vals[0] == 1,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+	}, nil
+}
+
+// Close закрывает соединения с сервером.
+func (l *Limiter) Close() error {
+	return l.db.Close()
+}
@@ -5,7 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -16,24 +16,62 @@ const (
 	collectionPosts   = "posts"   // имя коллекции в учебной БД
 )
 
+// Ключ счётчика ID коллекции (вне шаблона "коллекция:*", по которому читаются записи).
+func seqKey(collection string) string {
+	return "gonews:" + collection + ":seq"
+}
+
+// ID новой записи collection без ID (как последовательность PostgreSQL):
+// следующее значение счётчика, не занятое записью с ID, заданным клиентом.
+// exists проверяет существование ключа записи.
+func assignID(ctx context.Context, c redis.Cmdable, collection string, exists func(key string) (bool, error)) (int64, error) {
+	for {
+		id, err := c.Incr(ctx, seqKey(collection)).Result()
+		if err != nil {
+			return 0, err
+		}
+		taken, err := exists(fmt.Sprintf("%s:%d", collection, id))
+		if err != nil {
+			return 0, err
+		}
+		if !taken {
+			return id, nil
+		}
+	}
+}
+
 // Хранилище данных.
 type Store struct {
-	db *redis.Client
+	db  *redis.Client
+	ctx context.Context // контекст запросов (Bind), nil - context.Background()
 }
 
 func (s *Store) GetInform() string {
 	return "Redis"
 }
 
+// Время ожидания ответа сервера при подключении.
+const connectTimeout = 5 * time.Second
+
 // Конструктор объекта хранилища.
 func New(constr string, password string, number int) (*Store, error) {
 
 	db := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379", // Redis server address
-		Password: "",               // No password set
-		DB:       0,                // Use default DB
+		Addr:     constr,   // Redis server address
+		Password: password, // "" - no password set
+		DB:       number,   // 0 - use default DB
 	})
 
+	db.AddHook(commandTracer{})
+
+	// Недоступный сервер - ошибка при запуске, а не при первом запросе.
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	if err := db.Ping(ctx).Err(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	s := Store{
 		db: db,
 	}
@@ -43,12 +81,46 @@ func New(constr string, password string, number int) (*Store, error) {
 	return &s, nil
 }
 
-func (s *Store) Close() {
+// Close закрывает пул соединений клиента.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Ping проверяет соединение с сервером.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx).Err()
+}
+
+// Version - версия сервера Redis (redis_version из INFO server).
+func (s *Store) Version(ctx context.Context) (string, error) {
+	info, err := s.db.Info(ctx, "server").Result()
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if strings.HasPrefix(line, "redis_version:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "redis_version:")), nil
+		}
+	}
+	return "", nil
+}
+
+// Bind - копия хранилища, выполняющая команды с контекстом ctx.
+func (s *Store) Bind(ctx context.Context) storage.Interface {
+	return &Store{db: s.db, ctx: ctx}
+}
+
+// Контекст команд Redis.
+func (s *Store) opCtx() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return context.Background()
 }
 
 func (s *Store) existsKey(key string) bool {
 
-	exists, err := s.db.Exists(context.Background(), key).Result()
+	exists, err := s.db.Exists(s.opCtx(), key).Result()
 	if err != nil {
 		return false
 	}
@@ -59,11 +131,19 @@ func (s *Store) existsKey(key string) bool {
 	return true
 }
 
+func (s *Store) nextID(collection string) (int64, error) {
+	ctx := s.opCtx()
+	return assignID(ctx, s.db, collection, func(key string) (bool, error) {
+		n, err := s.db.Exists(ctx, key).Result()
+		return n > 0, err
+	})
+}
+
 func (s *Store) getNameAuthorsById(post storage.Post) (string, error) {
 
 	key := fmt.Sprintf("%s:%d", collectionAuthors, post.AuthorID)
 
-	val, err := s.db.Get(context.Background(), key).Result()
+	val, err := s.db.Get(s.opCtx(), key).Result()
 	if err != nil {
 		return fmt.Sprintf("AuthorID: %v not exist", post.AuthorID), err
 	}
@@ -76,18 +156,115 @@ func (s *Store) getNameAuthorsById(post storage.Post) (string, error) {
 	return author.Name, nil
 }
 
+// Изменение значения по ключу с оптимистичной блокировкой (WATCH/MULTI/EXEC):
+// если ключ изменён другим клиентом между чтением и записью, транзакция не выполнится.
+func (s *Store) patchKey(key string, apply func(val []byte) ([]byte, error)) error {
+	ctx := s.opCtx()
+
+	return s.db.Watch(ctx, func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return fmt.Errorf("PATCH Id: %v %w", key, storage.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		val, err = apply(val)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, string(val), 0)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+// Значения всех записей коллекций ARGV[1] и ARGV[2] одним скриптом: Redis
+// выполняет скрипт атомарно, поэтому значения соответствуют одному моменту.
+var snapshotScript = redis.NewScript(`
+local function values(collection)
+	local vals = {}
+	for i, key in ipairs(redis.call('KEYS', collection .. ':*')) do
+		vals[i] = redis.call('GET', key)
+	end
+	return vals
+end
+return {values(ARGV[1]), values(ARGV[2])}
+`)
+
+// Snapshot читает авторов и публикации одним скриптом (snapshotScript).
+// Блокирует сервер на время чтения, поэтому предназначен для резервного копирования.
+func (s *Store) Snapshot(ctx context.Context) ([]storage.Author, []storage.Post, error) {
+	res, err := snapshotScript.Run(ctx, s.db, nil, collectionAuthors, collectionPosts).Slice()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(res) != 2 {
+		return nil, nil, fmt.Errorf("snapshot: unexpected script result %v", res)
+	}
+
+	var authors []storage.Author
+	var posts []storage.Post
+	err = decodeValues(res[0], func(val []byte) error {
+		var author storage.Author
+		if err := json.Unmarshal(val, &author); err != nil {
+			return err
+		}
+		authors = append(authors, author)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = decodeValues(res[1], func(val []byte) error {
+		var post storage.Post
+		if err := json.Unmarshal(val, &post); err != nil {
+			return err
+		}
+		posts = append(posts, post)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return authors, posts, nil
+}
+
+// Разбор списка значений из результата скрипта.
+func decodeValues(list interface{}, decode func(val []byte) error) error {
+	vals, ok := list.([]interface{})
+	if !ok {
+		return fmt.Errorf("snapshot: unexpected script result %v", list)
+	}
+	for _, v := range vals {
+		str, ok := v.(string)
+		if !ok {
+			// Ключ не строкового типа.
+			continue
+		}
+		if err := decode([]byte(str)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Author - автор.
 func (s *Store) Authors() ([]storage.Author, error) {
 	var authors []storage.Author
 
 	keyPattern := fmt.Sprintf("%s:*", collectionAuthors)
-	keys, err := s.db.Keys(context.Background(), keyPattern).Result()
+	keys, err := s.db.Keys(s.opCtx(), keyPattern).Result()
 	if err != nil {
 		return nil, err
 	}
 
 	for _, key := range keys {
-		val, err := s.db.Get(context.Background(), key).Result()
+		val, err := s.db.Get(s.opCtx(), key).Result()
 		if err != nil {
 			return nil, err
 		}
@@ -102,8 +279,31 @@ func (s *Store) Authors() ([]storage.Author, error) {
 	return authors, nil
 }
 
+func (s *Store) AuthorByID(id int64) (storage.Author, error) {
+	var author storage.Author
+
+	key := fmt.Sprintf("%s:%d", collectionAuthors, id)
+	val, err := s.db.Get(s.opCtx(), key).Result()
+	if err == redis.Nil {
+		return author, fmt.Errorf("Id: %v %w", key, storage.ErrNotFound)
+	}
+	if err != nil {
+		return author, err
+	}
+
+	err = json.Unmarshal([]byte(val), &author)
+	return author, err
+}
+
 func (s *Store) AddAuthor(author storage.Author) (int64, error) {
 
+	if author.ID == 0 {
+		id, err := s.nextID(collectionAuthors)
+		if err != nil {
+			return 0, err
+		}
+		author.ID = id
+	}
 	key := fmt.Sprintf("%s:%d", collectionAuthors, author.ID)
 
 	if s.existsKey(key) {
@@ -114,7 +314,7 @@ func (s *Store) AddAuthor(author storage.Author) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	err = s.db.Set(context.Background(), key, string(val), 0).Err()
+	err = s.db.Set(s.opCtx(), key, string(val), 0).Err()
 	if err != nil {
 		return 0, err
 	}
@@ -134,7 +334,7 @@ func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	err = s.db.Set(context.Background(), key, string(val), 0).Err()
+	err = s.db.Set(s.opCtx(), key, string(val), 0).Err()
 	if err != nil {
 		return 0, err
 	}
@@ -142,48 +342,41 @@ func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
 	return author.ID, nil
 }
 
-func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
+func (s *Store) PatchAuthor(id int64, fields map[string]interface{}) (int64, error) {
 
-	key := fmt.Sprintf("%s:%d", collectionAuthors, author.ID)
+	key := fmt.Sprintf("%s:%d", collectionAuthors, id)
 
-	if !s.existsKey(key) {
-		return 0, fmt.Errorf("DELETE Id: %v not exist", key)
-	}
-
-	err := s.db.Del(context.Background(), key).Err()
+	err := s.patchKey(key, func(val []byte) ([]byte, error) {
+		var author storage.Author
+		if err := json.Unmarshal(val, &author); err != nil {
+			return nil, err
+		}
+		if err := storage.ApplyAuthorFields(&author, fields); err != nil {
+			return nil, err
+		}
+		return json.Marshal(author)
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	return author.ID, nil
+	return id, nil
 }
 
-func (s *Store) InsertInitDataFromFileAuthors(filename string) error {
+func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
 
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
-	}
+	key := fmt.Sprintf("%s:%d", collectionAuthors, author.ID)
 
-	var authors []storage.Author
-	err = json.Unmarshal(data, &authors)
-	if err != nil {
-		return err
+	if !s.existsKey(key) {
+		return 0, fmt.Errorf("DELETE Id: %v not exist", key)
 	}
 
-	for _, author := range authors {
-		key := fmt.Sprintf("%s:%d", collectionAuthors, author.ID)
-		val, err := json.Marshal(author)
-		if err != nil {
-			return err
-		}
-		err = s.db.Set(context.Background(), key, string(val), 0).Err()
-		if err != nil {
-			return err
-		}
+	err := s.db.Del(s.opCtx(), key).Err()
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	return author.ID, nil
 }
 
 // Post - публикация.
@@ -191,13 +384,13 @@ func (s *Store) Posts() ([]storage.Post, error) {
 	var posts []storage.Post
 
 	keyPattern := fmt.Sprintf("%s:*", collectionPosts)
-	keys, err := s.db.Keys(context.Background(), keyPattern).Result()
+	keys, err := s.db.Keys(s.opCtx(), keyPattern).Result()
 	if err != nil {
 		return nil, err
 	}
 
 	for _, key := range keys {
-		val, err := s.db.Get(context.Background(), key).Result()
+		val, err := s.db.Get(s.opCtx(), key).Result()
 		if err != nil {
 			return nil, err
 		}
@@ -209,8 +402,6 @@ func (s *Store) Posts() ([]storage.Post, error) {
 
 		authorName, _ := s.getNameAuthorsById(post)
 		post.AuthorName = authorName
-		post.CreatedAtTxt = time.Unix(int64(post.CreatedAt)/1000, 0).Format("2006-01-02 15:04:05.000")
-		post.PublishedAtTxt = time.Unix(int64(post.PublishedAt)/1000, 0).Format("2006-01-02 15:04:05.000")
 
 		posts = append(posts, post)
 	}
@@ -218,8 +409,38 @@ func (s *Store) Posts() ([]storage.Post, error) {
 	return posts, nil
 }
 
+func (s *Store) PostByID(id int64) (storage.Post, error) {
+	var post storage.Post
+
+	key := fmt.Sprintf("%s:%d", collectionPosts, id)
+	val, err := s.db.Get(s.opCtx(), key).Result()
+	if err == redis.Nil {
+		return post, fmt.Errorf("Id: %v %w", key, storage.ErrNotFound)
+	}
+	if err != nil {
+		return post, err
+	}
+
+	err = json.Unmarshal([]byte(val), &post)
+	if err != nil {
+		return post, err
+	}
+
+	authorName, _ := s.getNameAuthorsById(post)
+	post.AuthorName = authorName
+
+	return post, nil
+}
+
 func (s *Store) AddPost(post storage.Post) (int64, error) {
 
+	if post.ID == 0 {
+		id, err := s.nextID(collectionPosts)
+		if err != nil {
+			return 0, err
+		}
+		post.ID = id
+	}
 	key := fmt.Sprintf("%s:%d", collectionPosts, post.ID)
 
 	if s.existsKey(key) {
@@ -230,7 +451,7 @@ func (s *Store) AddPost(post storage.Post) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	err = s.db.Set(context.Background(), key, string(val), 0).Err()
+	err = s.db.Set(s.opCtx(), key, string(val), 0).Err()
 	if err != nil {
 		return 0, err
 	}
@@ -250,7 +471,7 @@ func (s *Store) UpdatePost(post storage.Post) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	err = s.db.Set(context.Background(), key, string(val), 0).Err()
+	err = s.db.Set(s.opCtx(), key, string(val), 0).Err()
 	if err != nil {
 		return 0, err
 	}
@@ -258,46 +479,194 @@ func (s *Store) UpdatePost(post storage.Post) (int64, error) {
 	return post.ID, nil
 }
 
-func (s *Store) DeletePost(post storage.Post) (int64, error) {
+func (s *Store) PatchPost(id int64, fields map[string]interface{}) (int64, error) {
 
-	key := fmt.Sprintf("%s:%d", collectionPosts, post.ID)
+	key := fmt.Sprintf("%s:%d", collectionPosts, id)
 
-	if !s.existsKey(key) {
-		return 0, fmt.Errorf("DELETE Id: %v not exist", key)
+	if authorID, ok := fields["author_id"].(int64); ok {
+		if !s.existsKey(fmt.Sprintf("%s:%d", collectionAuthors, authorID)) {
+			return 0, fmt.Errorf("PATCH AuthorID: %v not exist", authorID)
+		}
 	}
 
-	err := s.db.Del(context.Background(), key).Err()
+	err := s.patchKey(key, func(val []byte) ([]byte, error) {
+		var post storage.Post
+		if err := json.Unmarshal(val, &post); err != nil {
+			return nil, err
+		}
+		if err := storage.ApplyPostFields(&post, fields); err != nil {
+			return nil, err
+		}
+		return json.Marshal(post)
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	return post.ID, nil
+	return id, nil
 }
 
-func (s *Store) InsertInitDataFromFilePosts(filename string) error {
+func (s *Store) DeletePost(post storage.Post) (int64, error) {
 
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
+	key := fmt.Sprintf("%s:%d", collectionPosts, post.ID)
+
+	if !s.existsKey(key) {
+		return 0, fmt.Errorf("DELETE Id: %v not exist", key)
 	}
 
-	var posts []storage.Post
-	err = json.Unmarshal(data, &posts)
+	err := s.db.Del(s.opCtx(), key).Err()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	for _, post := range posts {
-		key := fmt.Sprintf("%s:%d", collectionPosts, post.ID)
-		val, err := json.Marshal(post)
+	return post.ID, nil
+}
+
+// Пакетная запись значений vals по ключам keys (vals == nil - удаление).
+// mustExist задаёт требование к ключу: true - ключ должен существовать
+// (обновление, удаление), false - не должен (создание).
+// Существование проверяется одним конвейером (pipeline); в режиме BatchAtomic
+// ключи отслеживаются через WATCH, а запись выполняется в MULTI/EXEC.
+func (s *Store) batch(mode storage.BatchMode, ids []int64, keys []string, vals []string, mustExist bool) ([]storage.BatchResult, error) {
+	ctx := s.opCtx()
+	results := storage.NewBatchResults(len(keys))
+
+	run := func(c redis.Cmdable, write func(func(redis.Pipeliner) error) ([]redis.Cmder, error)) error {
+		exists := make([]*redis.IntCmd, len(keys))
+		_, err := c.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				exists[i] = pipe.Exists(ctx, key)
+			}
+			return nil
+		})
 		if err != nil {
 			return err
 		}
-		err = s.db.Set(context.Background(), key, string(val), 0).Err()
+
+		seen := make(map[string]bool, len(keys))
+		for i, key := range keys {
+			switch {
+			case seen[key]:
+				results[i].Err = fmt.Sprintf("Id: %v duplicated in batch", key)
+			case mustExist && exists[i].Val() == 0:
+				results[i].Err = fmt.Sprintf("Id: %v not exist", key)
+			case !mustExist && exists[i].Val() != 0:
+				results[i].Err = fmt.Sprintf("Id: %v exist", key)
+			}
+			seen[key] = true
+		}
+		if mode == storage.BatchAtomic && storage.BatchFailed(results) {
+			return storage.AbortBatch(results)
+		}
+
+		_, err = write(func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				if results[i].Err != "" {
+					continue
+				}
+				if vals == nil {
+					pipe.Del(ctx, key)
+				} else {
+					pipe.Set(ctx, key, vals[i], 0)
+				}
+			}
+			return nil
+		})
 		if err != nil {
 			return err
 		}
+
+		for i := range results {
+			if results[i].Err == "" {
+				results[i].ID = ids[i]
+			}
+		}
+		return nil
 	}
 
-	return nil
+	var err error
+	if mode == storage.BatchAtomic {
+		err = s.db.Watch(ctx, func(tx *redis.Tx) error {
+			return run(tx, func(fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+				return tx.TxPipelined(ctx, fn)
+			})
+		}, keys...)
+	} else {
+		err = run(s.db, func(fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+			return s.db.Pipelined(ctx, fn)
+		})
+	}
+	if err != nil && err != storage.ErrBatchAborted {
+		return nil, err
+	}
+
+	return results, err
+}
+
+func (s *Store) AuthorsBatch(op storage.BatchOp, mode storage.BatchMode, authors []storage.Author) ([]storage.BatchResult, error) {
+	if !op.Valid() {
+		return nil, fmt.Errorf("unknown batch operation: %v", op)
+	}
+
+	ids := make([]int64, len(authors))
+	keys := make([]string, len(authors))
+	var vals []string
+	if op != storage.BatchDelete {
+		vals = make([]string, len(authors))
+	}
+
+	for i, author := range authors {
+		if op == storage.BatchCreate && author.ID == 0 {
+			id, err := s.nextID(collectionAuthors)
+			if err != nil {
+				return nil, err
+			}
+			author.ID = id
+		}
+		ids[i] = author.ID
+		keys[i] = fmt.Sprintf("%s:%d", collectionAuthors, author.ID)
+		if vals != nil {
+			val, err := json.Marshal(author)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = string(val)
+		}
+	}
+
+	return s.batch(mode, ids, keys, vals, op != storage.BatchCreate)
+}
+
+func (s *Store) PostsBatch(op storage.BatchOp, mode storage.BatchMode, posts []storage.Post) ([]storage.BatchResult, error) {
+	if !op.Valid() {
+		return nil, fmt.Errorf("unknown batch operation: %v", op)
+	}
+
+	ids := make([]int64, len(posts))
+	keys := make([]string, len(posts))
+	var vals []string
+	if op != storage.BatchDelete {
+		vals = make([]string, len(posts))
+	}
+
+	for i, post := range posts {
+		if op == storage.BatchCreate && post.ID == 0 {
+			id, err := s.nextID(collectionPosts)
+			if err != nil {
+				return nil, err
+			}
+			post.ID = id
+		}
+		ids[i] = post.ID
+		keys[i] = fmt.Sprintf("%s:%d", collectionPosts, post.ID)
+		if vals != nil {
+			val, err := json.Marshal(post)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = string(val)
+		}
+	}
+
+	return s.batch(mode, ids, keys, vals, op != storage.BatchCreate)
 }
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"GoNews/pkg/tracing"
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Спаны команд и конвейеров Redis (перехватчик клиента go-redis).
+// Аргументы команд (ключи и значения) в спан не попадают.
+type commandTracer struct{}
+
+type spanKey struct{}
+
+func (commandTracer) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return startSpan(ctx, "redis "+cmd.Name(), cmd.Name(), 1), nil
+}
+
+func (commandTracer) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	finishSpan(ctx, cmd.Err())
+	return nil
+}
+
+func (commandTracer) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	names := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name())
+	}
+	return startSpan(ctx, "redis pipeline", strings.Join(names, " "), len(cmds)), nil
+}
+
+func (commandTracer) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			err = cmd.Err()
+			break
+		}
+	}
+	finishSpan(ctx, err)
+	return nil
+}
+
+func startSpan(ctx context.Context, name, operation string, n int) context.Context {
+	if !tracing.Enabled() {
+		return ctx
+	}
+	_, span := tracing.Start(ctx, name, tracing.KindClient)
+	span.SetAttr("db.system", "redis")
+	span.SetAttr("db.operation", operation)
+	if n > 1 {
+		span.SetAttr("db.redis.commands", n)
+	}
+	// Спан передаётся в AfterProcess, но не становится родительским
+	// для следующих команд: контекст вызывающего не меняется.
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func finishSpan(ctx context.Context, err error) {
+	span, ok := ctx.Value(spanKey{}).(*tracing.Span)
+	if !ok {
+		return
+	}
+	if err != redis.Nil {
+		span.SetError(err)
+	}
+	span.Finish()
+}
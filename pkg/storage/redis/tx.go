@@ -0,0 +1,223 @@
+package redis
+
+import (
+	"GoNews/pkg/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Транзакция Redis для WithTx.
+// Прочитанные ключи отслеживаются через WATCH, изменения накапливаются
+// в памяти и записываются одним блоком MULTI/EXEC при фиксации.
+// Если отслеживаемый ключ изменён другим клиентом, фиксация не выполнится.
+type txStore struct {
+	ctx     context.Context
+	tx      *redis.Tx
+	pending map[string]*string // новое значение ключа, nil - ключ удалён
+	order   []string           // порядок изменения ключей
+}
+
+// WithTx выполняет fn в транзакции Redis (WATCH + MULTI/EXEC).
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Tx) error) error {
+	return s.db.Watch(ctx, func(tx *redis.Tx) error {
+		t := &txStore{
+			ctx:     ctx,
+			tx:      tx,
+			pending: map[string]*string{},
+		}
+
+		if err := fn(t); err != nil {
+			return err
+		}
+		if len(t.order) == 0 {
+			return nil
+		}
+
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range t.order {
+				if val := t.pending[key]; val != nil {
+					pipe.Set(ctx, key, *val, 0)
+				} else {
+					pipe.Del(ctx, key)
+				}
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+// Чтение значения с учётом изменений, сделанных в транзакции.
+func (t *txStore) get(key string) (string, bool, error) {
+	if val, ok := t.pending[key]; ok {
+		if val == nil {
+			return "", false, nil
+		}
+		return *val, true, nil
+	}
+
+	if err := t.tx.Watch(t.ctx, key).Err(); err != nil {
+		return "", false, err
+	}
+	val, err := t.tx.Get(t.ctx, key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return val, true, nil
+}
+
+func (t *txStore) put(key string, val *string) {
+	if _, ok := t.pending[key]; !ok {
+		t.order = append(t.order, key)
+	}
+	t.pending[key] = val
+}
+
+// Запись значения с проверкой существования ключа:
+// mustExist - ключ должен существовать (обновление), иначе - отсутствовать (создание).
+func (t *txStore) write(key string, v interface{}, mustExist bool) error {
+	_, exists, err := t.get(key)
+	if err != nil {
+		return err
+	}
+	if mustExist && !exists {
+		return fmt.Errorf("UPDATE Id: %v not exist", key)
+	}
+	if !mustExist && exists {
+		return fmt.Errorf("INSERT Id: %v exist", key)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	val := string(data)
+	t.put(key, &val)
+	return nil
+}
+
+func (t *txStore) remove(key string) error {
+	_, exists, err := t.get(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("DELETE Id: %v not exist", key)
+	}
+	t.put(key, nil)
+	return nil
+}
+
+func (t *txStore) AuthorByID(id int64) (storage.Author, error) {
+	var author storage.Author
+
+	key := fmt.Sprintf("%s:%d", collectionAuthors, id)
+	val, ok, err := t.get(key)
+	if err != nil {
+		return author, err
+	}
+	if !ok {
+		return author, fmt.Errorf("Id: %v %w", key, storage.ErrNotFound)
+	}
+
+	err = json.Unmarshal([]byte(val), &author)
+	return author, err
+}
+
+// ID новой записи. Счётчик изменяется сразу, вне MULTI/EXEC (как nextval
+// в PostgreSQL): при откате транзакции значение не возвращается.
+func (t *txStore) nextID(collection string) (int64, error) {
+	return assignID(t.ctx, t.tx, collection, func(key string) (bool, error) {
+		_, exists, err := t.get(key)
+		return exists, err
+	})
+}
+
+func (t *txStore) AddAuthor(author storage.Author) (int64, error) {
+	if author.ID == 0 {
+		id, err := t.nextID(collectionAuthors)
+		if err != nil {
+			return 0, err
+		}
+		author.ID = id
+	}
+	key := fmt.Sprintf("%s:%d", collectionAuthors, author.ID)
+	if err := t.write(key, author, false); err != nil {
+		return 0, err
+	}
+	return author.ID, nil
+}
+
+func (t *txStore) UpdateAuthor(author storage.Author) (int64, error) {
+	key := fmt.Sprintf("%s:%d", collectionAuthors, author.ID)
+	if err := t.write(key, author, true); err != nil {
+		return 0, err
+	}
+	return author.ID, nil
+}
+
+func (t *txStore) DeleteAuthor(author storage.Author) (int64, error) {
+	key := fmt.Sprintf("%s:%d", collectionAuthors, author.ID)
+	if err := t.remove(key); err != nil {
+		return 0, err
+	}
+	return author.ID, nil
+}
+
+func (t *txStore) PostByID(id int64) (storage.Post, error) {
+	var post storage.Post
+
+	key := fmt.Sprintf("%s:%d", collectionPosts, id)
+	val, ok, err := t.get(key)
+	if err != nil {
+		return post, err
+	}
+	if !ok {
+		return post, fmt.Errorf("Id: %v %w", key, storage.ErrNotFound)
+	}
+	if err = json.Unmarshal([]byte(val), &post); err != nil {
+		return post, err
+	}
+
+	if author, err := t.AuthorByID(post.AuthorID); err == nil {
+		post.AuthorName = author.Name
+	}
+	return post, nil
+}
+
+func (t *txStore) AddPost(post storage.Post) (int64, error) {
+	if post.ID == 0 {
+		id, err := t.nextID(collectionPosts)
+		if err != nil {
+			return 0, err
+		}
+		post.ID = id
+	}
+	key := fmt.Sprintf("%s:%d", collectionPosts, post.ID)
+	if err := t.write(key, post, false); err != nil {
+		return 0, err
+	}
+	return post.ID, nil
+}
+
+func (t *txStore) UpdatePost(post storage.Post) (int64, error) {
+	key := fmt.Sprintf("%s:%d", collectionPosts, post.ID)
+	if err := t.write(key, post, true); err != nil {
+		return 0, err
+	}
+	return post.ID, nil
+}
+
+func (t *txStore) DeletePost(post storage.Post) (int64, error) {
+	key := fmt.Sprintf("%s:%d", collectionPosts, post.ID)
+	if err := t.remove(key); err != nil {
+		return 0, err
+	}
+	return post.ID, nil
+}
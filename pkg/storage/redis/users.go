@@ -0,0 +1,128 @@
+package redis
+
+import (
+	"GoNews/pkg/auth"
+	"GoNews/pkg/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Пользователи API: JSON-записи в хэше по ID и индекс имя -> ID.
+// Уникальность имени обеспечивает HSETNX в индексе.
+const (
+	keyUsers       = "gonews:users"
+	keyUsersByName = "gonews:users:name"
+	keyUsersSeq    = "gonews:users:seq"
+)
+
+func (s *Store) Users() ([]auth.User, error) {
+	vals, err := s.db.HGetAll(context.Background(), keyUsers).Result()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]auth.User, 0, len(vals))
+	for _, val := range vals {
+		var u auth.User
+		if err := json.Unmarshal([]byte(val), &u); err != nil {
+			return nil, err
+		}
+		data = append(data, u)
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID < data[j].ID })
+	return data, nil
+}
+
+func (s *Store) UserByID(id int64) (auth.User, error) {
+	var u auth.User
+	val, err := s.db.HGet(context.Background(), keyUsers, strconv.FormatInt(id, 10)).Result()
+	if err == redis.Nil {
+		return u, fmt.Errorf("user %v %w", id, storage.ErrNotFound)
+	}
+	if err != nil {
+		return u, err
+	}
+	err = json.Unmarshal([]byte(val), &u)
+	return u, err
+}
+
+func (s *Store) UserByName(name string) (auth.User, error) {
+	id, err := s.db.HGet(context.Background(), keyUsersByName, name).Int64()
+	if err == redis.Nil {
+		return auth.User{}, fmt.Errorf("user %q %w", name, storage.ErrNotFound)
+	}
+	if err != nil {
+		return auth.User{}, err
+	}
+	return s.UserByID(id)
+}
+
+func (s *Store) AddUser(u auth.User) (int64, error) {
+	ctx := context.Background()
+	id, err := s.db.Incr(ctx, keyUsersSeq).Result()
+	if err != nil {
+		return 0, err
+	}
+	u.ID = id
+	field := strconv.FormatInt(id, 10)
+	ok, err := s.db.HSetNX(ctx, keyUsersByName, u.Name, field).Result()
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("%q: %w", u.Name, auth.ErrUserExists)
+	}
+	val, err := json.Marshal(u)
+	if err != nil {
+		return 0, err
+	}
+	return id, s.db.HSet(ctx, keyUsers, field, val).Err()
+}
+
+func (s *Store) UpdateUser(u auth.User) error {
+	ctx := context.Background()
+	old, err := s.UserByID(u.ID)
+	if err != nil {
+		return err
+	}
+	field := strconv.FormatInt(u.ID, 10)
+	if u.Name != old.Name {
+		ok, err := s.db.HSetNX(ctx, keyUsersByName, u.Name, field).Result()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%q: %w", u.Name, auth.ErrUserExists)
+		}
+	}
+	val, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, keyUsers, field, val)
+		if u.Name != old.Name {
+			p.HDel(ctx, keyUsersByName, old.Name)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *Store) DeleteUser(id int64) error {
+	ctx := context.Background()
+	u, err := s.UserByID(id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HDel(ctx, keyUsers, strconv.FormatInt(id, 10))
+		p.HDel(ctx, keyUsersByName, u.Name)
+		return nil
+	})
+	return err
+}
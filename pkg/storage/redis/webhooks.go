@@ -0,0 +1,261 @@
+package redis
+
+import (
+	"GoNews/pkg/storage"
+	"GoNews/pkg/webhooks"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Подписки и доставки webhooks: JSON-записи в хэшах по ID,
+// счётчики ID, очередь - сортированное множество по времени следующей
+// попытки, журнал - сортированное множество по ID.
+const (
+	keyWebhooks        = "gonews:webhooks"
+	keyWebhooksSeq     = "gonews:webhooks:seq"
+	keyDeliveries      = "gonews:deliveries"
+	keyDeliveriesSeq   = "gonews:deliveries:seq"
+	keyDeliveriesDue   = "gonews:deliveries:due"
+	keyDeliveriesLog   = "gonews:deliveries:log"
+	deliveriesReadPage = 100
+)
+
+func (s *Store) Webhooks() ([]webhooks.Subscription, error) {
+	vals, err := s.db.HGetAll(context.Background(), keyWebhooks).Result()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]webhooks.Subscription, 0, len(vals))
+	for _, val := range vals {
+		var sub webhooks.Subscription
+		if err := json.Unmarshal([]byte(val), &sub); err != nil {
+			return nil, err
+		}
+		data = append(data, sub)
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].ID < data[j].ID })
+	return data, nil
+}
+
+func (s *Store) WebhookByID(id int64) (webhooks.Subscription, error) {
+	var sub webhooks.Subscription
+	val, err := s.db.HGet(context.Background(), keyWebhooks, strconv.FormatInt(id, 10)).Result()
+	if err == redis.Nil {
+		return sub, fmt.Errorf("webhook %v %w", id, storage.ErrNotFound)
+	}
+	if err != nil {
+		return sub, err
+	}
+	err = json.Unmarshal([]byte(val), &sub)
+	return sub, err
+}
+
+func (s *Store) AddWebhook(sub webhooks.Subscription) (int64, error) {
+	ctx := context.Background()
+	id, err := s.db.Incr(ctx, keyWebhooksSeq).Result()
+	if err != nil {
+		return 0, err
+	}
+	sub.ID = id
+	val, err := json.Marshal(sub)
+	if err != nil {
+		return 0, err
+	}
+	return id, s.db.HSet(ctx, keyWebhooks, strconv.FormatInt(id, 10), val).Err()
+}
+
+func (s *Store) UpdateWebhook(sub webhooks.Subscription) error {
+	ctx := context.Background()
+	field := strconv.FormatInt(sub.ID, 10)
+	exists, err := s.db.HExists(ctx, keyWebhooks, field).Result()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("webhook %v %w", sub.ID, storage.ErrNotFound)
+	}
+	val, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	return s.db.HSet(ctx, keyWebhooks, field, val).Err()
+}
+
+func (s *Store) DeleteWebhook(id int64) error {
+	n, err := s.db.HDel(context.Background(), keyWebhooks, strconv.FormatInt(id, 10)).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("webhook %v %w", id, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) AddDelivery(d webhooks.Delivery) (int64, error) {
+	ctx := context.Background()
+	id, err := s.db.Incr(ctx, keyDeliveriesSeq).Result()
+	if err != nil {
+		return 0, err
+	}
+	d.ID = id
+	return id, s.saveDelivery(ctx, d)
+}
+
+func (s *Store) UpdateDelivery(d webhooks.Delivery) error {
+	ctx := context.Background()
+	exists, err := s.db.HExists(ctx, keyDeliveries, strconv.FormatInt(d.ID, 10)).Result()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("delivery %v %w", d.ID, storage.ErrNotFound)
+	}
+	return s.saveDelivery(ctx, d)
+}
+
+// Запись доставки вместе с её положением в очереди и журнале.
+func (s *Store) saveDelivery(ctx context.Context, d webhooks.Delivery) error {
+	val, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	member := strconv.FormatInt(d.ID, 10)
+	_, err = s.db.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, keyDeliveries, member, val)
+		p.ZAdd(ctx, keyDeliveriesLog, &redis.Z{Score: float64(d.ID), Member: member})
+		if d.Status == webhooks.StatusPending {
+			p.ZAdd(ctx, keyDeliveriesDue, &redis.Z{Score: float64(d.NextAttemptAt), Member: member})
+		} else {
+			p.ZRem(ctx, keyDeliveriesDue, member)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *Store) DeliveryByID(id int64) (webhooks.Delivery, error) {
+	var d webhooks.Delivery
+	val, err := s.db.HGet(context.Background(), keyDeliveries, strconv.FormatInt(id, 10)).Result()
+	if err == redis.Nil {
+		return d, fmt.Errorf("delivery %v %w", id, storage.ErrNotFound)
+	}
+	if err != nil {
+		return d, err
+	}
+	err = json.Unmarshal([]byte(val), &d)
+	return d, err
+}
+
+func (s *Store) DueDeliveries(now int64, limit int) ([]webhooks.Delivery, error) {
+	ctx := context.Background()
+	ids, err := s.db.ZRangeByScore(ctx, keyDeliveriesDue, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now, 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return s.loadDeliveries(ctx, ids)
+}
+
+func (s *Store) Deliveries(f webhooks.DeliveryFilter) ([]webhooks.Delivery, error) {
+	ctx := context.Background()
+	max := "+inf"
+	if f.BeforeID != 0 {
+		max = "(" + strconv.FormatInt(f.BeforeID, 10)
+	}
+
+	var data []webhooks.Delivery
+	for offset := int64(0); ; offset += deliveriesReadPage {
+		ids, err := s.db.ZRevRangeByScore(ctx, keyDeliveriesLog, &redis.ZRangeBy{
+			Min:    "-inf",
+			Max:    max,
+			Offset: offset,
+			Count:  deliveriesReadPage,
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+		page, err := s.loadDeliveries(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range page {
+			if (f.SubscriptionID == 0 || d.SubscriptionID == f.SubscriptionID) && (f.Status == "" || d.Status == f.Status) {
+				data = append(data, d)
+				if f.Limit > 0 && len(data) == f.Limit {
+					return data, nil
+				}
+			}
+		}
+		if len(ids) < deliveriesReadPage {
+			return data, nil
+		}
+	}
+}
+
+func (s *Store) DeleteDeliveries(before int64) (int64, error) {
+	ctx := context.Background()
+	var n int64
+	var cursor uint64
+	for {
+		vals, next, err := s.db.HScan(ctx, keyDeliveries, cursor, "", deliveriesReadPage).Result()
+		if err != nil {
+			return n, err
+		}
+		// HSCAN возвращает пары поле, значение.
+		for i := 0; i+1 < len(vals); i += 2 {
+			var d webhooks.Delivery
+			if err := json.Unmarshal([]byte(vals[i+1]), &d); err != nil {
+				return n, err
+			}
+			if d.Status == webhooks.StatusPending || d.UpdatedAt >= before {
+				continue
+			}
+			_, err := s.db.TxPipelined(ctx, func(p redis.Pipeliner) error {
+				p.HDel(ctx, keyDeliveries, vals[i])
+				p.ZRem(ctx, keyDeliveriesLog, vals[i])
+				p.ZRem(ctx, keyDeliveriesDue, vals[i])
+				return nil
+			})
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+		if cursor = next; cursor == 0 {
+			return n, nil
+		}
+	}
+}
+
+// Чтение доставок по списку ID в том же порядке; отсутствующие пропускаются.
+func (s *Store) loadDeliveries(ctx context.Context, ids []string) ([]webhooks.Delivery, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	vals, err := s.db.HMGet(ctx, keyDeliveries, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]webhooks.Delivery, 0, len(vals))
+	for _, v := range vals {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var d webhooks.Delivery
+		if err := json.Unmarshal([]byte(str), &d); err != nil {
+			return nil, err
+		}
+		data = append(data, d)
+	}
+	return data, nil
+}
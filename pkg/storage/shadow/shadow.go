@@ -0,0 +1,428 @@
+// Пакет shadow - обёртка для проверки новой БД на рабочей нагрузке перед переходом.
+//
+// Запросы выполняются в основной БД (primary), её результат возвращается
+// клиенту. Успешные изменения повторяются во второй БД (secondary) с теми же
+// ID; чтения повторяются во второй БД в фоне и сравниваются с основной.
+// Расхождения и ошибки второй БД записываются в журнал и не влияют на ответ.
+//
+// Счётчики публикуются через expvar (GET /debug/vars, переменная "shadow").
+package shadow
+
+import (
+	"GoNews/pkg/logger"
+	"GoNews/pkg/storage"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Максимальное число одновременных фоновых сравнений.
+// При превышении сравнение пропускается, чтобы не задерживать основную БД.
+const maxInFlight = 16
+
+// Сколько ID расхождений выводить в одной записи журнала.
+const maxLoggedIDs = 10
+
+// Счётчики: compared, mismatches, skipped, write_errors.
+var metrics = expvar.NewMap("shadow")
+
+// Store - основная БД с теневой второй БД.
+type Store struct {
+	storage.Interface // основная БД
+	secondary         storage.Interface
+	slots             chan struct{}
+}
+
+// New создаёт обёртку: primary обслуживает запросы, secondary получает
+// копии изменений и теневые чтения.
+func New(primary, secondary storage.Interface) *Store {
+	return &Store{
+		Interface: primary,
+		secondary: secondary,
+		slots:     make(chan struct{}, maxInFlight),
+	}
+}
+
+// Bind - копия обёртки, в которой основная БД привязана к ctx.
+// Вторая БД не привязывается: теневые чтения продолжаются после ответа.
+func (s *Store) Bind(ctx context.Context) storage.Interface {
+	return &Store{Interface: storage.Bind(s.Interface, ctx), secondary: s.secondary, slots: s.slots}
+}
+
+// Close дожидается фоновых сравнений и закрывает обе БД.
+func (s *Store) Close() error {
+	for i := 0; i < cap(s.slots); i++ {
+		s.slots <- struct{}{}
+	}
+	err := s.Interface.Close()
+	if serr := s.secondary.Close(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Author - автор.
+func (s *Store) Authors() ([]storage.Author, error) {
+	authors, err := s.Interface.Authors()
+	if err == nil {
+		primary := append([]storage.Author(nil), authors...)
+		s.compare("Authors", func() string {
+			secondary, err := s.secondary.Authors()
+			if err != nil {
+				return err.Error()
+			}
+			return diffAuthors(primary, secondary)
+		})
+	}
+	return authors, err
+}
+
+func (s *Store) AuthorByID(id int64) (storage.Author, error) {
+	author, err := s.Interface.AuthorByID(id)
+	s.compare(fmt.Sprintf("AuthorByID(%d)", id), func() string {
+		secondary, err2 := s.secondary.AuthorByID(id)
+		if msg := diffErrors(err, err2); msg != "" || err != nil {
+			return msg
+		}
+		return diffAuthors([]storage.Author{author}, []storage.Author{secondary})
+	})
+	return author, err
+}
+
+func (s *Store) AddAuthor(author storage.Author) (int64, error) {
+	id, err := s.Interface.AddAuthor(author)
+	if err == nil {
+		// Во второй БД запись создаётся с ID, назначенным основной.
+		author.ID = id
+		s.mirror("AddAuthor", func() error {
+			_, err := s.secondary.AddAuthor(author)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (s *Store) UpdateAuthor(author storage.Author) (int64, error) {
+	id, err := s.Interface.UpdateAuthor(author)
+	if err == nil {
+		s.mirror("UpdateAuthor", func() error {
+			_, err := s.secondary.UpdateAuthor(author)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (s *Store) PatchAuthor(id int64, fields map[string]interface{}) (int64, error) {
+	n, err := s.Interface.PatchAuthor(id, fields)
+	if err == nil {
+		s.mirror("PatchAuthor", func() error {
+			_, err := s.secondary.PatchAuthor(id, fields)
+			return err
+		})
+	}
+	return n, err
+}
+
+func (s *Store) DeleteAuthor(author storage.Author) (int64, error) {
+	id, err := s.Interface.DeleteAuthor(author)
+	if err == nil {
+		s.mirror("DeleteAuthor", func() error {
+			_, err := s.secondary.DeleteAuthor(author)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (s *Store) AuthorsBatch(op storage.BatchOp, mode storage.BatchMode, authors []storage.Author) ([]storage.BatchResult, error) {
+	results, err := s.Interface.AuthorsBatch(op, mode, authors)
+	if err == nil {
+		var applied []storage.Author
+		for i, res := range results {
+			if res.Err == "" {
+				a := authors[i]
+				a.ID = res.ID
+				applied = append(applied, a)
+			}
+		}
+		if len(applied) > 0 {
+			s.mirror("AuthorsBatch", func() error {
+				return batchError(s.secondary.AuthorsBatch(op, storage.BatchBestEffort, applied))
+			})
+		}
+	}
+	return results, err
+}
+
+// Post - публикация.
+func (s *Store) Posts() ([]storage.Post, error) {
+	posts, err := s.Interface.Posts()
+	if err == nil {
+		primary := append([]storage.Post(nil), posts...)
+		s.compare("Posts", func() string {
+			secondary, err := s.secondary.Posts()
+			if err != nil {
+				return err.Error()
+			}
+			return diffPosts(primary, secondary)
+		})
+	}
+	return posts, err
+}
+
+func (s *Store) PostByID(id int64) (storage.Post, error) {
+	post, err := s.Interface.PostByID(id)
+	primary := post
+	s.compare(fmt.Sprintf("PostByID(%d)", id), func() string {
+		secondary, err2 := s.secondary.PostByID(id)
+		if msg := diffErrors(err, err2); msg != "" || err != nil {
+			return msg
+		}
+		return diffPosts([]storage.Post{primary}, []storage.Post{secondary})
+	})
+	return post, err
+}
+
+func (s *Store) AddPost(post storage.Post) (int64, error) {
+	id, err := s.Interface.AddPost(post)
+	if err == nil {
+		post.ID = id
+		s.mirror("AddPost", func() error {
+			_, err := s.secondary.AddPost(post)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (s *Store) UpdatePost(post storage.Post) (int64, error) {
+	id, err := s.Interface.UpdatePost(post)
+	if err == nil {
+		s.mirror("UpdatePost", func() error {
+			_, err := s.secondary.UpdatePost(post)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (s *Store) PatchPost(id int64, fields map[string]interface{}) (int64, error) {
+	n, err := s.Interface.PatchPost(id, fields)
+	if err == nil {
+		s.mirror("PatchPost", func() error {
+			_, err := s.secondary.PatchPost(id, fields)
+			return err
+		})
+	}
+	return n, err
+}
+
+func (s *Store) DeletePost(post storage.Post) (int64, error) {
+	id, err := s.Interface.DeletePost(post)
+	if err == nil {
+		s.mirror("DeletePost", func() error {
+			_, err := s.secondary.DeletePost(post)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (s *Store) PostsBatch(op storage.BatchOp, mode storage.BatchMode, posts []storage.Post) ([]storage.BatchResult, error) {
+	results, err := s.Interface.PostsBatch(op, mode, posts)
+	if err == nil {
+		var applied []storage.Post
+		for i, res := range results {
+			if res.Err == "" {
+				p := posts[i]
+				p.ID = res.ID
+				applied = append(applied, p)
+			}
+		}
+		if len(applied) > 0 {
+			s.mirror("PostsBatch", func() error {
+				return batchError(s.secondary.PostsBatch(op, storage.BatchBestEffort, applied))
+			})
+		}
+	}
+	return results, err
+}
+
+// WithTx выполняет транзакцию в основной БД, записывая изменения,
+// и после фиксации повторяет их одной транзакцией во второй БД.
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Tx) error) error {
+	var rec *recorder
+	err := s.Interface.WithTx(ctx, func(tx storage.Tx) error {
+		// Функция может быть вызвана повторно (повтор транзакции MongoDB),
+		// поэтому журнал создаётся заново при каждом вызове.
+		rec = &recorder{Tx: tx}
+		return fn(rec)
+	})
+	if err == nil && len(rec.ops) > 0 {
+		s.mirror("WithTx", func() error {
+			return s.secondary.WithTx(ctx, func(tx storage.Tx) error {
+				for _, op := range rec.ops {
+					if err := op(tx); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	}
+	return err
+}
+
+// Повтор изменения во второй БД. Ошибка записывается в журнал.
+func (s *Store) mirror(method string, fn func() error) {
+	if err := fn(); err != nil {
+		metrics.Add("write_errors", 1)
+		s.log(fmt.Sprintf("%s: secondary write failed: %v", method, err))
+	}
+}
+
+// Фоновое сравнение чтения: diff возвращает описание расхождения или "".
+func (s *Store) compare(method string, diff func() string) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		metrics.Add("skipped", 1)
+		return
+	}
+
+	go func() {
+		defer func() { <-s.slots }()
+
+		metrics.Add("compared", 1)
+		if msg := diff(); msg != "" {
+			metrics.Add("mismatches", 1)
+			s.log(fmt.Sprintf("%s: mismatch: %s", method, msg))
+		}
+	}()
+}
+
+func (s *Store) log(mess string) {
+	logger.SetLog(time.Now(), fmt.Sprintf("shadow %s vs %s", s.Interface.GetInform(), s.secondary.GetInform()), mess)
+}
+
+// Ошибка пакета во второй БД: ошибка вызова или первая ошибка элемента.
+func batchError(results []storage.BatchResult, err error) error {
+	if err != nil {
+		return err
+	}
+	failed := 0
+	first := ""
+	for _, res := range results {
+		if res.Err != "" {
+			if failed == 0 {
+				first = fmt.Sprintf("item %d: %s", res.Index, res.Err)
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d items failed, %s", failed, len(results), first)
+	}
+	return nil
+}
+
+// Расхождение ошибок чтения по ID: найдено/не найдено/ошибка.
+func diffErrors(primary, secondary error) string {
+	pNotFound := errors.Is(primary, storage.ErrNotFound)
+	sNotFound := errors.Is(secondary, storage.ErrNotFound)
+	switch {
+	case primary == nil && secondary == nil, pNotFound && sNotFound:
+		return ""
+	case secondary != nil && !sNotFound:
+		return "secondary error: " + secondary.Error()
+	case primary != nil && !pNotFound:
+		return ""
+	case pNotFound:
+		return "found only in secondary"
+	}
+	return "not found in secondary"
+}
+
+func diffAuthors(primary, secondary []storage.Author) string {
+	byID := make(map[int64]storage.Author, len(secondary))
+	for _, a := range secondary {
+		byID[a.ID] = a
+	}
+	var d diff
+	for _, a := range primary {
+		other, ok := byID[a.ID]
+		delete(byID, a.ID)
+		if !ok {
+			d.missing = append(d.missing, a.ID)
+		} else if other != a {
+			d.changed = append(d.changed, a.ID)
+		}
+	}
+	for id := range byID {
+		d.extra = append(d.extra, id)
+	}
+	return d.String()
+}
+
+func diffPosts(primary, secondary []storage.Post) string {
+	byID := make(map[int64]storage.Post, len(secondary))
+	for _, p := range secondary {
+		byID[p.ID] = p
+	}
+	var d diff
+	for _, p := range primary {
+		other, ok := byID[p.ID]
+		delete(byID, p.ID)
+		if !ok {
+			d.missing = append(d.missing, p.ID)
+		} else if !samePost(p, other) {
+			d.changed = append(d.changed, p.ID)
+		}
+	}
+	for id := range byID {
+		d.extra = append(d.extra, id)
+	}
+	return d.String()
+}
+
+// Сравниваются хранимые поля и имя автора; *_txt формируются API и не сравниваются.
+func samePost(a, b storage.Post) bool {
+	return a.ID == b.ID && a.AuthorID == b.AuthorID && a.AuthorName == b.AuthorName &&
+		a.Title == b.Title && a.Content == b.Content &&
+		a.CreatedAt == b.CreatedAt && a.PublishedAt == b.PublishedAt
+}
+
+// Расхождение наборов записей по ID.
+type diff struct {
+	missing []int64 // есть только в основной БД
+	extra   []int64 // есть только во второй БД
+	changed []int64 // данные отличаются
+}
+
+func (d diff) String() string {
+	var parts []string
+	for _, group := range []struct {
+		name string
+		ids  []int64
+	}{{"missing in secondary", d.missing}, {"only in secondary", d.extra}, {"different", d.changed}} {
+		if len(group.ids) == 0 {
+			continue
+		}
+		sort.Slice(group.ids, func(i, j int) bool { return group.ids[i] < group.ids[j] })
+		shown := group.ids
+		if len(shown) > maxLoggedIDs {
+			shown = shown[:maxLoggedIDs]
+		}
+		s := fmt.Sprintf("%s: %d %v", group.name, len(group.ids), shown)
+		if len(shown) < len(group.ids) {
+			s = strings.TrimSuffix(s, "]") + " ...]"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "; ")
+}
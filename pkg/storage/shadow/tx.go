@@ -0,0 +1,82 @@
+package shadow
+
+import "GoNews/pkg/storage"
+
+// Транзакция основной БД, записывающая успешные изменения
+// для повтора во второй БД.
+type recorder struct {
+	storage.Tx
+	ops []func(tx storage.Tx) error
+}
+
+func (r *recorder) record(op func(tx storage.Tx) error) {
+	r.ops = append(r.ops, op)
+}
+
+func (r *recorder) AddAuthor(author storage.Author) (int64, error) {
+	id, err := r.Tx.AddAuthor(author)
+	if err == nil {
+		author.ID = id
+		r.record(func(tx storage.Tx) error {
+			_, err := tx.AddAuthor(author)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (r *recorder) UpdateAuthor(author storage.Author) (int64, error) {
+	id, err := r.Tx.UpdateAuthor(author)
+	if err == nil {
+		r.record(func(tx storage.Tx) error {
+			_, err := tx.UpdateAuthor(author)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (r *recorder) DeleteAuthor(author storage.Author) (int64, error) {
+	id, err := r.Tx.DeleteAuthor(author)
+	if err == nil {
+		r.record(func(tx storage.Tx) error {
+			_, err := tx.DeleteAuthor(author)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (r *recorder) AddPost(post storage.Post) (int64, error) {
+	id, err := r.Tx.AddPost(post)
+	if err == nil {
+		post.ID = id
+		r.record(func(tx storage.Tx) error {
+			_, err := tx.AddPost(post)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (r *recorder) UpdatePost(post storage.Post) (int64, error) {
+	id, err := r.Tx.UpdatePost(post)
+	if err == nil {
+		r.record(func(tx storage.Tx) error {
+			_, err := tx.UpdatePost(post)
+			return err
+		})
+	}
+	return id, err
+}
+
+func (r *recorder) DeletePost(post storage.Post) (int64, error) {
+	id, err := r.Tx.DeletePost(post)
+	if err == nil {
+		r.record(func(tx storage.Tx) error {
+			_, err := tx.DeletePost(post)
+			return err
+		})
+	}
+	return id, err
+}
@@ -1,5 +1,22 @@
 package storage
 
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound - запись с указанным ID не найдена.
+var ErrNotFound = errors.New("not found")
+
+// ErrUnavailable - БД временно недоступна, запрос в неё не отправлялся
+// (разомкнут автоматический выключатель, см. пакет breaker).
+var ErrUnavailable = errors.New("database unavailable")
+
+// ErrTxUnsupported - БД не поддерживает транзакции (MongoDB без replica set):
+// недоступны WithTx и пакеты в режиме BatchAtomic.
+var ErrTxUnsupported = errors.New("transactions are not supported by the database")
+
 const (
 	AuthorsDb string = "ui/database/tableAuthors.json"
 	PostsDb   string = "ui/database/tablePosts.json"
@@ -13,15 +30,17 @@ type Author struct {
 
 // Post - публикация.
 type Post struct {
-	ID             int64  `json:"id"                bson:"_id"`
-	AuthorID       int64  `json:"author_id"         bson:"author_id"`
-	AuthorName     string `json:"author_name"       bson:"author_name"`
-	Title          string `json:"title"             bson:"title"`
-	Content        string `json:"content"           bson:"content"`
-	CreatedAt      int64  `json:"created_at"        bson:"created_at"`
-	CreatedAtTxt   string `json:"created_at_txt"    bson:"created_at_txt"`
-	PublishedAt    int64  `json:"published_at"      bson:"published_at"`
-	PublishedAtTxt string `json:"published_at_txt"  bson:"published_at_txt"`
+	ID                 int64  `json:"id"                    bson:"_id"`
+	AuthorID           int64  `json:"author_id"             bson:"author_id"`
+	AuthorName         string `json:"author_name"           bson:"author_name"`
+	Title              string `json:"title"                 bson:"title"`
+	Content            string `json:"content"               bson:"content"`
+	CreatedAt          int64  `json:"created_at"            bson:"created_at"`
+	CreatedAtTxt       string `json:"created_at_txt"        bson:"-"` // заполняется TimeFormat
+	CreatedAtRFC3339   string `json:"created_at_rfc3339"    bson:"-"` // заполняется TimeFormat
+	PublishedAt        int64  `json:"published_at"          bson:"published_at"`
+	PublishedAtTxt     string `json:"published_at_txt"      bson:"-"` // заполняется TimeFormat
+	PublishedAtRFC3339 string `json:"published_at_rfc3339"  bson:"-"` // заполняется TimeFormat
 }
 
 type SqlResponse struct {
@@ -32,17 +51,136 @@ type SqlResponse struct {
 // Interface задаёт контракт на работу с БД.
 type Interface interface {
 	GetInform() string
-	Close()
-
-	Authors() ([]Author, error)                 // получение всех авторов
-	AddAuthor(Author) (int64, error)            // создание нового автора
-	UpdateAuthor(Author) (int64, error)         // обновление списка авторов
-	DeleteAuthor(Author) (int64, error)         // удаление автора по ID
-	InsertInitDataFromFileAuthors(string) error // загрузить данные из файла
-
-	Posts() ([]Post, error)                   // получение всех публикаций
-	AddPost(Post) (int64, error)              // создание новой публикации
-	UpdatePost(Post) (int64, error)           // обновление публикации
-	DeletePost(Post) (int64, error)           // удаление публикации по ID
-	InsertInitDataFromFilePosts(string) error // загрузить данные из файла
+	Close() error // закрытие соединений с БД
+
+	// Ping проверяет доступность БД (для /readyz).
+	Ping(ctx context.Context) error
+
+	// WithTx выполняет fn как единую транзакцию: изменения, сделанные через tx,
+	// фиксируются, если fn вернула nil, и отменяются в противном случае.
+	WithTx(ctx context.Context, fn func(tx Tx) error) error
+
+	Authors() ([]Author, error)                                         // получение всех авторов
+	AuthorByID(int64) (Author, error)                                   // получение автора по ID
+	AddAuthor(Author) (int64, error)                                    // создание нового автора
+	UpdateAuthor(Author) (int64, error)                                 // обновление списка авторов
+	PatchAuthor(id int64, fields map[string]interface{}) (int64, error) // обновление отдельных полей автора
+	DeleteAuthor(Author) (int64, error)                                 // удаление автора по ID
+	AuthorsBatch(BatchOp, BatchMode, []Author) ([]BatchResult, error)   // пакетная обработка авторов
+
+	Posts() ([]Post, error)                                           // получение всех публикаций
+	PostByID(int64) (Post, error)                                     // получение публикации по ID
+	AddPost(Post) (int64, error)                                      // создание новой публикации
+	UpdatePost(Post) (int64, error)                                   // обновление публикации
+	PatchPost(id int64, fields map[string]interface{}) (int64, error) // обновление отдельных полей публикации
+	DeletePost(Post) (int64, error)                                   // удаление публикации по ID
+	PostsBatch(BatchOp, BatchMode, []Post) ([]BatchResult, error)     // пакетная обработка публикаций
+}
+
+// Binder - хранилище, операции которого можно выполнять в контексте запроса
+// (отмена, трассировка): методы Interface контекст не принимают.
+type Binder interface {
+	// Bind возвращает копию хранилища, выполняющую операции с контекстом ctx.
+	Bind(ctx context.Context) Interface
+}
+
+// Snapshotter - БД, читающая всех авторов и все публикации из одного
+// согласованного снимка данных (архив на момент времени).
+type Snapshotter interface {
+	Snapshot(ctx context.Context) ([]Author, []Post, error)
+}
+
+// Versioner - БД, сообщающая версию своего сервера (для /admin/info).
+type Versioner interface {
+	Version(ctx context.Context) (string, error)
+}
+
+// Bind возвращает db, привязанное к контексту ctx, или само db, если
+// оно привязку не поддерживает. Обёртки хранилища привязывают и обёрнутое
+// хранилище, поэтому контекст доходит до клиента БД.
+func Bind(db Interface, ctx context.Context) Interface {
+	if b, ok := db.(Binder); ok {
+		return b.Bind(ctx)
+	}
+	return db
+}
+
+type clientKey struct{}
+
+// WithClient - контекст операций клиента client (ключ API, пользователь или
+// IP-адрес). Хранилища с репликами направляют чтения клиента в основную БД
+// вскоре после его изменений (read-your-writes), не затрагивая других клиентов.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// Client - клиент, заданный WithClient; "" - не задан (фоновые задачи).
+func Client(ctx context.Context) string {
+	client, _ := ctx.Value(clientKey{}).(string)
+	return client
+}
+
+type primaryKey struct{}
+
+// WithPrimary - контекст чтений, выполняемых в основной БД независимо от
+// клиента. Нужен чтениям, результат которых получат другие клиенты (кэш):
+// прочитанное на отстающей реплике вернулось бы и клиенту, только что
+// изменившему данные.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Primary - чтения должны выполняться в основной БД (WithPrimary).
+func Primary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// Поля, которые можно изменить через PatchAuthor/PatchPost.
+// Ключи - имена полей в JSON, значения - int64 или string.
+var (
+	AuthorPatchable = []string{"name"}
+	PostPatchable   = []string{"author_id", "title", "content", "created_at", "published_at"}
+)
+
+// ApplyAuthorFields изменяет поля автора согласно fields.
+func ApplyAuthorFields(a *Author, fields map[string]interface{}) error {
+	for k, v := range fields {
+		var ok bool
+		switch k {
+		case "name":
+			a.Name, ok = v.(string)
+		default:
+			return fmt.Errorf("field %q cannot be patched", k)
+		}
+		if !ok {
+			return fmt.Errorf("field %q: unexpected type %T", k, v)
+		}
+	}
+	return nil
+}
+
+// ApplyPostFields изменяет поля публикации согласно fields.
+func ApplyPostFields(p *Post, fields map[string]interface{}) error {
+	for k, v := range fields {
+		var ok bool
+		switch k {
+		case "author_id":
+			p.AuthorID, ok = v.(int64)
+		case "title":
+			p.Title, ok = v.(string)
+		case "content":
+			p.Content, ok = v.(string)
+		case "created_at":
+			p.CreatedAt, ok = v.(int64)
+		case "published_at":
+			p.PublishedAt, ok = v.(int64)
+		default:
+			return fmt.Errorf("field %q cannot be patched", k)
+		}
+		if !ok {
+			return fmt.Errorf("field %q: unexpected type %T", k, v)
+		}
+	}
+	return nil
 }
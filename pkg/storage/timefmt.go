@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Формат RFC 3339 с миллисекундами: время в БД хранится в мс.
+const layoutRFC3339Milli = "2006-01-02T15:04:05.000Z07:00"
+
+// Форматы отображения времени для поддерживаемых языков.
+// Ключ - тег языка в нижнем регистре ("en-us") или его основная часть ("en").
+var displayLayouts = map[string]string{
+	"ru":    "02.01.2006 15:04:05 MST",
+	"de":    "02.01.2006 15:04:05 MST",
+	"fr":    "02/01/2006 15:04:05 MST",
+	"en":    "01/02/2006 03:04:05 PM MST",
+	"en-gb": "02/01/2006 15:04:05 MST",
+}
+
+// Формат отображения по умолчанию (если язык не распознан).
+const defaultDisplayLayout = "2006-01-02 15:04:05 MST"
+
+// TimeFormat - единые правила форматирования времени для всех БД.
+// Backend'ы хранят только метки времени в мс, текстовые поля
+// заполняются на уровне API под конкретный запрос.
+type TimeFormat struct {
+	Location *time.Location
+	Layout   string
+}
+
+// DefaultTimeFormat - UTC и формат отображения по умолчанию.
+func DefaultTimeFormat() TimeFormat {
+	return TimeFormat{Location: time.UTC, Layout: defaultDisplayLayout}
+}
+
+// NewTimeFormat создаёт формат по имени часового пояса IANA (например,
+// "Europe/Moscow") и значению заголовка Accept-Language.
+// Пустой часовой пояс означает UTC.
+func NewTimeFormat(tz string, acceptLanguage string) (TimeFormat, error) {
+	f := DefaultTimeFormat()
+
+	if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return f, err
+		}
+		f.Location = loc
+	}
+
+	f.Layout = layoutForLanguage(acceptLanguage)
+	return f, nil
+}
+
+// RFC3339 возвращает время в формате RFC 3339 с миллисекундами.
+// Нулевая метка времени даёт пустую строку.
+func (f TimeFormat) RFC3339(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return f.time(ms).Format(layoutRFC3339Milli)
+}
+
+// Display возвращает время в формате отображения для выбранного языка.
+// Нулевая метка времени даёт пустую строку.
+func (f TimeFormat) Display(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return f.time(ms).Format(f.Layout)
+}
+
+// ApplyPost заполняет вычисляемые текстовые поля времени публикации.
+func (f TimeFormat) ApplyPost(p *Post) {
+	p.CreatedAtTxt = f.Display(p.CreatedAt)
+	p.CreatedAtRFC3339 = f.RFC3339(p.CreatedAt)
+	p.PublishedAtTxt = f.Display(p.PublishedAt)
+	p.PublishedAtRFC3339 = f.RFC3339(p.PublishedAt)
+}
+
+// ApplyPosts заполняет вычисляемые текстовые поля для списка публикаций.
+func (f TimeFormat) ApplyPosts(posts []Post) {
+	for i := range posts {
+		f.ApplyPost(&posts[i])
+	}
+}
+
+func (f TimeFormat) time(ms int64) time.Time {
+	loc := f.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)).In(loc)
+}
+
+// layoutForLanguage выбирает формат по заголовку Accept-Language
+// с учётом весов q (RFC 9110, раздел 12.5.4).
+func layoutForLanguage(acceptLanguage string) string {
+	type langWeight struct {
+		tag string
+		q   float64
+	}
+
+	var langs []langWeight
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		langs = append(langs, langWeight{tag: tag, q: q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	for _, l := range langs {
+		if l.q <= 0 {
+			continue
+		}
+		if layout, ok := displayLayouts[l.tag]; ok {
+			return layout
+		}
+		if i := strings.Index(l.tag, "-"); i > 0 {
+			if layout, ok := displayLayouts[l.tag[:i]]; ok {
+				return layout
+			}
+		}
+	}
+
+	return defaultDisplayLayout
+}
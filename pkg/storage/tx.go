@@ -0,0 +1,16 @@
+package storage
+
+// Tx - операции, доступные внутри транзакции WithTx.
+// Изменения применяются вместе при успешном завершении функции
+// и отменяются, если она вернула ошибку.
+type Tx interface {
+	AuthorByID(int64) (Author, error)
+	AddAuthor(Author) (int64, error)
+	UpdateAuthor(Author) (int64, error)
+	DeleteAuthor(Author) (int64, error)
+
+	PostByID(int64) (Post, error)
+	AddPost(Post) (int64, error)
+	UpdatePost(Post) (int64, error)
+	DeletePost(Post) (int64, error)
+}
@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Exporter - получатель завершённых спанов.
+type Exporter interface {
+	// Export выгружает пакет спанов сервиса service.
+	Export(ctx context.Context, service string, spans []*Span) error
+	Close() error
+}
+
+// Параметры выгрузки спанов.
+const (
+	queueSize     = 4096            // спанов в очереди; при переполнении новые отбрасываются
+	batchSize     = 512             // наибольший пакет выгрузки
+	batchInterval = 2 * time.Second // выгрузка не реже
+	exportTimeout = 10 * time.Second
+)
+
+// Счётчики: exported, dropped, export_errors.
+var counters = expvar.NewMap("tracing")
+
+// Фоновая выгрузка спанов пакетами.
+type processor struct {
+	service   string
+	exporters []Exporter
+	queue     chan *Span
+	done      chan struct{}
+}
+
+var (
+	enabled int32 // 1 - трассировка включена
+	mu      sync.RWMutex
+	current *processor
+)
+
+// Enabled сообщает, включена ли трассировка.
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// Setup включает трассировку сервиса service с выгрузкой спанов
+// в exporters. Без exporters трассировка остаётся отключённой.
+func Setup(service string, exporters ...Exporter) {
+	if len(exporters) == 0 {
+		return
+	}
+	p := &processor{
+		service:   service,
+		exporters: exporters,
+		queue:     make(chan *Span, queueSize),
+		done:      make(chan struct{}),
+	}
+	mu.Lock()
+	prev := current
+	current = p
+	atomic.StoreInt32(&enabled, 1)
+	mu.Unlock()
+	go p.run()
+	if prev != nil {
+		prev.shutdown(context.Background())
+	}
+}
+
+// Shutdown выгружает накопленные спаны (не дольше ctx) и отключает трассировку.
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	p := current
+	current = nil
+	atomic.StoreInt32(&enabled, 0)
+	mu.Unlock()
+	if p == nil {
+		return nil
+	}
+	return p.shutdown(ctx)
+}
+
+// Постановка завершённого спана в очередь выгрузки.
+func enqueue(s *Span) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if current == nil {
+		return
+	}
+	select {
+	case current.queue <- s:
+	default:
+		counters.Add("dropped", 1)
+	}
+}
+
+func (p *processor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	for {
+		select {
+		case s, ok := <-p.queue:
+			if !ok {
+				p.export(batch)
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				p.export(batch)
+				batch = make([]*Span, 0, batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				p.export(batch)
+				batch = make([]*Span, 0, batchSize)
+			}
+		}
+	}
+}
+
+func (p *processor) export(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	for _, e := range p.exporters {
+		if err := e.Export(ctx, p.service, batch); err != nil {
+			counters.Add("export_errors", 1)
+			log.Warn().Err(err).Int("spans", len(batch)).Msg("tracing: export failed")
+		}
+	}
+	counters.Add("exported", int64(len(batch)))
+}
+
+// Завершение выгрузки: оставшиеся спаны выгружаются, получатели закрываются.
+func (p *processor) shutdown(ctx context.Context) error {
+	close(p.queue)
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	var err error
+	for _, e := range p.exporters {
+		if cerr := e.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Stdout - путь NewFileExporter для вывода спанов в стандартный вывод.
+const Stdout = "stdout"
+
+// Спан в файле трассировки: одна JSON-строка на спан.
+type fileSpan struct {
+	Service    string                 `json:"service"`
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_span_id,omitempty"`
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	DurationMS float64                `json:"duration_ms"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+var kindNames = map[Kind]string{KindInternal: "internal", KindServer: "server", KindClient: "client"}
+
+// FileExporter записывает спаны в файл или stdout, JSON-строка на спан.
+// Работает без сборщика трассировок: файл можно просматривать jq.
+type FileExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+	f  *os.File // nil - stdout
+}
+
+// NewFileExporter открывает файл path для дозаписи спанов; Stdout - вывод в stdout.
+func NewFileExporter(path string) (*FileExporter, error) {
+	if path == Stdout || path == "-" {
+		return &FileExporter{w: os.Stdout}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{w: f, f: f}, nil
+}
+
+func (e *FileExporter) Export(ctx context.Context, service string, spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		fs := fileSpan{
+			Service:    service,
+			TraceID:    s.TraceID.String(),
+			SpanID:     s.SpanID.String(),
+			Name:       s.Name,
+			Kind:       kindNames[s.Kind],
+			Start:      s.Start,
+			End:        s.End,
+			DurationMS: float64(s.End.Sub(s.Start)) / float64(time.Millisecond),
+			Error:      s.Err,
+		}
+		if s.ParentID.IsValid() {
+			fs.ParentID = s.ParentID.String()
+		}
+		if len(s.Attrs) > 0 {
+			fs.Attributes = make(map[string]interface{}, len(s.Attrs))
+			for _, a := range s.Attrs {
+				fs.Attributes[a.Key] = a.Value
+			}
+		}
+		if err := enc.Encode(fs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *FileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.f == nil {
+		return nil
+	}
+	err := e.f.Close()
+	e.f = nil
+	return err
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"GoNews/pkg/logger"
+	"GoNews/pkg/response"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// TraceparentHeader - заголовок W3C Trace Context с родительским спаном.
+const TraceparentHeader = "traceparent"
+
+// ParseTraceparent разбирает заголовок traceparent (версия 00:
+// 00-<trace-id>-<parent-id>-<flags>).
+func ParseTraceparent(h string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+	var sc SpanContext
+	var flags [1]byte
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return SpanContext{}, false
+	}
+	if !sc.TraceID.IsValid() || !sc.SpanID.IsValid() {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+// Traceparent - значение заголовка traceparent для sc.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// Middleware создаёт спан обработки запроса HTTP "<метод> <шаблон маршрута>",
+// дочерний для спана из заголовка traceparent, если он есть. ID трассировки
+// добавляется в запись журнала о запросе (поле trace_id). Если вызывающий
+// сервис не записывает трассировку (флаг sampled сброшен), спаны запроса
+// не создаются.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+		if sc, ok := ParseTraceparent(r.Header.Get(TraceparentHeader)); ok {
+			ctx = ContextWithRemote(ctx, sc)
+			if !sc.Sampled {
+				logger.Annotate(ctx, "trace_id", sc.TraceID.String())
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+		}
+
+		route := "unmatched"
+		if cur := mux.CurrentRoute(r); cur != nil {
+			if tpl, err := cur.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		ctx, span := Start(ctx, r.Method+" "+route, KindServer)
+		defer span.Finish()
+		span.SetAttr("http.method", r.Method)
+		span.SetAttr("http.route", route)
+		span.SetAttr("http.target", r.URL.RequestURI())
+		span.SetAttr("net.peer.addr", r.RemoteAddr)
+		if id := logger.RequestID(ctx); id != "" {
+			span.SetAttr("http.request_id", id)
+		}
+		logger.Annotate(ctx, "trace_id", span.TraceID.String())
+
+		rec := response.NewRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttr("http.status_code", rec.Status)
+		if rec.Status >= 500 {
+			span.SetError(errors.New(http.StatusText(rec.Status)))
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package tracing
+
+import (
+	"GoNews/pkg/storage"
+	"context"
+	"errors"
+)
+
+// Store - обёртка БД, создающая спан "storage.<метод>" на каждый вызов
+// storage.Interface. Спаны дочерние для спана контекста, к которому
+// хранилище привязано (storage.Bind); привязка передаётся обёрнутому
+// хранилищу, и спаны запросов клиента БД становятся дочерними для них.
+type Store struct {
+	storage.Interface
+	backend string
+	ctx     context.Context
+}
+
+// Instrument оборачивает db трассировкой вызовов.
+func Instrument(db storage.Interface) *Store {
+	return &Store{Interface: db, backend: db.GetInform(), ctx: context.Background()}
+}
+
+// Bind - копия хранилища, создающая спаны в контексте ctx.
+func (s *Store) Bind(ctx context.Context) storage.Interface {
+	return &Store{Interface: s.Interface, backend: s.backend, ctx: ctx}
+}
+
+// Начало спана вызова method и хранилище, привязанное к нему.
+func (s *Store) start(method string) (storage.Interface, *Span) {
+	if !Enabled() {
+		return s.Interface, nil
+	}
+	ctx, span := Start(s.ctx, "storage."+method, KindInternal)
+	span.SetAttr("db.backend", s.backend)
+	return storage.Bind(s.Interface, ctx), span
+}
+
+// Завершение спана с результатом err; "не найдено" ошибкой не считается.
+func finish(span *Span, err error) {
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		span.SetError(err)
+	}
+	span.Finish()
+}
+
+// WithTx создаёт спан транзакции; операции в ней - дочерние спаны "storage.Tx.<метод>".
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Tx) error) (err error) {
+	if FromContext(ctx) == nil {
+		if parent := FromContext(s.ctx); parent != nil {
+			ctx = context.WithValue(ctx, spanKey{}, parent)
+		}
+	}
+	ctx, span := Start(ctx, "storage.WithTx", KindInternal)
+	span.SetAttr("db.backend", s.backend)
+	defer func() { finish(span, err) }()
+	return s.Interface.WithTx(ctx, func(tx storage.Tx) error {
+		return fn(&tracedTx{Tx: tx, ctx: ctx, backend: s.backend})
+	})
+}
+
+func (s *Store) Authors() (authors []storage.Author, err error) {
+	db, span := s.start("Authors")
+	defer func() { finish(span, err) }()
+	return db.Authors()
+}
+
+func (s *Store) AuthorByID(id int64) (author storage.Author, err error) {
+	db, span := s.start("AuthorByID")
+	defer func() { finish(span, err) }()
+	return db.AuthorByID(id)
+}
+
+func (s *Store) AddAuthor(author storage.Author) (id int64, err error) {
+	db, span := s.start("AddAuthor")
+	defer func() { finish(span, err) }()
+	return db.AddAuthor(author)
+}
+
+func (s *Store) UpdateAuthor(author storage.Author) (id int64, err error) {
+	db, span := s.start("UpdateAuthor")
+	defer func() { finish(span, err) }()
+	return db.UpdateAuthor(author)
+}
+
+func (s *Store) PatchAuthor(id int64, fields map[string]interface{}) (n int64, err error) {
+	db, span := s.start("PatchAuthor")
+	defer func() { finish(span, err) }()
+	return db.PatchAuthor(id, fields)
+}
+
+func (s *Store) DeleteAuthor(author storage.Author) (id int64, err error) {
+	db, span := s.start("DeleteAuthor")
+	defer func() { finish(span, err) }()
+	return db.DeleteAuthor(author)
+}
+
+func (s *Store) AuthorsBatch(op storage.BatchOp, mode storage.BatchMode, authors []storage.Author) (res []storage.BatchResult, err error) {
+	db, span := s.start("AuthorsBatch")
+	defer func() { finish(span, err) }()
+	return db.AuthorsBatch(op, mode, authors)
+}
+
+func (s *Store) Posts() (posts []storage.Post, err error) {
+	db, span := s.start("Posts")
+	defer func() { finish(span, err) }()
+	return db.Posts()
+}
+
+func (s *Store) PostByID(id int64) (post storage.Post, err error) {
+	db, span := s.start("PostByID")
+	defer func() { finish(span, err) }()
+	return db.PostByID(id)
+}
+
+func (s *Store) AddPost(post storage.Post) (id int64, err error) {
+	db, span := s.start("AddPost")
+	defer func() { finish(span, err) }()
+	return db.AddPost(post)
+}
+
+func (s *Store) UpdatePost(post storage.Post) (id int64, err error) {
+	db, span := s.start("UpdatePost")
+	defer func() { finish(span, err) }()
+	return db.UpdatePost(post)
+}
+
+func (s *Store) PatchPost(id int64, fields map[string]interface{}) (n int64, err error) {
+	db, span := s.start("PatchPost")
+	defer func() { finish(span, err) }()
+	return db.PatchPost(id, fields)
+}
+
+func (s *Store) DeletePost(post storage.Post) (id int64, err error) {
+	db, span := s.start("DeletePost")
+	defer func() { finish(span, err) }()
+	return db.DeletePost(post)
+}
+
+func (s *Store) PostsBatch(op storage.BatchOp, mode storage.BatchMode, posts []storage.Post) (res []storage.BatchResult, err error) {
+	db, span := s.start("PostsBatch")
+	defer func() { finish(span, err) }()
+	return db.PostsBatch(op, mode, posts)
+}
+
+// Транзакция, создающая спаны своих операций.
+type tracedTx struct {
+	storage.Tx
+	ctx     context.Context
+	backend string
+}
+
+func (t *tracedTx) start(method string) *Span {
+	_, span := Start(t.ctx, "storage.Tx."+method, KindInternal)
+	span.SetAttr("db.backend", t.backend)
+	return span
+}
+
+func (t *tracedTx) AuthorByID(id int64) (author storage.Author, err error) {
+	span := t.start("AuthorByID")
+	defer func() { finish(span, err) }()
+	return t.Tx.AuthorByID(id)
+}
+
+func (t *tracedTx) AddAuthor(author storage.Author) (id int64, err error) {
+	span := t.start("AddAuthor")
+	defer func() { finish(span, err) }()
+	return t.Tx.AddAuthor(author)
+}
+
+func (t *tracedTx) UpdateAuthor(author storage.Author) (id int64, err error) {
+	span := t.start("UpdateAuthor")
+	defer func() { finish(span, err) }()
+	return t.Tx.UpdateAuthor(author)
+}
+
+func (t *tracedTx) DeleteAuthor(author storage.Author) (id int64, err error) {
+	span := t.start("DeleteAuthor")
+	defer func() { finish(span, err) }()
+	return t.Tx.DeleteAuthor(author)
+}
+
+func (t *tracedTx) PostByID(id int64) (post storage.Post, err error) {
+	span := t.start("PostByID")
+	defer func() { finish(span, err) }()
+	return t.Tx.PostByID(id)
+}
+
+func (t *tracedTx) AddPost(post storage.Post) (id int64, err error) {
+	span := t.start("AddPost")
+	defer func() { finish(span, err) }()
+	return t.Tx.AddPost(post)
+}
+
+func (t *tracedTx) UpdatePost(post storage.Post) (id int64, err error) {
+	span := t.start("UpdatePost")
+	defer func() { finish(span, err) }()
+	return t.Tx.UpdatePost(post)
+}
+
+func (t *tracedTx) DeletePost(post storage.Post) (id int64, err error) {
+	span := t.start("DeletePost")
+	defer func() { finish(span, err) }()
+	return t.Tx.DeletePost(post)
+}
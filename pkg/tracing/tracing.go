@@ -0,0 +1,171 @@
+// Пакет tracing - трассировка запросов в модели OpenTelemetry: спаны
+// обработчиков HTTP (Middleware), вызовов storage.Interface (Instrument)
+// и запросов к БД (перехватчики клиентов pgx, MongoDB и Redis в пакетах БД).
+//
+// Контекст трассировки передаётся в заголовке W3C traceparent. Спаны
+// выгружаются пакетами в файл или stdout (NewFileExporter, JSON-строка
+// на спан) и по OTLP/HTTP (NewOTLPExporter). Пока Setup не вызван,
+// трассировка отключена и Start почти ничего не стоит.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID - ID трассировки.
+type TraceID [16]byte
+
+// SpanID - ID спана.
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// IsValid сообщает, что ID не нулевой.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+func (id SpanID) IsValid() bool  { return id != SpanID{} }
+
+// Kind - вид спана (значения как в OTLP).
+type Kind int
+
+const (
+	KindInternal Kind = 1 // операция внутри сервера
+	KindServer   Kind = 2 // обработка входящего запроса
+	KindClient   Kind = 3 // запрос к внешней системе (БД)
+)
+
+// Attribute - атрибут спана: string, int64, float64 или bool.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanContext - ID трассировки и спана, передаваемые между сервисами.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// Span - операция в трассировке. Методы nil-спана ничего не делают:
+// при отключённой трассировке Start возвращает nil.
+type Span struct {
+	TraceID  TraceID
+	SpanID   SpanID
+	ParentID SpanID // нулевой - корневой спан
+	Name     string
+	Kind     Kind
+	Start    time.Time
+	End      time.Time
+	Attrs    []Attribute
+	Err      string // описание ошибки; непустое - статус спана "ошибка"
+
+	mu    sync.Mutex
+	ended bool
+}
+
+// SetAttr добавляет атрибут спана.
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	switch v := value.(type) {
+	case int:
+		value = int64(v)
+	case int32:
+		value = int64(v)
+	case uint32:
+		value = int64(v)
+	case float32:
+		value = float64(v)
+	}
+	s.mu.Lock()
+	s.Attrs = append(s.Attrs, Attribute{Key: key, Value: value})
+	s.mu.Unlock()
+}
+
+// SetError отмечает спан ошибкой err (nil - без изменений).
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.Err = err.Error()
+	s.mu.Unlock()
+}
+
+// Finish завершает спан и передаёт его на выгрузку. Повторные вызовы
+// ничего не делают.
+func (s *Span) Finish() {
+	s.FinishAt(time.Now())
+}
+
+// FinishAt - Finish со временем завершения end.
+func (s *Span) FinishAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended, s.End = true, end
+	s.mu.Unlock()
+	enqueue(s)
+}
+
+// SpanContext - ID спана для дочерних спанов и заголовка traceparent.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: s.TraceID, SpanID: s.SpanID, Sampled: true}
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// FromContext возвращает текущий спан контекста, nil - нет.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithRemote добавляет в ctx родительский спан другого сервиса.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Start начинает спан name, дочерний для спана из ctx, и возвращает
+// контекст с ним. При отключённой трассировке, а также если другой сервис
+// решил не записывать трассировку (родитель без флага sampled), возвращает
+// ctx и nil.
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	return StartAt(ctx, name, kind, time.Now())
+}
+
+// StartAt - Start со временем начала start (для операций, о которых
+// известно уже после завершения, например по журналу pgx).
+func StartAt(ctx context.Context, name string, kind Kind, start time.Time) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	s := &Span{Name: name, Kind: kind, Start: start}
+	if parent := FromContext(ctx); parent != nil {
+		s.TraceID, s.ParentID = parent.TraceID, parent.SpanID
+	} else if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok && sc.TraceID.IsValid() {
+		if !sc.Sampled {
+			return ctx, nil
+		}
+		s.TraceID, s.ParentID = sc.TraceID, sc.SpanID
+	} else {
+		rand.Read(s.TraceID[:])
+	}
+	rand.Read(s.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
@@ -0,0 +1,309 @@
+// Пакет validation проверяет входные данные API до обращения к БД.
+package validation
+
+import (
+	"GoNews/pkg/storage"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ограничения на размер данных.
+const (
+	MaxBodySize   int64 = 1 << 20 // максимальный размер тела запроса, байт
+	MaxNameLen          = 255     // максимальная длина имени автора, символов
+	MaxTitleLen         = 255     // максимальная длина заголовка, символов
+	MaxContentLen       = 65536   // максимальная длина текста публикации, символов
+
+	MaxBatchBodySize int64 = 16 << 20 // максимальный размер тела пакетного запроса, байт
+	MaxBatchItems          = 1000     // максимальное число элементов в пакете
+
+	MaxImportBodySize int64 = 1 << 30 // максимальный размер загружаемых данных (POST /import), байт
+)
+
+// Op - операция, для которой проверяются данные.
+type Op int
+
+const (
+	OpCreate Op = iota
+	OpUpdate
+	OpDelete
+)
+
+// Поля, которые вычисляются сервером и не принимаются от клиента.
+var (
+	PostReadOnly   = []string{"author_name", "created_at_txt", "created_at_rfc3339", "published_at_txt", "published_at_rfc3339"}
+	AuthorReadOnly = []string{}
+)
+
+// ErrTooLarge - тело запроса превышает допустимый размер.
+var ErrTooLarge = errors.New("request body too large")
+
+// FieldError - ошибка в конкретном поле.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors - список ошибок по полям.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *Errors) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Prefix добавляет prefix к именам полей в ошибке проверки
+// (например, "posts[0]." для элементов вложенного списка).
+func Prefix(err error, prefix string) error {
+	var errs Errors
+	if !errors.As(err, &errs) {
+		return fmt.Errorf("%s %w", strings.TrimSuffix(prefix, "."), err)
+	}
+	prefixed := make(Errors, len(errs))
+	for i, fe := range errs {
+		prefixed[i] = FieldError{Field: prefix + fe.Field, Message: fe.Message}
+	}
+	return prefixed
+}
+
+// Decode читает JSON-объект из r в dst.
+// Отклоняет тело больше MaxBodySize, неизвестные поля и поля из readOnly.
+// Ошибки в полях возвращаются как Errors.
+func Decode(r io.Reader, dst interface{}, readOnly []string) error {
+	return DecodeLimit(r, MaxBodySize, dst, readOnly)
+}
+
+// DecodeLimit - Decode с ограничением размера тела limit байт.
+func DecodeLimit(r io.Reader, limit int64, dst interface{}, readOnly []string) error {
+	data, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > limit {
+		return fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, limit)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("invalid JSON object: %v", err)
+	}
+
+	var errs Errors
+	for _, field := range readOnly {
+		if _, ok := raw[field]; ok {
+			errs.add(field, "read-only field")
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			errs.add(typeErr.Field, "must be %s", typeErr.Type.String())
+			return errs
+		}
+		if strings.HasPrefix(err.Error(), "json: unknown field ") {
+			errs.add(strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`), "unknown field")
+			return errs
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Author проверяет данные автора для операции op.
+func Author(a storage.Author, op Op) error {
+	var errs Errors
+
+	checkID(&errs, "id", a.ID, op)
+	if op == OpDelete {
+		return errs.err()
+	}
+
+	checkText(&errs, "name", a.Name, MaxNameLen, true)
+
+	return errs.err()
+}
+
+// Post проверяет данные публикации для операции op.
+func Post(p storage.Post, op Op) error {
+	var errs Errors
+
+	checkID(&errs, "id", p.ID, op)
+	if op == OpDelete {
+		return errs.err()
+	}
+
+	if p.AuthorID <= 0 {
+		errs.add("author_id", "must be positive")
+	}
+	checkText(&errs, "title", p.Title, MaxTitleLen, true)
+	checkText(&errs, "content", p.Content, MaxContentLen, false)
+	if p.CreatedAt < 0 {
+		errs.add("created_at", "must not be negative")
+	}
+	if p.PublishedAt < 0 {
+		errs.add("published_at", "must not be negative")
+	}
+
+	return errs.err()
+}
+
+// Для создания ID может быть 0 (назначается БД), для изменения и удаления обязателен.
+func checkID(errs *Errors, field string, id int64, op Op) {
+	if op == OpCreate {
+		if id < 0 {
+			errs.add(field, "must not be negative")
+		}
+		return
+	}
+	if id <= 0 {
+		errs.add(field, "must be positive")
+	}
+}
+
+func checkText(errs *Errors, field string, value string, maxLen int, required bool) {
+	if required && strings.TrimSpace(value) == "" {
+		errs.add(field, "must not be empty")
+		return
+	}
+	if !utf8.ValidString(value) {
+		errs.add(field, "must be valid UTF-8")
+		return
+	}
+	if n := utf8.RuneCountInString(value); n > maxLen {
+		errs.add(field, "must be at most %d characters, got %d", maxLen, n)
+	}
+}
+
+// Document возвращает v в виде JSON-объекта без вычисляемых полей readOnly.
+// Используется как исходный документ для PATCH.
+func Document(v interface{}, readOnly []string) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for _, field := range readOnly {
+		delete(doc, field)
+	}
+
+	return json.Marshal(doc)
+}
+
+// Patched проверяет документ after, полученный применением патча к before,
+// заполняет dst итоговыми значениями и возвращает изменённые поля из patchable.
+// Значения полей имеют тип int64 или string.
+func Patched(before, after []byte, patchable []string, dst interface{}) (map[string]interface{}, error) {
+	var beforeDoc, afterDoc map[string]json.RawMessage
+	if err := json.Unmarshal(before, &beforeDoc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &afterDoc); err != nil {
+		return nil, fmt.Errorf("patched document is not a JSON object: %v", err)
+	}
+
+	allowed := make(map[string]bool, len(patchable))
+	for _, field := range patchable {
+		allowed[field] = true
+	}
+
+	keys := make([]string, 0, len(afterDoc))
+	for field := range afterDoc {
+		keys = append(keys, field)
+	}
+	sort.Strings(keys)
+
+	var errs Errors
+	for _, field := range keys {
+		val := afterDoc[field]
+		switch {
+		case field == "id":
+			if !equalJSON(beforeDoc[field], val) {
+				errs.add(field, "immutable field")
+			}
+		case !allowed[field]:
+			errs.add(field, "unknown or read-only field")
+		}
+	}
+	for _, field := range patchable {
+		if val, ok := afterDoc[field]; !ok || string(val) == "null" {
+			errs.add(field, "must not be removed")
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	if err := Decode(bytes.NewReader(after), dst, nil); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	for _, field := range patchable {
+		if equalJSON(beforeDoc[field], afterDoc[field]) {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(afterDoc[field]))
+		dec.UseNumber()
+		var val interface{}
+		if err := dec.Decode(&val); err != nil {
+			return nil, err
+		}
+		switch v := val.(type) {
+		case json.Number:
+			n, err := v.Int64()
+			if err != nil {
+				errs.add(field, "must be an integer")
+				continue
+			}
+			fields[field] = n
+		case string:
+			fields[field] = v
+		default:
+			errs.add(field, "unexpected type %T", v)
+		}
+	}
+
+	return fields, errs.err()
+}
+
+func equalJSON(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,302 @@
+package webhooks
+
+import (
+	"GoNews/pkg/events"
+	"GoNews/pkg/logger"
+	"GoNews/pkg/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Options - параметры доставки.
+type Options struct {
+	MaxAttempts  int           // после стольких неудач доставка получает состояние failed
+	Backoff      time.Duration // задержка перед первым повтором, далее удваивается
+	MaxBackoff   time.Duration // наибольшая задержка между попытками
+	Timeout      time.Duration // время ожидания ответа получателя
+	PollInterval time.Duration // период проверки очереди
+	Workers      int           // одновременных запросов
+	Retention    time.Duration // сколько хранить завершённые доставки в журнале, 0 - всегда
+}
+
+// DefaultOptions - параметры по умолчанию: 8 попыток в течение примерно 20 минут.
+func DefaultOptions() Options {
+	return Options{
+		MaxAttempts:  8,
+		Backoff:      10 * time.Second,
+		MaxBackoff:   time.Hour,
+		Timeout:      10 * time.Second,
+		PollInterval: time.Second,
+		Workers:      4,
+		Retention:    7 * 24 * time.Hour,
+	}
+}
+
+// Сколько доставок выбирается из очереди за раз.
+const dueBatch = 20
+
+// Название источника в журнале ошибок.
+const logSource = "webhooks"
+
+// Dispatcher ставит события в очередь доставок и отправляет их.
+// Очередь хранится в БД, поэтому доставки, не завершённые до остановки
+// сервера, продолжаются после запуска. События, произошедшие, пока сервер
+// не работал, в очередь не попадают.
+type Dispatcher struct {
+	store  Store
+	src    events.Source
+	opts   Options
+	client *http.Client
+	wake   chan struct{}
+}
+
+// NewDispatcher создаёт отправителя для подписок из store и событий src.
+func NewDispatcher(store Store, src events.Source, opts Options) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		src:    src,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Run обрабатывает события и очередь, пока не отменён ctx.
+func (d *Dispatcher) Run(ctx context.Context) {
+	go d.consume(ctx)
+
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for {
+		d.deliverDue(ctx)
+
+		if d.opts.Retention > 0 && time.Since(lastPrune) > time.Hour {
+			lastPrune = time.Now()
+			if _, err := d.store.DeleteDeliveries(millis(time.Now().Add(-d.opts.Retention))); err != nil {
+				d.log(fmt.Sprintf("prune delivery log: %v", err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Retry ставит доставку id из store в очередь заново с обнулённым числом попыток.
+// Dispatcher отправит её при следующей проверке очереди.
+func Retry(store Store, id int64) (Delivery, error) {
+	dl, err := store.DeliveryByID(id)
+	if err != nil {
+		return dl, err
+	}
+	dl.Status = StatusPending
+	dl.Attempts = 0
+	dl.NextAttemptAt = millis(time.Now())
+	dl.UpdatedAt = dl.NextAttemptAt
+	return dl, store.UpdateDelivery(dl)
+}
+
+// Подписка на поток изменений; после разрыва продолжается с последнего события.
+func (d *Dispatcher) consume(ctx context.Context) {
+	var lastID string
+	for ctx.Err() == nil {
+		ch, err := d.src.Subscribe(ctx, lastID)
+		if err != nil {
+			d.log(fmt.Sprintf("subscribe to events: %v", err))
+		} else {
+			for e := range ch {
+				lastID = e.ID
+				if err := d.enqueue(e); err != nil {
+					d.log(fmt.Sprintf("enqueue event %s: %v", e.ID, err))
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Добавление в очередь доставок события e для подходящих подписок.
+func (d *Dispatcher) enqueue(e events.Event) error {
+	subs, err := d.store.Webhooks()
+	if err != nil {
+		return err
+	}
+
+	name := EventName(e)
+	var payload []byte
+	added := false
+	for _, s := range subs {
+		if !s.Active || !s.Matches(name) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(Payload{Event: name, Data: e}); err != nil {
+				return err
+			}
+		}
+		now := millis(time.Now())
+		_, err := d.store.AddDelivery(Delivery{
+			SubscriptionID: s.ID,
+			Event:          name,
+			EventID:        e.ID,
+			Payload:        payload,
+			Status:         StatusPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+		if err != nil {
+			return err
+		}
+		added = true
+	}
+	if added {
+		d.notify()
+	}
+	return nil
+}
+
+// Отправка всех доставок, время которых наступило.
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := d.store.DueDeliveries(millis(time.Now()), dueBatch)
+		if err != nil {
+			d.log(fmt.Sprintf("read delivery queue: %v", err))
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+
+		workers := d.opts.Workers
+		if workers <= 0 {
+			workers = 1
+		}
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for _, dl := range due {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(dl Delivery) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				d.attempt(ctx, dl)
+			}(dl)
+		}
+		wg.Wait()
+
+		if len(due) < dueBatch {
+			return
+		}
+	}
+}
+
+// Одна попытка доставки и запись её результата.
+func (d *Dispatcher) attempt(ctx context.Context, dl Delivery) {
+	sub, err := d.store.WebhookByID(dl.SubscriptionID)
+	if errors.Is(err, storage.ErrNotFound) {
+		dl.Status = StatusFailed
+		dl.LastError = "subscription deleted"
+		d.save(dl)
+		return
+	}
+	var code int
+	if err == nil {
+		code, err = d.send(ctx, sub, dl)
+	}
+	dl.Attempts++
+	dl.ResponseCode = code
+	if err == nil {
+		dl.Status = StatusDelivered
+		dl.LastError = ""
+	} else {
+		dl.LastError = err.Error()
+		if dl.Attempts >= d.opts.MaxAttempts {
+			dl.Status = StatusFailed
+			d.log(fmt.Sprintf("delivery %d to webhook %d failed after %d attempts: %v", dl.ID, dl.SubscriptionID, dl.Attempts, err))
+		} else {
+			dl.NextAttemptAt = millis(time.Now().Add(d.backoff(dl.Attempts)))
+		}
+	}
+	d.save(dl)
+}
+
+// Запрос к получателю; ответ 2xx - доставлено.
+func (d *Dispatcher) send(ctx context.Context, sub Subscription, dl Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(dl.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoNews-Webhooks")
+	req.Header.Set(HeaderEvent, dl.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(dl.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, time.Now().Unix(), dl.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Задержка перед повтором после attempts неудачных попыток:
+// Backoff * 2^(attempts-1), не больше MaxBackoff, со случайной добавкой до 10%.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.Backoff
+	for i := 1; i < attempts && delay < d.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.opts.MaxBackoff {
+		delay = d.opts.MaxBackoff
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
+
+func (d *Dispatcher) save(dl Delivery) {
+	dl.UpdatedAt = millis(time.Now())
+	if err := d.store.UpdateDelivery(dl); err != nil {
+		d.log(fmt.Sprintf("save delivery %d: %v", dl.ID, err))
+	}
+}
+
+func (d *Dispatcher) log(mess string) {
+	logger.SetLog(time.Now(), logSource, mess)
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
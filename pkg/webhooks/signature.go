@@ -0,0 +1,68 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запроса к получателю.
+const (
+	HeaderSignature = "X-GoNews-Signature" // t=<время Unix>,v1=<HMAC-SHA256 в hex>
+	HeaderEvent     = "X-GoNews-Event"
+	HeaderDelivery  = "X-GoNews-Delivery"
+)
+
+// ErrSignature - подпись отсутствует, неверна или устарела.
+var ErrSignature = errors.New("invalid webhook signature")
+
+// Sign возвращает значение заголовка подписи тела body.
+// Подписывается строка "<timestamp>.<body>" ключом secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	return "t=" + strconv.FormatInt(timestamp, 10) + ",v1=" + signature(secret, timestamp, body)
+}
+
+// Verify проверяет заголовок подписи header для тела body.
+// Подпись старше tolerance отклоняется (0 - без проверки времени).
+// Используется получателями, написанными на Go.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp int64
+	var sig string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp, _ = strconv.ParseInt(kv[1], 10, 64)
+		case "v1":
+			sig = kv[1]
+		}
+	}
+	if timestamp == 0 || sig == "" {
+		return ErrSignature
+	}
+	if tolerance > 0 {
+		age := time.Since(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrSignature
+		}
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, timestamp, body))) {
+		return ErrSignature
+	}
+	return nil
+}
+
+func signature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,149 @@
+// Пакет webhooks - исходящие уведомления об изменениях (webhooks).
+//
+// Подписка задаёт URL, список событий ("posts.create", "posts.*", "*")
+// и секрет. На каждое подходящее событие из потока изменений (пакет events)
+// в очередь добавляется доставка; Dispatcher отправляет её POST-запросом
+// с подписью HMAC-SHA256 и при ошибке повторяет с экспоненциальной задержкой.
+// Подписки, очередь и журнал доставок хранятся в основной БД (Store).
+package webhooks
+
+import (
+	"GoNews/pkg/events"
+	"GoNews/pkg/validation"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Состояния доставки.
+const (
+	StatusPending   = "pending"   // ожидает отправки или повтора
+	StatusDelivered = "delivered" // получатель ответил 2xx
+	StatusFailed    = "failed"    // попытки исчерпаны или подписка удалена
+)
+
+// Subscription - подписка на события.
+type Subscription struct {
+	ID        int64    `json:"id"                bson:"_id"`
+	URL       string   `json:"url"               bson:"url"`
+	Events    []string `json:"events"            bson:"events"`
+	Secret    string   `json:"secret,omitempty"  bson:"secret"`
+	Active    bool     `json:"active"            bson:"active"`
+	CreatedAt int64    `json:"created_at"        bson:"created_at"`
+}
+
+// Delivery - доставка события одной подписке. Время - в миллисекундах Unix.
+type Delivery struct {
+	ID             int64           `json:"id"                       bson:"_id"`
+	SubscriptionID int64           `json:"subscription_id"          bson:"subscription_id"`
+	Event          string          `json:"event"                    bson:"event"`
+	EventID        string          `json:"event_id"                 bson:"event_id"`
+	Payload        json.RawMessage `json:"payload"                  bson:"payload"`
+	Status         string          `json:"status"                   bson:"status"`
+	Attempts       int             `json:"attempts"                 bson:"attempts"`
+	NextAttemptAt  int64           `json:"next_attempt_at"          bson:"next_attempt_at"`
+	ResponseCode   int             `json:"response_code,omitempty"  bson:"response_code"`
+	LastError      string          `json:"last_error,omitempty"     bson:"last_error"`
+	CreatedAt      int64           `json:"created_at"               bson:"created_at"`
+	UpdatedAt      int64           `json:"updated_at"               bson:"updated_at"`
+}
+
+// DeliveryFilter - отбор доставок для журнала (новые первыми).
+type DeliveryFilter struct {
+	SubscriptionID int64  // 0 - все подписки
+	Status         string // "" - все состояния
+	BeforeID       int64  // только доставки с меньшим ID (страницы), 0 - с последней
+	Limit          int
+}
+
+// Store - хранение подписок, очереди и журнала доставок.
+// Отсутствующая запись - ошибка, оборачивающая storage.ErrNotFound.
+type Store interface {
+	Webhooks() ([]Subscription, error)
+	WebhookByID(id int64) (Subscription, error)
+	AddWebhook(Subscription) (int64, error)
+	UpdateWebhook(Subscription) error
+	DeleteWebhook(id int64) error
+
+	AddDelivery(Delivery) (int64, error)
+	UpdateDelivery(Delivery) error
+	DeliveryByID(id int64) (Delivery, error)
+	// DueDeliveries - доставки в состоянии pending с NextAttemptAt <= now,
+	// по возрастанию NextAttemptAt, не больше limit.
+	DueDeliveries(now int64, limit int) ([]Delivery, error)
+	Deliveries(f DeliveryFilter) ([]Delivery, error)
+	// DeleteDeliveries удаляет завершённые доставки, изменённые до before.
+	DeleteDeliveries(before int64) (int64, error)
+}
+
+// Payload - тело запроса к получателю.
+type Payload struct {
+	Event string       `json:"event"` // например, posts.create
+	Data  events.Event `json:"data"`
+}
+
+// EventName - имя события для подписок: <сущность>.<операция>.
+func EventName(e events.Event) string {
+	return e.Entity + "." + string(e.Op)
+}
+
+// Matches сообщает, подписана ли s на событие name.
+func (s Subscription) Matches(name string) bool {
+	entity := strings.SplitN(name, ".", 2)[0]
+	for _, pattern := range s.Events {
+		if pattern == "*" || pattern == name || pattern == entity+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate проверяет URL и список событий подписки.
+// Ошибки возвращаются как validation.Errors.
+func (s Subscription) Validate() error {
+	var errs validation.Errors
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, validation.FieldError{Field: "url", Message: "must be an absolute http or https URL"})
+	}
+	if len(s.Events) == 0 {
+		errs = append(errs, validation.FieldError{Field: "events", Message: "must not be empty"})
+	}
+	for _, name := range s.Events {
+		if !validEvent(name) {
+			errs = append(errs, validation.FieldError{Field: "events", Message: "unknown event " + strconv.Quote(name) +
+				", expected *, <entity>.* or <entity>.<op> with entity authors, posts and op create, update, delete"})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validEvent(name string) bool {
+	if name == "*" {
+		return true
+	}
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 || (parts[0] != events.Authors && parts[0] != events.Posts) {
+		return false
+	}
+	switch events.Op(parts[1]) {
+	case "*", events.OpCreate, events.OpUpdate, events.OpDelete:
+		return true
+	}
+	return false
+}
+
+// NewSecret создаёт случайный секрет подписки.
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,372 @@
+package webhooks
+
+import (
+	"GoNews/pkg/events"
+	"GoNews/pkg/logger"
+	"GoNews/pkg/storage"
+	"GoNews/pkg/validation"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Журнал ошибок доставки - во временный каталог, а не в logs/ пакета.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "webhooks-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := logger.OpenFile(filepath.Join(dir, "log.json"), logger.RotateOptions{}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	logger.CloseFile()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// Store в памяти для тестов.
+type memStore struct {
+	mu         sync.Mutex
+	subs       map[int64]Subscription
+	deliveries map[int64]Delivery
+	nextID     int64
+}
+
+func newMemStore() *memStore {
+	return &memStore{subs: make(map[int64]Subscription), deliveries: make(map[int64]Delivery)}
+}
+
+func (s *memStore) Webhooks() ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var subs []Subscription
+	for _, sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs, nil
+}
+
+func (s *memStore) WebhookByID(id int64) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.subs[id]
+	if !ok {
+		return sub, fmt.Errorf("webhook %d: %w", id, storage.ErrNotFound)
+	}
+	return sub, nil
+}
+
+func (s *memStore) AddWebhook(sub Subscription) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	sub.ID = s.nextID
+	s.subs[sub.ID] = sub
+	return sub.ID, nil
+}
+
+func (s *memStore) UpdateWebhook(sub Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub.ID] = sub
+	return nil
+}
+
+func (s *memStore) DeleteWebhook(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, id)
+	return nil
+}
+
+func (s *memStore) AddDelivery(dl Delivery) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	dl.ID = s.nextID
+	s.deliveries[dl.ID] = dl
+	return dl.ID, nil
+}
+
+func (s *memStore) UpdateDelivery(dl Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[dl.ID] = dl
+	return nil
+}
+
+func (s *memStore) DeliveryByID(id int64) (Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dl, ok := s.deliveries[id]
+	if !ok {
+		return dl, fmt.Errorf("delivery %d: %w", id, storage.ErrNotFound)
+	}
+	return dl, nil
+}
+
+func (s *memStore) DueDeliveries(now int64, limit int) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []Delivery
+	for _, dl := range s.deliveries {
+		if dl.Status == StatusPending && dl.NextAttemptAt <= now {
+			due = append(due, dl)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt < due[j].NextAttemptAt })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (s *memStore) Deliveries(f DeliveryFilter) ([]Delivery, error) {
+	return nil, nil
+}
+
+func (s *memStore) DeleteDeliveries(before int64) (int64, error) {
+	return 0, nil
+}
+
+// Получатель, записывающий запросы и отвечающий кодом status.
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	rc.mu.Lock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := rc.status
+	rc.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func testOptions() Options {
+	opts := DefaultOptions()
+	opts.Timeout = 5 * time.Second
+	return opts
+}
+
+func TestDeliverySigned(t *testing.T) {
+	rc := &receiver{status: http.StatusNoContent}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	store := newMemStore()
+	const secret = "s3cret"
+	subID, _ := store.AddWebhook(Subscription{URL: srv.URL, Events: []string{"posts.*"}, Secret: secret, Active: true})
+	store.AddWebhook(Subscription{URL: srv.URL, Events: []string{"authors.create"}, Secret: secret, Active: true})
+	store.AddWebhook(Subscription{URL: srv.URL, Events: []string{"*"}, Secret: secret, Active: false})
+
+	d := NewDispatcher(store, nil, testOptions())
+	e := events.Event{ID: "1", Entity: events.Posts, Op: events.OpCreate, EntityID: 7, Time: time.Now()}
+	if err := d.enqueue(e); err != nil {
+		t.Fatal(err)
+	}
+	d.deliverDue(context.Background())
+
+	if rc.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1 (only the active posts.* subscription)", rc.count())
+	}
+	req, body := rc.requests[0], rc.bodies[0]
+	if got := req.Header.Get(HeaderEvent); got != "posts.create" {
+		t.Errorf("%s = %q, want posts.create", HeaderEvent, got)
+	}
+	sig := req.Header.Get(HeaderSignature)
+	if err := Verify(secret, sig, body, time.Minute); err != nil {
+		t.Errorf("Verify(%q) = %v", sig, err)
+	}
+	if err := Verify("other", sig, body, time.Minute); err == nil {
+		t.Error("Verify with a wrong secret succeeded")
+	}
+	if err := Verify(secret, sig, append(body, ' '), time.Minute); err == nil {
+		t.Error("Verify of a modified body succeeded")
+	}
+
+	due, _ := store.DueDeliveries(millis(time.Now()), dueBatch)
+	if len(due) != 0 {
+		t.Errorf("%d deliveries still pending", len(due))
+	}
+	for _, dl := range store.deliveries {
+		if dl.SubscriptionID != subID {
+			t.Errorf("delivery for subscription %d, want %d", dl.SubscriptionID, subID)
+		}
+		if dl.Status != StatusDelivered || dl.Attempts != 1 || dl.ResponseCode != http.StatusNoContent {
+			t.Errorf("delivery = %s, %d attempts, code %d; want delivered, 1, 204", dl.Status, dl.Attempts, dl.ResponseCode)
+		}
+	}
+}
+
+func TestVerifyTolerance(t *testing.T) {
+	body := []byte(`{"event":"posts.create"}`)
+	old := Sign("k", time.Now().Add(-time.Hour).Unix(), body)
+	if err := Verify("k", old, body, 5*time.Minute); err != ErrSignature {
+		t.Errorf("Verify of an hour-old signature = %v, want ErrSignature", err)
+	}
+	if err := Verify("k", old, body, 0); err != nil {
+		t.Errorf("Verify without tolerance = %v", err)
+	}
+	for _, header := range []string{"", "t=1", "v1=abc", "garbage"} {
+		if err := Verify("k", header, body, 0); err != ErrSignature {
+			t.Errorf("Verify(%q) = %v, want ErrSignature", header, err)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, nil, Options{Backoff: 10 * time.Second, MaxBackoff: time.Minute})
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{10, time.Minute},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			got := d.backoff(tt.attempts)
+			if got < tt.base || got > tt.base+tt.base/10 {
+				t.Fatalf("backoff(%d) = %s, want %s..%s", tt.attempts, got, tt.base, tt.base+tt.base/10)
+			}
+		}
+	}
+}
+
+func TestRetriesUntilFailed(t *testing.T) {
+	rc := &receiver{status: http.StatusInternalServerError}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	store := newMemStore()
+	subID, _ := store.AddWebhook(Subscription{URL: srv.URL, Events: []string{"*"}, Secret: "k", Active: true})
+	opts := testOptions()
+	opts.MaxAttempts = 3
+	opts.Backoff = time.Second
+	opts.MaxBackoff = time.Minute
+	d := NewDispatcher(store, nil, opts)
+
+	id, _ := store.AddDelivery(Delivery{SubscriptionID: subID, Event: "posts.delete", Payload: []byte(`{}`), Status: StatusPending})
+	for attempt := 1; attempt <= opts.MaxAttempts; attempt++ {
+		dl, _ := store.DeliveryByID(id)
+		start := time.Now()
+		d.attempt(context.Background(), dl)
+
+		dl, _ = store.DeliveryByID(id)
+		if dl.Attempts != attempt || dl.ResponseCode != http.StatusInternalServerError || dl.LastError == "" {
+			t.Fatalf("after attempt %d: attempts %d, code %d, error %q", attempt, dl.Attempts, dl.ResponseCode, dl.LastError)
+		}
+		if attempt < opts.MaxAttempts {
+			if dl.Status != StatusPending {
+				t.Fatalf("after attempt %d: status %s, want pending", attempt, dl.Status)
+			}
+			// Следующая попытка не раньше Backoff * 2^(attempt-1).
+			min := millis(start.Add(opts.Backoff << uint(attempt-1)))
+			if dl.NextAttemptAt < min {
+				t.Errorf("after attempt %d: next attempt in %dms, want at least %s",
+					attempt, dl.NextAttemptAt-millis(start), opts.Backoff<<uint(attempt-1))
+			}
+			if due, _ := store.DueDeliveries(millis(time.Now()), dueBatch); len(due) != 0 {
+				t.Errorf("after attempt %d: delivery is due before its backoff", attempt)
+			}
+		} else if dl.Status != StatusFailed {
+			t.Fatalf("after %d attempts: status %s, want failed", attempt, dl.Status)
+		}
+	}
+	if rc.count() != opts.MaxAttempts {
+		t.Errorf("receiver got %d requests, want %d", rc.count(), opts.MaxAttempts)
+	}
+}
+
+func TestDeletedSubscriptionFails(t *testing.T) {
+	store := newMemStore()
+	d := NewDispatcher(store, nil, testOptions())
+	id, _ := store.AddDelivery(Delivery{SubscriptionID: 42, Payload: []byte(`{}`), Status: StatusPending})
+	d.deliverDue(context.Background())
+	if dl, _ := store.DeliveryByID(id); dl.Status != StatusFailed || dl.LastError != "subscription deleted" {
+		t.Errorf("delivery = %s (%q), want failed (subscription deleted)", dl.Status, dl.LastError)
+	}
+}
+
+func TestSubscriptionMatches(t *testing.T) {
+	tests := []struct {
+		events []string
+		name   string
+		want   bool
+	}{
+		{[]string{"*"}, "posts.create", true},
+		{[]string{"posts.*"}, "posts.delete", true},
+		{[]string{"posts.*"}, "authors.create", false},
+		{[]string{"posts.create"}, "posts.create", true},
+		{[]string{"posts.create"}, "posts.update", false},
+		{[]string{"authors.update", "posts.delete"}, "posts.delete", true},
+		{[]string{"post.*"}, "posts.create", false},
+		{nil, "posts.create", false},
+	}
+	for _, tt := range tests {
+		if got := (Subscription{Events: tt.events}).Matches(tt.name); got != tt.want {
+			t.Errorf("%v.Matches(%q) = %v, want %v", tt.events, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	tests := []struct {
+		url    string
+		events []string
+		fields []string // поля с ошибками
+	}{
+		{"https://example.com/hook", []string{"*"}, nil},
+		{"http://localhost:9000/h", []string{"posts.*", "authors.delete"}, nil},
+		{"ftp://example.com", []string{"*"}, []string{"url"}},
+		{"/relative", []string{"*"}, []string{"url"}},
+		{"https://", []string{"*"}, []string{"url"}},
+		{"https://example.com", nil, []string{"events"}},
+		{"https://example.com", []string{"posts.publish"}, []string{"events"}},
+		{"https://example.com", []string{"comments.*"}, []string{"events"}},
+		{"https://example.com", []string{"posts"}, []string{"events"}},
+		{"", []string{"bad", "*"}, []string{"url", "events"}},
+	}
+	for _, tt := range tests {
+		err := Subscription{URL: tt.url, Events: tt.events}.Validate()
+		var fields []string
+		if err != nil {
+			errs, ok := err.(validation.Errors)
+			if !ok {
+				t.Fatalf("Validate(%q, %v) = %T, want validation.Errors", tt.url, tt.events, err)
+			}
+			for _, fe := range errs {
+				if len(fields) == 0 || fields[len(fields)-1] != fe.Field {
+					fields = append(fields, fe.Field)
+				}
+			}
+		}
+		if fmt.Sprint(fields) != fmt.Sprint(tt.fields) {
+			t.Errorf("Validate(%q, %v) fields = %v, want %v (%v)", tt.url, tt.events, fields, tt.fields, err)
+		}
+	}
+}